@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AdminClient talks to the admin listener [admin.address], the admin api isn't on the public one
+type AdminClient struct {
+	c *Client
+}
+
+// NewAdmin takes the address of the admin listener, like "http://localhost:6060", and the same options
+// as New, WithToken for admin.token or the api key of an admin user
+func NewAdmin(adminURL string, opts ...Option) (*AdminClient, error) {
+	c, err := New(adminURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &AdminClient{c: c}, nil
+}
+
+// CreateWebhook subscribes endpoint to the student events, deliveries are signed with secret [16+ chars]
+func (a *AdminClient) CreateWebhook(ctx context.Context, endpoint string, secret string) (int64, error) {
+	var created struct {
+		Id int64 `json:"id"`
+	}
+	err := a.c.do(ctx, http.MethodPost, "/admin/webhooks", nil, Webhook{URL: endpoint, Secret: secret}, &created)
+	return created.Id, err
+}
+
+func (a *AdminClient) Webhooks(ctx context.Context) ([]Webhook, error) {
+	var webhooks []Webhook
+	err := a.c.do(ctx, http.MethodGet, "/admin/webhooks", nil, nil, &webhooks)
+	return webhooks, err
+}
+
+func (a *AdminClient) DeleteWebhook(ctx context.Context, id int64) error {
+	return a.c.do(ctx, http.MethodDelete, fmt.Sprintf("/admin/webhooks/%d", id), nil, nil, nil)
+}
+
+// Deliveries lists the webhook deliveries with the given status [DeliveryDead for the dead letters], "" for all
+func (a *AdminClient) Deliveries(ctx context.Context, status string) ([]Delivery, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+
+	var deliveries []Delivery
+	err := a.c.do(ctx, http.MethodGet, "/admin/webhooks/deliveries", query, nil, &deliveries)
+	return deliveries, err
+}
+
+// Redeliver queues a delivery again
+func (a *AdminClient) Redeliver(ctx context.Context, deliveryId int64) error {
+	return a.c.do(ctx, http.MethodPost, fmt.Sprintf("/admin/webhooks/deliveries/%d/redeliver", deliveryId), nil, nil, nil)
+}
+
+// CreateBackup takes a consistent snapshot of the database on the server
+func (a *AdminClient) CreateBackup(ctx context.Context) (BackupInfo, error) {
+	var info BackupInfo
+	err := a.c.do(ctx, http.MethodPost, "/admin/backups", nil, nil, &info)
+	return info, err
+}
+
+func (a *AdminClient) Backups(ctx context.Context) ([]BackupInfo, error) {
+	var backups []BackupInfo
+	err := a.c.do(ctx, http.MethodGet, "/admin/backups", nil, nil, &backups)
+	return backups, err
+}
+
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+func (a *AdminClient) LogLevel(ctx context.Context) (string, error) {
+	var level logLevel
+	err := a.c.do(ctx, http.MethodGet, "/admin/log/level", nil, nil, &level)
+	return level.Level, err
+}
+
+// SetLogLevel changes the server's log level ["debug", "info", "warn", "error"] and returns the new one
+func (a *AdminClient) SetLogLevel(ctx context.Context, level string) (string, error) {
+	var current logLevel
+	err := a.c.do(ctx, http.MethodPut, "/admin/log/level", nil, logLevel{Level: level}, &current)
+	return current.Level, err
+}
+
+// /healthz and /graphql aren't versioned, they sit at the root of the server
+
+// Health is nil while the server and its database answer, ErrUnavailable otherwise
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/healthz", nil, nil, nil)
+}
+
+// GraphQLError is a query the server ran [or refused, for its depth and cost limits] with errors
+type GraphQLError struct {
+	Messages []string
+}
+
+func (e *GraphQLError) Error() string {
+	return "students api: graphql: " + strings.Join(e.Messages, "; ")
+}
+
+// GraphQL runs a query and decodes its "data" into out, errors in the result are a *GraphQLError
+// a query is sent as POST so it isn't retried, even when it doesn't change anything
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]any, out any) error {
+	req, err := jsonCall(http.MethodPost, "/graphql", map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	//queries over the limits come back as 400 with a graphql result, not the usual error body
+	req.accept = []int{http.StatusBadRequest}
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+		errorBody
+	}
+	if err := decode(resp, &result); err != nil {
+		return err
+	}
+
+	if result.Status != "" {
+		return &Error{StatusCode: resp.StatusCode, Message: result.Error}
+	}
+	if len(result.Errors) > 0 {
+		gqlErr := &GraphQLError{}
+		for _, e := range result.Errors {
+			gqlErr.Messages = append(gqlErr.Messages, e.Message)
+		}
+		return gqlErr
+	}
+
+	if out == nil || len(result.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("client: decoding graphql data: %w", err)
+	}
+	return nil
+}
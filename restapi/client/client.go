@@ -0,0 +1,289 @@
+// Package client is a typed Go client for the students api, it talks to the v2 routes:
+//
+//	c, err := client.New("http://localhost:8082")
+//	id, err := c.CreateStudent(ctx, client.Student{FirstName: "Alan", Email: "alan@gmail.com", DateOfBirth: &dob})
+//	for student, err := range c.Students(ctx, client.ListOptions{Filter: "age>=18"}) { ... }
+//
+// the admin api is on its own listener, NewAdmin makes a client for it
+//
+// error answers come back as *Error [errors.Is(err, client.ErrNotFound) and friends], idempotent calls
+// are retried with backoff when the server is busy or unreachable
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiPrefix is where the versioned routes live, /graphql and /healthz are at the root
+const apiPrefix = "/api/v2"
+
+type Client struct {
+	base   *url.URL
+	http   *http.Client
+	retry  RetryPolicy
+	header http.Header
+}
+
+// RetryPolicy is how often and how long a failed call is tried again
+// the backoff doubles from MinBackoff up to MaxBackoff with full jitter, a Retry-After from the
+// server wins when it is longer
+type RetryPolicy struct {
+	MaxAttempts int // 1 turns retries off
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, MinBackoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient [timeouts, proxies, a test server's client]
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// WithHeader is sent on every request, e.g. a token for a proxy in front of the api
+func WithHeader(key string, value string) Option {
+	return func(c *Client) { c.header.Add(key, value) }
+}
+
+// WithToken authenticates every request, with a key from `students-api apikey create` [auth.enabled]
+// or for NewAdmin also with admin.token
+func WithToken(token string) Option {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithLanguage asks for error messages in a language the server has a catalog for ["fr", "hi"...]
+func WithLanguage(lang string) Option {
+	return WithHeader("Accept-Language", lang)
+}
+
+// New takes the address of the server without any path, like "http://localhost:8082"
+func New(baseURL string, opts ...Option) (*Client, error) {
+	base, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("client: base url %q must be http or https", baseURL)
+	}
+
+	c := &Client{
+		base:   base,
+		http:   http.DefaultClient,
+		retry:  DefaultRetryPolicy,
+		header: http.Header{"User-Agent": {"students-api-client"}},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+
+	return c, nil
+}
+
+// call is one api request, the body is kept as bytes so it can be sent again on a retry
+type call struct {
+	method      string
+	path        string
+	query       url.Values
+	header      http.Header
+	body        []byte
+	contentType string
+	accept      []int // statuses that aren't errors besides 2xx [the batch answers 422 with results]
+}
+
+// jsonCall encodes in as the body [nil for none]
+func jsonCall(method string, path string, in any) (call, error) {
+	c := call{method: method, path: path}
+	if in == nil {
+		return c, nil
+	}
+
+	body, err := json.Marshal(in)
+	if err != nil {
+		return call{}, fmt.Errorf("client: %w", err)
+	}
+	c.body, c.contentType = body, "application/json"
+	return c, nil
+}
+
+// do sends a json request and decodes the answer into out [nil to ignore it]
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, in any, out any) error {
+	req, err := jsonCall(method, path, in)
+	if err != nil {
+		return err
+	}
+	req.query = query
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return decode(resp, out)
+}
+
+func decode(resp *http.Response, out any) error {
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("client: decoding %s answer: %w", resp.Request.URL.Path, err)
+	}
+	return nil
+}
+
+// send runs the call with retries, the caller closes the body of the response
+// an answer that isn't 2xx [or in accept] is returned as an error and its body is already closed
+func (c *Client) send(ctx context.Context, req call) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.attempt(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= c.retry.MaxAttempts || !retryable(req.method, err) {
+			return nil, err
+		}
+
+		wait := c.backoff(attempt)
+		var apiErr *Error
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > wait {
+			wait = apiErr.RetryAfter
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, req call) (*http.Response, error) {
+	u := *c.base
+	u.Path += req.path
+	u.RawQuery = req.query.Encode()
+
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, req.method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+	for key, values := range c.header {
+		r.Header[key] = values
+	}
+	for key, values := range req.header {
+		r.Header[key] = values
+	}
+	if req.contentType != "" {
+		r.Header.Set("Content-Type", req.contentType)
+	}
+
+	resp, err := c.http.Do(r)
+	if err != nil {
+		//the context's own error is easier to check for than the *url.Error around it
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &transportError{err}
+	}
+
+	if resp.StatusCode < 300 || accepted(req.accept, resp.StatusCode) {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	return nil, parseError(resp)
+}
+
+func accepted(statuses []int, status int) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// transportError is a request that never got an answer [connection refused, reset...]
+type transportError struct{ err error }
+
+func (e *transportError) Error() string { return "client: " + e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+// retryable: the rate limiter answers 429 before any handler runs so that is safe to send again for
+// every method, a lost connection or a 502/503/504 only for methods that can run twice without harm
+func retryable(method string, err error) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests:
+			return true
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return idempotent(method)
+		}
+		return false
+	}
+
+	var te *transportError
+	return errors.As(err, &te) && idempotent(method)
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// backoff before retry number attempt [1 for the first retry], a random duration up to the doubled step
+func (c *Client) backoff(attempt int) time.Duration {
+	step := c.retry.MinBackoff << (attempt - 1)
+	if step <= 0 || step > c.retry.MaxBackoff {
+		step = c.retry.MaxBackoff
+	}
+	if step <= 0 {
+		return 0
+	}
+	return rand.N(step) + 1
+}
+
+// parseRetryAfter reads the seconds form, the only one the rate limiter sends
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func apiPath(format string, args ...any) string {
+	return apiPrefix + fmt.Sprintf(format, args...)
+}
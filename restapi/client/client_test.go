@@ -0,0 +1,442 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shivakr07/students-api/client"
+	"github.com/shivakr07/students-api/internal/apitest"
+)
+
+// newClient runs the real router behind an httptest server, wrap can put a handler in front of it
+func newClient(t *testing.T, wrap func(http.Handler) http.Handler, opts ...client.Option) (*client.Client, *apitest.Harness) {
+	h := apitest.New(t)
+
+	var handler http.Handler = h.App.Handler
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	opts = append([]client.Option{
+		client.WithHTTPClient(server.Client()),
+		client.WithRetry(client.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}),
+	}, opts...)
+	c, err := client.New(server.URL, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c, h
+}
+
+func newStudent(t *testing.T, first string, last string, email string, born string) client.Student {
+	dob, err := client.ParseDate(born)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.Student{FirstName: first, LastName: last, Email: email, DateOfBirth: &dob}
+}
+
+func TestStudents(t *testing.T) {
+	c, _ := newClient(t, nil)
+	ctx := context.Background()
+
+	id, err := c.CreateStudent(ctx, newStudent(t, "Alan", "Turing", "alan@gmail.com", "2000-06-23"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	student, err := c.Student(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if student.Id != id || student.FirstName != "Alan" || student.DateOfBirth.String() != "2000-06-23" || student.Age == 0 {
+		t.Errorf("got %+v", student)
+	}
+
+	student, err = c.MergePatch(ctx, id, map[string]any{"last_name": "Mathison Turing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if student.LastName != "Mathison Turing" {
+		t.Errorf("merge patch: got last name %q", student.LastName)
+	}
+
+	value := json.RawMessage(`"Someone Else"`)
+	_, err = c.JSONPatch(ctx, id, []client.PatchOperation{{Op: "test", Path: "/last_name", Value: &value}})
+	if !errors.Is(err, client.ErrConflict) {
+		t.Errorf("failed test operation: got %v, want ErrConflict", err)
+	}
+
+	_, err = c.CreateStudent(ctx, newStudent(t, "Alan", "Again", "alan@gmail.com", "2001-01-01"))
+	if !errors.Is(err, client.ErrConflict) {
+		t.Errorf("taken email: got %v, want ErrConflict", err)
+	}
+
+	_, err = c.CreateStudent(ctx, client.Student{FirstName: "Nobody"})
+	if !errors.Is(err, client.ErrBadRequest) {
+		t.Errorf("invalid student: got %v, want ErrBadRequest", err)
+	}
+
+	if err := c.DeleteStudent(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.DeleteStudent(ctx, id)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("deleted student: got %v, want a 404 *Error", err)
+	}
+	if !errors.Is(err, client.ErrNotFound) || errors.Is(err, client.ErrGone) {
+		t.Errorf("deleted student: %v should only match ErrNotFound", err)
+	}
+	if apiErr.Message != "no student found with id 1" {
+		t.Errorf("message: got %q", apiErr.Message)
+	}
+
+	if _, err := c.Student(ctx, id); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("deleted student: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestErrorLanguage(t *testing.T) {
+	c, _ := newClient(t, nil, client.WithLanguage("fr"))
+
+	_, err := c.Export(context.Background(), 99)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got %v, want *Error", err)
+	}
+	if apiErr.Message != "aucun étudiant avec l'id 99" {
+		t.Errorf("got %q", apiErr.Message)
+	}
+}
+
+func TestStudentsIterator(t *testing.T) {
+	var lists atomic.Int32
+	c, _ := newClient(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && r.URL.Path == "/api/v2/students" {
+				lists.Add(1)
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	ctx := context.Background()
+
+	names := []string{"Alan", "Ada", "Grace", "Edsger", "Barbara"}
+	for i, name := range names {
+		if _, err := c.CreateStudent(ctx, newStudent(t, name, "", name+"@example.com", fmt.Sprintf("2001-01-%02d", i+1))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for student, err := range c.Students(ctx, client.ListOptions{Limit: 2}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, student.FirstName)
+	}
+	if len(got) != len(names) {
+		t.Fatalf("got %v, want %v", got, names)
+	}
+	for i := range names {
+		if got[i] != names[i] {
+			t.Errorf("student %d: got %s, want %s", i, got[i], names[i])
+		}
+	}
+	//2 + 2 + 1, the short page is the last one
+	if n := lists.Load(); n != 3 {
+		t.Errorf("got %d list requests, want 3", n)
+	}
+
+	//stopping early doesn't fetch the next page
+	lists.Store(0)
+	for student := range c.Students(ctx, client.ListOptions{Limit: 2, Filter: `first_name~"a"`}) {
+		if student.FirstName == "Ada" {
+			break
+		}
+	}
+	if n := lists.Load(); n != 1 {
+		t.Errorf("got %d list requests after break, want 1", n)
+	}
+
+	//errors from the server end the loop
+	var errs []error
+	for _, err := range c.Students(ctx, client.ListOptions{Filter: "nope>1"}) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], client.ErrBadRequest) {
+		t.Errorf("bad filter: got %v, want one ErrBadRequest", errs)
+	}
+}
+
+// flaky answers the first failures requests with status, then hands over to the router
+func flaky(status int, failures int32, calls *atomic.Int32) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) <= failures {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(status)
+				w.Write([]byte(`{"status": "Error", "error": "try again"}`))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("idempotent", func(t *testing.T) {
+		var calls atomic.Int32
+		c, h := newClient(t, flaky(http.StatusServiceUnavailable, 2, &calls))
+		h.LoadStudents()
+
+		student, err := c.Student(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if student.FirstName != "Alan" || calls.Load() != 3 {
+			t.Errorf("got %+v after %d calls", student, calls.Load())
+		}
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		var calls atomic.Int32
+		c, _ := newClient(t, flaky(http.StatusBadGateway, 10, &calls))
+
+		_, err := c.Courses(ctx)
+		var apiErr *client.Error
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway || apiErr.Message != "try again" {
+			t.Errorf("got %v, want the last 502", err)
+		}
+		if calls.Load() != 3 {
+			t.Errorf("got %d calls, want 3", calls.Load())
+		}
+	})
+
+	t.Run("not idempotent", func(t *testing.T) {
+		var calls atomic.Int32
+		c, _ := newClient(t, flaky(http.StatusServiceUnavailable, 1, &calls))
+
+		_, err := c.CreateStudent(ctx, newStudent(t, "Alan", "", "alan@gmail.com", "2000-06-23"))
+		if !errors.Is(err, client.ErrUnavailable) || calls.Load() != 1 {
+			t.Errorf("got %v after %d calls, want one ErrUnavailable", err, calls.Load())
+		}
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		//a 429 never reached a handler, so even a create is sent again
+		var calls atomic.Int32
+		c, _ := newClient(t, flaky(http.StatusTooManyRequests, 1, &calls))
+
+		id, err := c.CreateStudent(ctx, newStudent(t, "Alan", "", "alan@gmail.com", "2000-06-23"))
+		if err != nil || id != 1 || calls.Load() != 2 {
+			t.Errorf("got id %d, %v after %d calls", id, err, calls.Load())
+		}
+	})
+}
+
+func TestContext(t *testing.T) {
+	var calls atomic.Int32
+	c, _ := newClient(t, flaky(http.StatusServiceUnavailable, 100, &calls),
+		client.WithRetry(client.RetryPolicy{MaxAttempts: 10, MinBackoff: time.Second, MaxBackoff: time.Second}))
+
+	//the wait between attempts stops with the context
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.Courses(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("took %s, the backoff didn't stop with the context", time.Since(start))
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Courses(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestErase(t *testing.T) {
+	c, h := newClient(t, nil)
+	ctx := context.Background()
+	h.LoadStudents()
+
+	export, err := c.Export(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if export.Student.FirstName != "Ada" || len(export.Events) != 1 {
+		t.Errorf("got %+v", export)
+	}
+
+	erasure, err := c.Erase(ctx, 2, "ticket 42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if erasure.StudentId != 2 || erasure.Reason != "ticket 42" || erasure.Digest == "" {
+		t.Errorf("got %+v", erasure)
+	}
+
+	_, err = c.Export(ctx, 2)
+	var erased *client.ErasedError
+	if !errors.As(err, &erased) {
+		t.Fatalf("got %v, want *ErasedError", err)
+	}
+	if erased.Erasure.Digest != erasure.Digest || !errors.Is(err, client.ErrGone) {
+		t.Errorf("got %+v", erased.Erasure)
+	}
+}
+
+func TestCourses(t *testing.T) {
+	c, h := newClient(t, nil)
+	ctx := context.Background()
+	h.LoadStudents()
+
+	courseId, err := c.CreateCourse(ctx, client.Course{Code: "CS101", Title: "Computing", Term: "2026-fall", Credits: 3, Capacity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Enroll(ctx, courseId, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Enroll(ctx, courseId, 2); !errors.Is(err, client.ErrConflict) {
+		t.Errorf("full course: got %v, want ErrConflict", err)
+	}
+
+	assessmentId, err := c.CreateAssessment(ctx, courseId, client.Assessment{Name: "Final", Weight: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RecordScore(ctx, assessmentId, 1, 95); err != nil {
+		t.Fatal(err)
+	}
+
+	transcript, err := c.Transcript(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transcript.Terms) != 1 || transcript.CumulativeGPA == nil {
+		t.Errorf("got %+v", transcript)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	c, _ := newClient(t, nil)
+	ctx := context.Background()
+
+	alan := newStudent(t, "Alan", "", "alan@gmail.com", "2000-06-23")
+	result, err := c.Batch(ctx, []client.BatchOperation{
+		{Op: client.BatchCreate, Student: &alan},
+		{Op: client.BatchDelete, Id: 99},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Committed || result.Results[1].Status != http.StatusNotFound {
+		t.Errorf("got %+v", result)
+	}
+
+	result, err = c.Batch(ctx, []client.BatchOperation{{Op: client.BatchCreate, Student: &alan}}, false)
+	if err != nil || !result.Committed || result.Results[0].Id != 1 {
+		t.Errorf("got %+v, %v", result, err)
+	}
+}
+
+func TestGraphQL(t *testing.T) {
+	c, h := newClient(t, nil)
+	ctx := context.Background()
+	h.LoadStudents()
+
+	var data struct {
+		Student struct {
+			Email string `json:"email"`
+		} `json:"student"`
+	}
+	if err := c.GraphQL(ctx, `query($id: ID!) { student(id: $id) { email } }`, map[string]any{"id": "2"}, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Student.Email != "ada@example.com" {
+		t.Errorf("got %+v", data)
+	}
+
+	var gqlErr *client.GraphQLError
+	if err := c.GraphQL(ctx, `{ nope }`, nil, nil); !errors.As(err, &gqlErr) {
+		t.Errorf("got %v, want *GraphQLError", err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	c, h := newClient(t, nil)
+	h.LoadStudents()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	//the replay from the outbox, the harness doesn't run the broker so nothing live comes after it
+	var got []client.Event
+	for event, err := range c.EventsAfter(ctx, 0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, event)
+		if len(got) == 2 {
+			break
+		}
+	}
+
+	if got[0].Type != client.EventStudentCreated || got[1].StudentId != 2 || got[1].Id <= got[0].Id {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	const token = "0123456789-admin-token"
+	h := apitest.New(t, apitest.Set("admin.token", token))
+	server := httptest.NewServer(h.Admin)
+	t.Cleanup(server.Close)
+	ctx := context.Background()
+
+	admin, err := client.NewAdmin(server.URL, client.WithHTTPClient(server.Client()), client.WithToken(token))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := admin.CreateWebhook(ctx, "https://example.com/hook", "0123456789abcdef-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if webhooks, err := admin.Webhooks(ctx); err != nil || len(webhooks) != 1 || webhooks[0].Id != id {
+		t.Errorf("got %+v, %v", webhooks, err)
+	}
+	if level, err := admin.SetLogLevel(ctx, "debug"); err != nil || level != "debug" {
+		t.Errorf("got %q, %v", level, err)
+	}
+
+	//without the token
+	anonymous, err := client.NewAdmin(server.URL, client.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := anonymous.Webhooks(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("got %v, want ErrUnauthorized", err)
+	}
+}
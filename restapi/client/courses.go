@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// CreateCourse returns the id of the new course, Scale is a grading scale name from the server's config
+func (c *Client) CreateCourse(ctx context.Context, course Course) (int64, error) {
+	var created struct {
+		Id int64 `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, apiPath("/courses"), nil, course, &created)
+	return created.Id, err
+}
+
+func (c *Client) Course(ctx context.Context, id int64) (Course, error) {
+	var course Course
+	err := c.do(ctx, http.MethodGet, apiPath("/courses/%d", id), nil, nil, &course)
+	return course, err
+}
+
+func (c *Client) Courses(ctx context.Context) ([]Course, error) {
+	var courses []Course
+	err := c.do(ctx, http.MethodGet, apiPath("/courses"), nil, nil, &courses)
+	return courses, err
+}
+
+// Enroll a full course is ErrConflict, so is a student who is already enrolled
+func (c *Client) Enroll(ctx context.Context, courseId int64, studentId int64) (Enrollment, error) {
+	var enrollment Enrollment
+	err := c.do(ctx, http.MethodPost, apiPath("/courses/%d/enrollments", courseId), nil,
+		map[string]int64{"student_id": studentId}, &enrollment)
+	return enrollment, err
+}
+
+// Enrollments of a course, dropped ones included
+func (c *Client) Enrollments(ctx context.Context, courseId int64) ([]Enrollment, error) {
+	var enrollments []Enrollment
+	err := c.do(ctx, http.MethodGet, apiPath("/courses/%d/enrollments", courseId), nil, nil, &enrollments)
+	return enrollments, err
+}
+
+func (c *Client) Drop(ctx context.Context, courseId int64, studentId int64) error {
+	return c.do(ctx, http.MethodDelete, apiPath("/courses/%d/enrollments/%d", courseId, studentId), nil, nil, nil)
+}
+
+// StudentCourses are the enrollments of a student with their course
+func (c *Client) StudentCourses(ctx context.Context, studentId int64) ([]Enrollment, error) {
+	var enrollments []Enrollment
+	err := c.do(ctx, http.MethodGet, apiPath("/students/%d/courses", studentId), nil, nil, &enrollments)
+	return enrollments, err
+}
+
+// CreateAssessment returns the id of the new assessment, the weights of a course don't have to add up to anything
+func (c *Client) CreateAssessment(ctx context.Context, courseId int64, assessment Assessment) (int64, error) {
+	var created struct {
+		Id int64 `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, apiPath("/courses/%d/assessments", courseId), nil, assessment, &created)
+	return created.Id, err
+}
+
+func (c *Client) Assessments(ctx context.Context, courseId int64) ([]Assessment, error) {
+	var assessments []Assessment
+	err := c.do(ctx, http.MethodGet, apiPath("/courses/%d/assessments", courseId), nil, nil, &assessments)
+	return assessments, err
+}
+
+// RecordScore sets the student's score [0-100], grading again overwrites it
+func (c *Client) RecordScore(ctx context.Context, assessmentId int64, studentId int64, score float64) (Score, error) {
+	var recorded Score
+	err := c.do(ctx, http.MethodPut, apiPath("/assessments/%d/scores/%d", assessmentId, studentId), nil,
+		map[string]float64{"score": score}, &recorded)
+	return recorded, err
+}
+
+// Transcript has the grades per term and the gpa, the student in it is the server's full record
+func (c *Client) Transcript(ctx context.Context, studentId int64) (Transcript, error) {
+	var transcript Transcript
+	err := c.do(ctx, http.MethodGet, apiPath("/students/%d/transcript", studentId), url.Values{"format": {"json"}}, nil, &transcript)
+	return transcript, err
+}
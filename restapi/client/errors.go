@@ -0,0 +1,86 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Error is an answer the server gave with an error status, Message is the "error" field of the
+// {"status": "Error", "error": "..."} body [in the language asked for with WithLanguage]
+type Error struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // from the Retry-After header, 0 when there was none
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("students api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("students api: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is matches the sentinels below by status code, so errors.Is(err, client.ErrNotFound) works on any 404
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Message == "" && t.StatusCode == e.StatusCode
+}
+
+var (
+	ErrBadRequest       = &Error{StatusCode: http.StatusBadRequest}
+	ErrUnauthorized     = &Error{StatusCode: http.StatusUnauthorized}
+	ErrForbidden        = &Error{StatusCode: http.StatusForbidden}
+	ErrNotFound         = &Error{StatusCode: http.StatusNotFound}
+	ErrConflict         = &Error{StatusCode: http.StatusConflict}
+	ErrGone             = &Error{StatusCode: http.StatusGone}
+	ErrTooLarge         = &Error{StatusCode: http.StatusRequestEntityTooLarge}
+	ErrUnsupportedMedia = &Error{StatusCode: http.StatusUnsupportedMediaType}
+	ErrUnprocessable    = &Error{StatusCode: http.StatusUnprocessableEntity}
+	ErrRateLimited      = &Error{StatusCode: http.StatusTooManyRequests}
+	ErrUnavailable      = &Error{StatusCode: http.StatusServiceUnavailable}
+)
+
+// ErasedError is the 410 for a student that was erased, the server answers with the tombstone
+// instead of an error message
+type ErasedError struct {
+	Erasure Erasure
+}
+
+func (e *ErasedError) Error() string {
+	return fmt.Sprintf("students api: student %d was erased at %s", e.Erasure.StudentId, e.Erasure.ErasedAt.Format(time.RFC3339))
+}
+
+func (e *ErasedError) Is(target error) bool { return target == ErrGone }
+
+// errorBody is what utils/response writes for every error
+type errorBody struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// parseError reads an error answer, bodies that aren't the json error format [a proxy's html page,
+// http.Error's plain text] are kept as the message as they are
+func parseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	apiErr := &Error{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+
+	var body errorBody
+	if err := json.Unmarshal(data, &body); err == nil && body.Status != "" {
+		apiErr.Message = body.Error
+		return apiErr
+	}
+
+	if resp.StatusCode == http.StatusGone {
+		var erasure Erasure
+		if err := json.Unmarshal(data, &erasure); err == nil && erasure.Digest != "" {
+			return &ErasedError{Erasure: erasure}
+		}
+	}
+
+	apiErr.Message = strings.TrimSpace(string(data))
+	return apiErr
+}
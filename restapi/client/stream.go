@@ -0,0 +1,94 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrStreamDropped is yielded when the server cut the stream because the events were read too slowly,
+// EventsAfter with the id of the last event seen picks up where it stopped
+var ErrStreamDropped = errors.New("students api: event stream dropped, consumer too slow")
+
+// Events follows the live change feed from now on, the loop ends when ctx is cancelled [ctx.Err() is
+// yielded], the stream ends or fails
+// the connection stays open for as long as the loop runs, so the http client must not have a Timeout
+func (c *Client) Events(ctx context.Context) iter.Seq2[Event, error] {
+	return c.events(ctx, nil)
+}
+
+// EventsAfter replays the events after lastEventId first [0 for all of them], then follows the feed
+func (c *Client) EventsAfter(ctx context.Context, lastEventId int64) iter.Seq2[Event, error] {
+	return c.events(ctx, http.Header{"Last-Event-ID": {strconv.FormatInt(lastEventId, 10)}})
+}
+
+func (c *Client) events(ctx context.Context, header http.Header) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		resp, err := c.send(ctx, call{
+			method: http.MethodGet,
+			path:   apiPath("/students/stream"),
+			header: header,
+		})
+		if err != nil {
+			yield(Event{}, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		//server-sent events: "field: value" lines, a blank line ends an event, ":" lines are heartbeats
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+
+		var name string
+		var data []string
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			if line != "" {
+				field, value, _ := strings.Cut(line, ":")
+				value = strings.TrimPrefix(value, " ")
+				switch field {
+				case "event":
+					name = value
+				case "data":
+					data = append(data, value)
+				}
+				continue
+			}
+
+			if len(data) == 0 {
+				continue
+			}
+			payload := strings.Join(data, "\n")
+			kind := name
+			name, data = "", nil
+
+			if kind == "dropped" {
+				yield(Event{}, ErrStreamDropped)
+				return
+			}
+
+			var event Event
+			if err := json.Unmarshal([]byte(payload), &event); err != nil {
+				yield(Event{}, fmt.Errorf("client: decoding event: %w", err))
+				return
+			}
+			if !yield(event, nil) {
+				return
+			}
+		}
+
+		switch {
+		case ctx.Err() != nil:
+			yield(Event{}, ctx.Err())
+		case scanner.Err() != nil:
+			yield(Event{}, fmt.Errorf("client: reading event stream: %w", scanner.Err()))
+		}
+	}
+}
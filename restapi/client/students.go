@@ -0,0 +1,267 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/shivakr07/students-api/internal/patch"
+)
+
+// MaxPageSize is the biggest page the server hands out
+const MaxPageSize = 100
+
+// CreateStudent returns the id of the new student
+func (c *Client) CreateStudent(ctx context.Context, student Student) (int64, error) {
+	var created struct {
+		Id int64 `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, apiPath("/students"), nil, student, &created)
+	return created.Id, err
+}
+
+func (c *Client) Student(ctx context.Context, id int64) (Student, error) {
+	var student Student
+	err := c.do(ctx, http.MethodGet, apiPath("/students/%d", id), nil, nil, &student)
+	return student, err
+}
+
+// ListOptions narrows a list, Filter is the ?filter= language [age>=18 and last_name~"sha"]
+// a zero Limit is every student for ListStudents, and pages of MaxPageSize for Students
+type ListOptions struct {
+	Filter string
+	Limit  int
+	Offset int
+}
+
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	if o.Filter != "" {
+		query.Set("filter", o.Filter)
+	}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		query.Set("offset", strconv.Itoa(o.Offset))
+	}
+	return query
+}
+
+// ListStudents is one page [or everything when Limit is 0], in id order
+func (c *Client) ListStudents(ctx context.Context, opts ListOptions) ([]Student, error) {
+	var students []Student
+	err := c.do(ctx, http.MethodGet, apiPath("/students"), opts.query(), nil, &students)
+	return students, err
+}
+
+// Students goes through the list page by page, Limit is the page size, the loop stops at the first
+// error [which is yielded]
+// pages are read by offset, students created or deleted while it runs can shift a page
+func (c *Client) Students(ctx context.Context, opts ListOptions) iter.Seq2[Student, error] {
+	if opts.Limit <= 0 || opts.Limit > MaxPageSize {
+		opts.Limit = MaxPageSize
+	}
+
+	return func(yield func(Student, error) bool) {
+		for {
+			page, err := c.ListStudents(ctx, opts)
+			if err != nil {
+				yield(Student{}, err)
+				return
+			}
+
+			for _, student := range page {
+				if !yield(student, nil) {
+					return
+				}
+			}
+
+			if len(page) < opts.Limit {
+				return
+			}
+			opts.Offset += len(page)
+		}
+	}
+}
+
+// MergePatch changes the fields set in body [RFC 7396, a null removes a field], a map or a struct
+// with omitempty fields, and returns the student as it is now
+func (c *Client) MergePatch(ctx context.Context, id int64, body any) (Student, error) {
+	return c.patch(ctx, id, patch.MergePatchType, body)
+}
+
+// JSONPatch applies the operations in one transaction [RFC 6902], a failed "test" is ErrConflict
+func (c *Client) JSONPatch(ctx context.Context, id int64, ops []PatchOperation) (Student, error) {
+	return c.patch(ctx, id, patch.JSONPatchType, ops)
+}
+
+func (c *Client) patch(ctx context.Context, id int64, contentType string, body any) (Student, error) {
+	req, err := jsonCall(http.MethodPatch, apiPath("/students/%d", id), body)
+	if err != nil {
+		return Student{}, err
+	}
+	req.contentType = contentType
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return Student{}, err
+	}
+	defer resp.Body.Close()
+
+	var student Student
+	return student, decode(resp, &student)
+}
+
+func (c *Client) DeleteStudent(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, apiPath("/students/%d", id), nil, nil, nil)
+}
+
+// StatsOptions: Buckets are the age histogram boundaries [the server's default when empty],
+// GroupBy is "email_domain" or empty
+type StatsOptions struct {
+	Buckets []int
+	GroupBy string
+	Filter  string
+}
+
+func (c *Client) Stats(ctx context.Context, opts StatsOptions) (StudentStats, error) {
+	query := url.Values{}
+	if len(opts.Buckets) > 0 {
+		bounds := make([]string, len(opts.Buckets))
+		for i, b := range opts.Buckets {
+			bounds[i] = strconv.Itoa(b)
+		}
+		query.Set("buckets", strings.Join(bounds, ","))
+	}
+	if opts.GroupBy != "" {
+		query.Set("group_by", opts.GroupBy)
+	}
+	if opts.Filter != "" {
+		query.Set("filter", opts.Filter)
+	}
+
+	var stats StudentStats
+	err := c.do(ctx, http.MethodGet, apiPath("/students/stats"), query, nil, &stats)
+	return stats, err
+}
+
+// Batch runs the operations in one transaction, a failed batch isn't an error here: check
+// Committed and the status of every result
+// with continueOnError the operations that work are kept and the failed ones are reported
+func (c *Client) Batch(ctx context.Context, ops []BatchOperation, continueOnError bool) (BatchResponse, error) {
+	req, err := jsonCall(http.MethodPost, apiPath("/batch"), struct {
+		Operations      []BatchOperation `json:"operations"`
+		ContinueOnError bool             `json:"continue_on_error"`
+	}{ops, continueOnError})
+	if err != nil {
+		return BatchResponse{}, err
+	}
+	req.accept = []int{http.StatusUnprocessableEntity}
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return BatchResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var result BatchResponse
+	return result, decode(resp, &result)
+}
+
+// UploadPhoto replaces the student's photo [jpeg, png or gif], the server checks the type from the content
+func (c *Client) UploadPhoto(ctx context.Context, id int64, filename string, photo io.Reader) (Photo, error) {
+	//the form is built in memory so a retry can send it again, photos are small [photos.max_bytes]
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("photo", filename)
+	if err != nil {
+		return Photo{}, fmt.Errorf("client: %w", err)
+	}
+	if _, err := io.Copy(part, photo); err != nil {
+		return Photo{}, fmt.Errorf("client: reading photo: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return Photo{}, fmt.Errorf("client: %w", err)
+	}
+
+	resp, err := c.send(ctx, call{
+		method:      http.MethodPut,
+		path:        apiPath("/students/%d/photo", id),
+		body:        body.Bytes(),
+		contentType: form.FormDataContentType(),
+	})
+	if err != nil {
+		return Photo{}, err
+	}
+	defer resp.Body.Close()
+
+	var meta Photo
+	return meta, decode(resp, &meta)
+}
+
+// Photo returns the photo and its content type, thumb asks for the thumbnail instead
+func (c *Client) Photo(ctx context.Context, id int64, thumb bool) ([]byte, string, error) {
+	query := url.Values{}
+	if thumb {
+		query.Set("size", "thumb")
+	}
+
+	data, header, err := c.download(ctx, apiPath("/students/%d/photo", id), query)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, header.Get("Content-Type"), nil
+}
+
+func (c *Client) DeletePhoto(ctx context.Context, id int64) error {
+	return c.do(ctx, http.MethodDelete, apiPath("/students/%d/photo", id), nil, nil, nil)
+}
+
+// Export is everything held about the student, an erased student is an *ErasedError
+func (c *Client) Export(ctx context.Context, id int64) (StudentExport, error) {
+	var export StudentExport
+	err := c.do(ctx, http.MethodGet, apiPath("/students/%d/export", id), nil, nil, &export)
+	return export, err
+}
+
+// ExportZip is the export as a zip archive, student.json and the photo file
+func (c *Client) ExportZip(ctx context.Context, id int64) ([]byte, error) {
+	data, _, err := c.download(ctx, apiPath("/students/%d/export", id), url.Values{"format": {"zip"}})
+	return data, err
+}
+
+// Erase removes the student for good and returns the tombstone, reason is stored with it so it
+// must not say anything about the student
+func (c *Client) Erase(ctx context.Context, id int64, reason string) (Erasure, error) {
+	var body any
+	if reason != "" {
+		body = map[string]string{"reason": reason}
+	}
+
+	var erasure Erasure
+	err := c.do(ctx, http.MethodPost, apiPath("/students/%d/erase", id), nil, body, &erasure)
+	return erasure, err
+}
+
+// download reads a whole non-json answer
+func (c *Client) download(ctx context.Context, path string, query url.Values) ([]byte, http.Header, error) {
+	resp, err := c.send(ctx, call{method: http.MethodGet, path: path, query: query})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("client: %w", err)
+	}
+	return data, resp.Header, nil
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"time"
+
+	v2 "github.com/shivakr07/students-api/internal/api/v2"
+	"github.com/shivakr07/students-api/internal/backup"
+	"github.com/shivakr07/students-api/internal/patch"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// the json shapes are the server's own types, aliased so they can't drift apart from the api
+
+// Student is the v2 form: first/last name and a date of birth, Age is worked out by the server
+type Student = v2.Student
+
+// Date is a date of birth, "2006-01-02" on the wire
+type Date = types.Date
+
+var ParseDate = types.ParseDate
+
+type (
+	Course       = types.Course
+	Enrollment   = types.Enrollment
+	Assessment   = types.Assessment
+	Score        = types.Score
+	StudentScore = types.StudentScore
+	Transcript   = types.Transcript
+	StudentStats = types.StudentStats
+	Photo        = types.Photo
+	Erasure      = types.Erasure
+	Disclosure   = types.Disclosure
+	Event        = types.Event
+	Webhook      = types.Webhook
+	Delivery     = types.Delivery
+	BatchResult  = types.BatchResult
+	BackupInfo   = backup.Info
+
+	// PatchOperation is one step of a JSON Patch [RFC 6902]
+	PatchOperation = patch.Operation
+)
+
+// event types of the change feed and the webhooks
+const (
+	EventStudentCreated = types.EventStudentCreated
+	EventStudentUpdated = types.EventStudentUpdated
+	EventStudentDeleted = types.EventStudentDeleted
+	EventStudentErased  = types.EventStudentErased
+)
+
+// statuses of a webhook delivery
+const (
+	DeliveryPending   = types.DeliveryPending
+	DeliveryDelivered = types.DeliveryDelivered
+	DeliveryDead      = types.DeliveryDead
+)
+
+// batch operations
+const (
+	BatchCreate = types.BatchCreate
+	BatchUpdate = types.BatchUpdate
+	BatchDelete = types.BatchDelete
+)
+
+// BatchOperation is one step of a batch, Student is the whole student for create and update
+type BatchOperation struct {
+	Op      string   `json:"op"`
+	Id      int64    `json:"id,omitempty"`
+	Student *Student `json:"student,omitempty"`
+}
+
+// BatchResponse: Committed is false when the batch was rolled back [or, with ContinueOnError, when
+// some operations failed], each result has the status the single request would have had
+type BatchResponse = types.BatchResponse
+
+// StudentExport is everything the server holds about one student
+type StudentExport struct {
+	ExportedAt  time.Time      `json:"exported_at"`
+	Student     Student        `json:"student"`
+	Enrollments []Enrollment   `json:"enrollments"`
+	Scores      []StudentScore `json:"scores"`
+	Photo       *Photo         `json:"photo"`
+	Events      []Event        `json:"events"`
+	Disclosures []Disclosure   `json:"disclosures"`
+}
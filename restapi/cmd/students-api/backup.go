@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/backup"
+	"github.com/shivakr07/students-api/internal/storage/sqlite"
+)
+
+// students-api backup [-out file]
+// without -out the snapshot goes to the backup dir from the config and old ones are rotated
+func runBackup(args []string) int {
+	fs, global := newFlagSet("backup")
+	out := fs.String("out", "", "write the backup to this file instead of the backup dir")
+	fs.Parse(args)
+
+	cfg, err := global.load()
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+
+	db, err := sqlite.New(cfg)
+	if err != nil {
+		slog.Error("can't open storage", slog.String("error", err.Error()))
+		return 1
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	if *out != "" {
+		if err := db.Backup(ctx, *out); err != nil {
+			slog.Error("backup failed", slog.String("error", err.Error()))
+			return 1
+		}
+		sum, err := backup.WriteChecksum(*out)
+		if err != nil {
+			slog.Error("can't write checksum", slog.String("error", err.Error()))
+			return 1
+		}
+
+		slog.Info("backup written", slog.String("path", *out), slog.String("sha256", sum))
+		return 0
+	}
+
+	if _, err := backup.New(db, cfg.Backup).Snapshot(ctx); err != nil {
+		slog.Error("backup failed", slog.String("error", err.Error()))
+		return 1
+	}
+
+	return 0
+}
+
+// students-api restore (-in file | -at time)
+// -at restores the latest snapshot from the backup dir taken at or before that time, the database
+// ends up as it was when that snapshot was taken [changes between it and -at are not replayed]
+func runRestore(args []string) int {
+	fs, global := newFlagSet("restore")
+	in := fs.String("in", "", "backup file to restore")
+	at := fs.String("at", "", "restore the latest snapshot taken at or before this RFC3339 time [nothing after the snapshot is replayed]")
+	fs.Parse(args)
+
+	if (*in == "") == (*at == "") {
+		fmt.Fprintln(os.Stderr, "exactly one of -in or -at is required")
+		return 2
+	}
+
+	cfg, err := global.load()
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+
+	path := *in
+	if *at != "" {
+		t, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -at time: %s\n", err)
+			return 2
+		}
+
+		picked, err := backup.At(cfg.Backup.Dir, t)
+		if err != nil {
+			slog.Error("no backup to restore", slog.String("error", err.Error()))
+			return 1
+		}
+		path = picked.Path
+		slog.Info("restoring the latest snapshot before -at", slog.String("snapshot_taken_at", picked.CreatedAt.Format(time.RFC3339)))
+	}
+
+	//never restore a file we can't prove is the one we wrote
+	if err := backup.VerifyChecksum(path); err != nil {
+		slog.Error("refusing to restore", slog.String("error", err.Error()))
+		return 1
+	}
+
+	if err := sqlite.Restore(context.Background(), path, cfg.StoragePath); err != nil {
+		slog.Error("restore failed", slog.String("error", err.Error()))
+		return 1
+	}
+
+	slog.Info("database restored", slog.String("from", path), slog.String("to", cfg.StoragePath))
+	return 0
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// students-api config validate
+// loads the config exactly like serve would (defaults, file, env, flags), validates it and prints the effective result
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "usage: students-api config validate [flags]")
+		return 2
+	}
+
+	fs, global := newFlagSet("config validate")
+	fs.Parse(args[1:])
+
+	cfg, err := global.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config is invalid: %s\n", err)
+		return 1
+	}
+
+	//secrets are printed as [REDACTED], the dump is meant to be pasted into tickets
+	out, err := cfg.Dump()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "can't print config: %s\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "config %s is valid\n", global.configPath)
+	os.Stdout.Write(out)
+	return 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/shivakr07/students-api/internal/fieldcrypt"
+)
+
+// students-api encryption keygen|reencrypt
+func runEncryption(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: students-api encryption keygen|reencrypt [flags]")
+		return 2
+	}
+
+	fs, global := newFlagSet("encryption " + args[0])
+	batch := fs.Int("batch", 500, "rows per transaction when re-encrypting")
+	fs.Parse(args[1:])
+
+	switch args[0] {
+	case "keygen":
+		//goes into encryption.keys [or index_key], printed and nowhere else
+		key, err := fieldcrypt.GenerateKey()
+		if err != nil {
+			slog.Error("can't generate key", slog.String("error", err.Error()))
+			return 1
+		}
+		fmt.Println(key)
+
+	case "reencrypt":
+		//the same work the server does in the background, all at once [e.g. before an old key is removed]
+		if *batch < 1 {
+			fmt.Fprintln(os.Stderr, "-batch must be at least 1")
+			return 2
+		}
+
+		db, err := global.openStorage()
+		if err != nil {
+			slog.Error(err.Error())
+			return 1
+		}
+		defer db.Close()
+
+		total := 0
+		for {
+			n, err := db.Reencrypt(*batch)
+			if err != nil {
+				slog.Error("re-encryption failed", slog.String("error", err.Error()), slog.Int("rows", total))
+				return 1
+			}
+			if n == 0 {
+				break
+			}
+			total += n
+		}
+
+		slog.Info("re-encryption done", slog.Int("rows", total))
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown encryption command %q\n", args[0])
+		return 2
+	}
+
+	return 0
+}
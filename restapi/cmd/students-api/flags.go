@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/storage/sqlite"
+)
+
+// globalFlags are accepted by every subcommand
+// precedence is always: flag, then environment variable, then the config file
+type globalFlags struct {
+	configPath  string
+	storagePath string
+	env         string
+	set         setFlags
+}
+
+// setFlags collects repeated -set key=value flags
+type setFlags map[string]string
+
+func (s setFlags) String() string { return fmt.Sprint(map[string]string(s)) }
+
+func (s setFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	s[key] = val
+	return nil
+}
+
+// newFlagSet returns a flag set for a subcommand with the shared flags already defined on it
+func newFlagSet(name string) (*flag.FlagSet, *globalFlags) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	g := &globalFlags{set: setFlags{}}
+
+	//the path comes from CONFIG_PATH unless -config is given, same as before the subcommands existed
+	fs.StringVar(&g.configPath, "config", os.Getenv("CONFIG_PATH"), "path to the configuration file [env CONFIG_PATH]")
+	fs.StringVar(&g.storagePath, "storage-path", "", "override storage_path from the config [env STORAGE_PATH]")
+	fs.StringVar(&g.env, "env", "", "override env from the config [env ENV]")
+	fs.Var(g.set, "set", "override any setting by its yaml path, like -set http_server.address=:9000 (repeatable)")
+
+	return fs, g
+}
+
+// overrides turns the flags into the last layer config.Load applies
+func (g *globalFlags) overrides() map[string]string {
+	overrides := map[string]string{}
+	for key, value := range g.set {
+		overrides[key] = value
+	}
+
+	//the dedicated flags win over a -set for the same setting
+	if g.storagePath != "" {
+		overrides["storage_path"] = g.storagePath
+	}
+	if g.env != "" {
+		overrides["env"] = g.env
+	}
+
+	return overrides
+}
+
+func (g *globalFlags) load() (*config.Config, error) {
+	return config.Load(g.configPath, g.overrides())
+}
+
+// openStorage loads the config and opens the sqlite store, for the commands that only need the db
+func (g *globalFlags) openStorage() (*sqlite.Sqlite, error) {
+	cfg, err := g.load()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sqlite.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("can't open storage: %w", err)
+	}
+
+	return db, nil
+}
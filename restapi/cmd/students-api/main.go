@@ -1,121 +1,66 @@
 package main
 
 import (
-	"context"
-	"log"
-	"log/slog"
-	"net/http"
+	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
-
-	"github.com/shivakr07/students-api/internal/config"
-	"github.com/shivakr07/students-api/internal/handlers/student"
-	"github.com/shivakr07/students-api/internal/storage/sqlite"
+	"strings"
 )
 
-func main() {
-	//load config
-	// custom logger [if you have any]
-	// db setup
-	// router setup
-	// server setup
-
-	//load config
-	cfg := config.MustLoad()
+// every subcommand gets the arguments after its name and returns the exit code
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) int
+}
 
-	//db setup [call the New method defined in the sqlite]
-	storage, err := sqlite.New(cfg)
-	if err != nil {
-		log.Fatal(err)
+var commands []command
+
+func init() {
+	//filled in init because some commands (help) refer back to this list
+	commands = []command{
+		{"serve", "start the http server (default when no command is given)", runServe},
+		{"migrate", "apply pending database migrations", runMigrate},
+		{"seed", "insert sample students", runSeed},
+		{"import", "import students from a csv or json file", runImport},
+		{"export", "export students to a csv or json file", runExport},
+		{"user", "manage users: add, list, delete", runUser},
+		{"apikey", "manage api keys: create, list, revoke", runAPIKey},
+		{"config", "config validate: check a config file and print the result", runConfig},
+		{"backup", "write a consistent snapshot of the database", runBackup},
+		{"restore", "restore the database from a snapshot [-at: the latest one before a time]", runRestore},
+		{"encryption", "field encryption: keygen, reencrypt", runEncryption},
+		{"help", "show this help", runHelp},
 	}
+}
 
-	slog.Info("storage initialized", slog.String("env", cfg.Env), slog.String("version", "1.0.0")) //here we are hardcoding this value but later you can add actual values
-	// now db is ready and now if we run the app then table should be created
-	// we can use gui apps for db's like tableplus
-
-	//router setup
-	//we will use net/http inbuilt package
-	router := http.NewServeMux()
-	//now we can make url's
-	// router.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
-	// 	w.Write([]byte("Welcome to students api"))
-	// 	//in write method we can add bytes
-	// })
-
-	//since now we will use reference of that function here which is defined in the student.go using New
-	//we keep resources as plural in the REST
-	// router.HandleFunc("POST /api/students", student.New())
-
-	//we want to use the database in the new function now so we need to receive this as a dependency [in the func definition]
-	router.HandleFunc("POST /api/students", student.New(storage))
-	//time to create one more route
-	router.HandleFunc("GET /api/students/{id}", student.GetById(storage))
-	router.HandleFunc("GET /api/students", student.GetList(storage))
+func main() {
+	args := os.Args[1:]
 
-	//setup server
-	server := http.Server{
-		Addr:    cfg.Addr,
-		Handler: router,
+	//no command, or only flags [the old `students-api -config file` form] means serve
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		os.Exit(runServe(args))
 	}
 
-	// fmt.Printf("server started %s", cfg.HTTPServer.Addr)
-	slog.Info("server started", slog.String("address", cfg.Addr))
-
-	// but generally this is not how we keep our server
-	// because if some interruption happens from user like C^Signal = interrupt or other then it will immediate terminate the server, but there might be some request which is in processing so first we need to complete that and then shutdown [called graceful shutdown]
-
-	// err := server.ListenAndServe()
-	// //this is blocking so keeping print before this
-	// if err != nil {
-	// 	log.Fatal("failed to start server")
-	// }
-	// so we keep our server in other go routine
-
-	// --- SERVER WITH GRACEFUL SHUTDOWN ---
-	// to synchronize the go routine we use wg/channel
-
-	done := make(chan os.Signal, 1) //buffered
-
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	// if any mentioned signal comes from os or user then notify in the channel
-
-	go func() {
-		err := server.ListenAndServe()
-		if err != nil {
-			log.Fatal("failed to start server")
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			os.Exit(cmd.run(args[1:]))
 		}
-	}()
-
-	<-done
-	// it will be unblocked when channel receives the signal
-
-	//now then we will shutdown...
-	// structured logging
-	slog.Info("shutting down the server")
-	// server.Shutdown()
-	// this gracefully shutdown the server but still it have some problem
-	// it will take some [as of processing if any ongoing request]
-	// or sometime it gets infinitely hang so our PORT will be locked
-
-	// so we use timer, like if after this time shutdown didn't happen then report that, for that we use CONTEXT [a package][we pass an empty context/starting point [it is just a container to store anything]]
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	//cancel timeout
-	defer cancel()
+	}
 
-	//this ctx will be passed to the server
-	// err := server.Shutdown(ctx)
-	// if err != nil {
-	// 	slog.Error("failed to shutdown the server", slog.String("error", err.Error()))
-	// }
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	runHelp(nil)
+	os.Exit(2)
+}
 
-	//SHORTFORM
-	if err := server.Shutdown(ctx); err != nil {
-		slog.Error("failed to shutdown the server", slog.String("error", err.Error()))
+func runHelp(args []string) int {
+	fmt.Fprintln(os.Stderr, "usage: students-api <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.usage)
 	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "every command accepts -config, -storage-path, -env and -set key=value, run `students-api <command> -h` for the rest")
 
-	slog.Info("server shutown successfully")
-	// fmt.Println("server started")
-	//to test fo run cmd/../main.go -config config/local.yaml
+	return 0
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/storage/sqlite"
+)
+
+// students-api migrate [-status]
+func runMigrate(args []string) int {
+	fs, global := newFlagSet("migrate")
+	status := fs.Bool("status", false, "only list the migrations and whether they are applied")
+	fs.Parse(args)
+
+	cfg, err := global.load()
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+
+	//Open and not New: New refuses to work on an outdated schema when auto_migrate is off
+	db, err := sqlite.Open(cfg)
+	if err != nil {
+		slog.Error("can't open storage", slog.String("error", err.Error()))
+		return 1
+	}
+	defer db.Close()
+
+	if *status {
+		migrations, err := db.Migrations()
+		if err != nil {
+			slog.Error("can't read migrations", slog.String("error", err.Error()))
+			return 1
+		}
+
+		for _, m := range migrations {
+			applied := "pending"
+			if m.AppliedAt != nil {
+				applied = m.AppliedAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(os.Stdout, "%4d  %-30s %s\n", m.Version, m.Name, applied)
+		}
+		return 0
+	}
+
+	applied, err := db.Migrate()
+	for _, version := range applied {
+		slog.Info("migration applied", slog.Int("version", version))
+	}
+	if err != nil {
+		slog.Error("migration failed", slog.String("error", err.Error()))
+		return 1
+	}
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		slog.Error("can't read schema version", slog.String("error", err.Error()))
+		return 1
+	}
+
+	slog.Info("database is up to date", slog.Int("version", version))
+	return 0
+}
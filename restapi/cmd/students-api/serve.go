@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/admin"
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/fieldcrypt"
+	"github.com/shivakr07/students-api/internal/lifecycle"
+	"github.com/shivakr07/students-api/internal/logging"
+	"github.com/shivakr07/students-api/internal/server"
+	"github.com/shivakr07/students-api/internal/storage/sqlite"
+	"github.com/shivakr07/students-api/internal/webhook"
+)
+
+// exit codes of serve, so a supervisor can tell a clean stop from a crash [2 is a usage error, see main]
+const (
+	exitOK       = 0
+	exitStartup  = 1 //bad config, the port is taken...
+	exitFailed   = 3 //a component failed while serving
+	exitShutdown = 4 //stopped on a signal, but something didn't stop cleanly in time
+)
+
+// runServe is `students-api serve`, it is also what runs when no subcommand is given
+func runServe(args []string) int {
+	//load config
+	// custom logger [if you have any]
+	// db setup
+	// router setup
+	// server setup
+
+	fs, global := newFlagSet("serve")
+	fs.Parse(args)
+
+	//load config, until the logger is set up errors go to stderr through the standard log
+	cfg, err := global.load()
+	if err != nil {
+		log.Print(err)
+		return exitStartup
+	}
+
+	//the logger is the first thing the config decides, its level can change later [SIGHUP or the admin api]
+	logger, err := logging.New(cfg.Log)
+	if err != nil {
+		log.Print(err)
+		return exitStartup
+	}
+	//a return from here on flushes the log file, os.Exit [log.Fatal] would skip this
+	defer logger.Close()
+	slog.SetDefault(logger.Logger)
+
+	if dump, err := cfg.Dump(); err == nil {
+		slog.Debug("effective config\n" + string(dump))
+	}
+
+	//db setup [call the New method defined in the sqlite]
+	storage, err := sqlite.New(cfg)
+	if err != nil {
+		slog.Error("can't open the database", slog.String("error", err.Error()))
+		return exitStartup
+	}
+
+	//version and commit are on every line already, they come from the build [see buildinfo]
+	slog.Info("storage initialized", slog.String("env", cfg.Env))
+	// now db is ready and now if we run the app then table should be created
+	// we can use gui apps for db's like tableplus
+
+	//from here on the database is open, a setup error closes it on the way out too
+	setupFailed := func(err error) int {
+		slog.Error("can't set up the server", slog.String("error", err.Error()))
+		storage.Close()
+		return exitStartup
+	}
+
+	//router setup, every route and the middleware in front of them [see the server package]
+	app, err := server.New(cfg, storage, logger)
+	if err != nil {
+		return setupFailed(err)
+	}
+
+	//setup server
+	httpServer := &http.Server{
+		Addr:    cfg.Addr,
+		Handler: app.Handler,
+	}
+
+	//`kill -HUP <pid>` re-reads the config, log level and rate limits apply without a restart
+	reloader := config.NewReloader(global.configPath, global.overrides(), cfg)
+	//the level only follows the file when log.level itself changed, so a level set through the admin api
+	//survives a reload that was about something else
+	fileLevel := cfg.Log.Level
+	reloader.OnReload(func(next *config.Config) {
+		if next.Log.Level != fileLevel {
+			fileLevel = next.Log.Level
+			if err := logger.SetLevel(next.Log.Level); err != nil {
+				slog.Error("can't apply log level", slog.String("error", err.Error()))
+			}
+		}
+		app.Limiter.Update(next.RateLimit)
+	})
+
+	//open streams never finish on their own, so end them when Shutdown starts or it would wait for them forever
+	httpServer.RegisterOnShutdown(app.Broker.Close)
+
+	// --- SERVER WITH GRACEFUL SHUTDOWN ---
+	// if some interruption happens from user like C^Signal = interrupt then we don't terminate right away, there might
+	// be some request which is in processing so first we need to complete that and then shutdown [called graceful shutdown]
+	// the lifecycle manager does that for every part of the server: started in this order, stopped in reverse,
+	// each one with its own deadline so a stuck one can't keep the PORT locked forever
+	manager := lifecycle.New()
+
+	//added first so it is closed last, after everything that uses it has stopped
+	manager.Add(lifecycle.Closer("sqlite", storage.Close))
+
+	manager.Add(lifecycle.Worker("event broker", app.Broker.Run, 0))
+	if cfg.Backup.Interval > 0 {
+		//a snapshot in progress is cancelled, it gets a bit longer to clean up after itself
+		manager.Add(lifecycle.Worker("backup scheduler", app.Backups.Run, 10*time.Second))
+	}
+	if cfg.Webhooks.Enabled {
+		manager.Add(lifecycle.Worker("webhook dispatcher", webhook.New(storage, cfg.Webhooks).Run, cfg.Webhooks.Timeout))
+	}
+	if len(cfg.Encryption.Keys) > 0 && cfg.Encryption.Interval > 0 {
+		//old rows are moved to the active key [and plain ones sealed] a batch at a time, while serving
+		manager.Add(lifecycle.Worker("re-encryption", fieldcrypt.NewReencryptor(storage, cfg.Encryption).Run, 0))
+	}
+	manager.Add(lifecycle.Worker("config reloader", func(ctx context.Context) {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				slog.Info("reloading config", slog.String("path", global.configPath))
+				if err := reloader.Reload(); err != nil {
+					slog.Error("config reload failed, keeping the running config", slog.String("error", err.Error()))
+				}
+			}
+		}
+	}, 0))
+
+	//profiling, runtime/build info and the admin api for operators, on its own address [admin.address]
+	//it stops after the main server, so it can still be profiled while that one drains
+	if cfg.Admin.Enabled {
+		adminServer := admin.NewServer(cfg.Admin, reloader.Current, app.Health, app.Admin, app.Users)
+		manager.Add(lifecycle.HTTPServer("admin server", adminServer, 0))
+	}
+
+	//last to start, requests only come in once everything behind them runs
+	manager.Add(lifecycle.HTTPServer("http server", httpServer, cfg.HTTPServer.ShutdownTimeout))
+
+	// if any of these signals comes from os or user then ctx is done and the shutdown starts
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	//a second ctrl-c while draining kills the process the usual way
+	context.AfterFunc(ctx, stop)
+
+	slog.Info("starting the server", slog.String("address", cfg.Addr))
+
+	err = manager.Run(ctx)
+	switch {
+	case err == nil:
+		slog.Info("server shutdown successfully")
+		return exitOK
+	case errors.Is(err, lifecycle.ErrStart):
+		slog.Error("server didn't start", slog.String("error", err.Error()))
+		return exitStartup
+	case errors.Is(err, lifecycle.ErrFailed):
+		slog.Error("server stopped after a failure", slog.String("error", err.Error()))
+		return exitFailed
+	default:
+		slog.Error("server shutdown incomplete", slog.String("error", err.Error()))
+		return exitShutdown
+	}
+}
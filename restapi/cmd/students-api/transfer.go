@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shivakr07/students-api/internal/handlers/student"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// columns of the csv format, the same for import and export
+var csvHeader = []string{"id", "name", "first_name", "last_name", "email", "age", "date_of_birth"}
+
+// students-api seed [-count n]
+func runSeed(args []string) int {
+	fs, global := newFlagSet("seed")
+	count := fs.Int("count", 10, "how many sample students to insert")
+	fs.Parse(args)
+
+	db, err := global.openStorage()
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	defer db.Close()
+
+	names := []string{"Alan", "Evans", "Priya", "Rahul", "Maria", "Chen", "Fatima", "Lucas", "Aisha", "Noah"}
+
+	for i := 0; i < *count; i++ {
+		name := names[i%len(names)]
+		email := fmt.Sprintf("%s.%d@example.com", strings.ToLower(name), i+1)
+		age := 18 + i%12
+
+		if _, err := db.CreateStudent(types.Student{Name: name, Email: email, Age: age}); err != nil {
+			slog.Error("seed failed", slog.Int("inserted", i), slog.String("error", err.Error()))
+			return 1
+		}
+	}
+
+	slog.Info("seeded students", slog.Int("count", *count))
+	return 0
+}
+
+// students-api import -file students.csv [-format csv|json] [-dry-run]
+// every row is validated like POST /api/students, nothing is written when any row is invalid
+func runImport(args []string) int {
+	fs, global := newFlagSet("import")
+	file := fs.String("file", "", "file to import, - for stdin")
+	format := fs.String("format", "", "csv or json, guessed from the file extension when empty")
+	dryRun := fs.Bool("dry-run", false, "only validate the file")
+	fs.Parse(args)
+
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "-file is required")
+		return 2
+	}
+
+	in := os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			slog.Error(err.Error())
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	students, err := readStudents(in, formatOf(*file, *format))
+	if err != nil {
+		slog.Error("can't read import file", slog.String("error", err.Error()))
+		return 1
+	}
+
+	invalid := 0
+	for i := range students {
+		//the same as storage does on write, so a record with a date of birth and no age is valid
+		students[i].Normalize(time.Now())
+		if err := student.Validate(students[i]); err != nil {
+			var validateErrors validator.ValidationErrors
+			if errors.As(err, &validateErrors) {
+				err = errors.New(response.ValidationError(validateErrors).Error)
+			}
+			slog.Error("invalid record", slog.Int("record", i+1), slog.String("error", err.Error()))
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		slog.Error("import aborted", slog.Int("invalid", invalid), slog.Int("total", len(students)))
+		return 1
+	}
+
+	if *dryRun {
+		slog.Info("import file is valid", slog.Int("records", len(students)))
+		return 0
+	}
+
+	db, err := global.openStorage()
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	defer db.Close()
+
+	for i, s := range students {
+		if _, err := db.CreateStudent(s); err != nil {
+			slog.Error("import failed", slog.Int("imported", i), slog.String("error", err.Error()))
+			return 1
+		}
+	}
+
+	slog.Info("imported students", slog.Int("count", len(students)))
+	return 0
+}
+
+// students-api export [-out file] [-format csv|json]
+func runExport(args []string) int {
+	fs, global := newFlagSet("export")
+	file := fs.String("out", "-", "file to write, - for stdout")
+	format := fs.String("format", "", "csv or json, guessed from the file extension when empty (json for stdout)")
+	fs.Parse(args)
+
+	db, err := global.openStorage()
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	defer db.Close()
+
+	students, err := db.GetStudents()
+	if err != nil {
+		slog.Error("can't read students", slog.String("error", err.Error()))
+		return 1
+	}
+
+	out := os.Stdout
+	if *file != "-" {
+		f, err := os.Create(*file)
+		if err != nil {
+			slog.Error(err.Error())
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := writeStudents(out, formatOf(*file, *format), students); err != nil {
+		slog.Error("export failed", slog.String("error", err.Error()))
+		return 1
+	}
+
+	if *file != "-" {
+		slog.Info("exported students", slog.Int("count", len(students)), slog.String("file", *file))
+	}
+	return 0
+}
+
+func formatOf(file string, format string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	if strings.EqualFold(filepath.Ext(file), ".csv") {
+		return "csv"
+	}
+	return "json"
+}
+
+func readStudents(r io.Reader, format string) ([]types.Student, error) {
+	switch format {
+	case "json":
+		var students []types.Student
+		if err := json.NewDecoder(r).Decode(&students); err != nil {
+			return nil, err
+		}
+		return students, nil
+
+	case "csv":
+		records, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, nil
+		}
+
+		//columns are found by header name so the order in the file doesn't matter, id is ignored
+		columns := map[string]int{}
+		for i, name := range records[0] {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		//name can come as first_name/last_name and age as date_of_birth, older exports only have name and age
+		if !hasColumn(columns, "name", "first_name") || !hasColumn(columns, "email") || !hasColumn(columns, "age", "date_of_birth") {
+			return nil, fmt.Errorf("csv header needs name or first_name, email and age or date_of_birth columns")
+		}
+
+		students := make([]types.Student, 0, len(records)-1)
+		for line, record := range records[1:] {
+			field := func(name string) string {
+				i, ok := columns[name]
+				if !ok || i >= len(record) {
+					return ""
+				}
+				return strings.TrimSpace(record[i])
+			}
+
+			s := types.Student{
+				Name:      field("name"),
+				FirstName: field("first_name"),
+				LastName:  field("last_name"),
+				Email:     field("email"),
+			}
+
+			if dob := field("date_of_birth"); dob != "" {
+				date, err := types.ParseDate(dob)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid date_of_birth: %w", line+2, err)
+				}
+				s.DateOfBirth = &date
+			}
+
+			//the age is worked out from the date of birth when there is one
+			if age := field("age"); age != "" && s.DateOfBirth == nil {
+				s.Age, err = strconv.Atoi(age)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid age %q", line+2, age)
+				}
+			}
+
+			students = append(students, s)
+		}
+		return students, nil
+	}
+
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// hasColumn reports whether the header has at least one of names
+func hasColumn(columns map[string]int, names ...string) bool {
+	for _, name := range names {
+		if _, ok := columns[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func writeStudents(w io.Writer, format string, students []types.Student) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if students == nil {
+			students = []types.Student{}
+		}
+		return enc.Encode(students)
+
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(csvHeader)
+		for _, s := range students {
+			dob := ""
+			if s.DateOfBirth != nil {
+				dob = s.DateOfBirth.String()
+			}
+			cw.Write([]string{strconv.FormatInt(s.Id, 10), s.Name, s.FirstName, s.LastName, s.Email,
+				strconv.Itoa(s.CurrentAge(time.Now())), dob})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
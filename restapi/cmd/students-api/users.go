@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/auth"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// students-api user add|list|delete
+func runUser(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: students-api user add|list|delete [flags]")
+		return 2
+	}
+
+	fs, global := newFlagSet("user " + args[0])
+	username := fs.String("username", "", "username")
+	role := fs.String("role", types.RoleStaff, "role of a new user: admin or staff")
+	password := fs.String("password", "", "password of a new user [env STUDENTS_API_PASSWORD, read from stdin when neither is set]")
+	fs.Parse(args[1:])
+
+	db, err := global.openStorage()
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "add":
+		if *username == "" {
+			fmt.Fprintln(os.Stderr, "-username is required")
+			return 2
+		}
+		if *role != types.RoleAdmin && *role != types.RoleStaff {
+			fmt.Fprintf(os.Stderr, "unknown role %q\n", *role)
+			return 2
+		}
+
+		pass, err := readPassword(*password)
+		if err != nil {
+			slog.Error(err.Error())
+			return 1
+		}
+
+		hash, err := auth.HashPassword(pass)
+		if err != nil {
+			slog.Error("can't hash password", slog.String("error", err.Error()))
+			return 1
+		}
+
+		id, err := db.CreateUser(*username, hash, *role)
+		if err != nil {
+			slog.Error("can't create user", slog.String("error", err.Error()))
+			return 1
+		}
+
+		slog.Info("user created", slog.String("id", fmt.Sprint(id)), slog.String("username", *username), slog.String("role", *role))
+
+	case "list":
+		users, err := db.GetUsers()
+		if err != nil {
+			slog.Error("can't list users", slog.String("error", err.Error()))
+			return 1
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tUSERNAME\tROLE\tCREATED")
+		for _, u := range users {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", u.Id, u.Username, u.Role, u.CreatedAt.Local().Format(time.DateTime))
+		}
+		tw.Flush()
+
+	case "delete":
+		if *username == "" {
+			fmt.Fprintln(os.Stderr, "-username is required")
+			return 2
+		}
+
+		if err := db.DeleteUser(*username); err != nil {
+			slog.Error("can't delete user", slog.String("error", err.Error()))
+			return 1
+		}
+
+		slog.Info("user deleted", slog.String("username", *username))
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown user command %q\n", args[0])
+		return 2
+	}
+
+	return 0
+}
+
+// students-api apikey create|list|revoke
+func runAPIKey(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: students-api apikey create|list|revoke [flags]")
+		return 2
+	}
+
+	fs, global := newFlagSet("apikey " + args[0])
+	username := fs.String("username", "", "owner of the new key")
+	name := fs.String("name", "", "what the new key is for, e.g. ci or billing")
+	id := fs.String("id", "", "id of the key to revoke")
+	fs.Parse(args[1:])
+
+	db, err := global.openStorage()
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "create":
+		if *username == "" || *name == "" {
+			fmt.Fprintln(os.Stderr, "-username and -name are required")
+			return 2
+		}
+
+		user, err := db.GetUserByUsername(*username)
+		if err != nil {
+			slog.Error(err.Error())
+			return 1
+		}
+
+		key, prefix, hash, err := auth.GenerateAPIKey()
+		if err != nil {
+			slog.Error("can't generate key", slog.String("error", err.Error()))
+			return 1
+		}
+
+		if _, err := db.CreateAPIKey(user.Id, *name, prefix, hash); err != nil {
+			slog.Error("can't store key", slog.String("error", err.Error()))
+			return 1
+		}
+
+		//the only time the key is ever visible, we keep just its hash
+		fmt.Fprintln(os.Stdout, key)
+		fmt.Fprintln(os.Stderr, "store this key now, it can't be shown again")
+
+	case "list":
+		keys, err := db.GetAPIKeys()
+		if err != nil {
+			slog.Error("can't list keys", slog.String("error", err.Error()))
+			return 1
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tPREFIX\tNAME\tUSER\tCREATED\tREVOKED")
+		for _, k := range keys {
+			revoked := "-"
+			if k.RevokedAt != nil {
+				revoked = k.RevokedAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", k.Id, k.Prefix, k.Name, k.Username, k.CreatedAt.Local().Format(time.DateTime), revoked)
+		}
+		tw.Flush()
+
+	case "revoke":
+		keyId, err := strconv.ParseInt(*id, 10, 64)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "-id must be the numeric id of a key")
+			return 2
+		}
+
+		if err := db.RevokeAPIKey(keyId); err != nil {
+			slog.Error("can't revoke key", slog.String("error", err.Error()))
+			return 1
+		}
+
+		slog.Info("api key revoked", slog.String("id", *id))
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown apikey command %q\n", args[0])
+		return 2
+	}
+
+	return 0
+}
+
+// readPassword takes the flag, then STUDENTS_API_PASSWORD, then one line of stdin
+func readPassword(flagValue string) (string, error) {
+	password := flagValue
+	if password == "" {
+		password = os.Getenv("STUDENTS_API_PASSWORD")
+	}
+	if password == "" {
+		fmt.Fprint(os.Stderr, "password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("can't read password: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	if len(password) < 8 {
+		return "", fmt.Errorf("password must be at least 8 characters")
+	}
+
+	return password, nil
+}
@@ -0,0 +1,183 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/auth"
+	"github.com/shivakr07/students-api/internal/buildinfo"
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/metrics"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// the admin listener is for operators, not clients: profiling, what is running and how it is configured,
+// and the admin api [webhooks, backups, log level]
+// it runs on its own address [admin.address] and the public listener doesn't serve any of it
+
+// started is when the process came up, for the uptime in /debug/runtime
+var started = time.Now()
+
+var errUnauthorized = errors.New("missing or wrong admin token or credentials")
+
+// NewServer builds the admin server, config returns the running config [it changes on SIGHUP],
+// health is the same check the public listener serves and api is the /admin routes
+// users is nil unless auth is enabled, then admin users get in with their own credentials too
+func NewServer(cfg config.Admin, current func() *config.Config, health http.HandlerFunc, api http.Handler, users storage.UserStorage) *http.Server {
+	mux := http.NewServeMux()
+
+	//net/http/pprof registers itself on the default mux, we don't serve that one so they are added here
+	//the index also serves the named profiles: /debug/pprof/heap, /debug/pprof/goroutine?debug=2...
+	mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+
+	mux.HandleFunc("GET /debug/goroutines", goroutines)
+	mux.HandleFunc("GET /debug/runtime", runtimeInfo)
+	mux.HandleFunc("GET /debug/buildinfo", buildInfo)
+	mux.HandleFunc("GET /debug/config", effectiveConfig(current))
+
+	mux.HandleFunc("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /healthz", health)
+	mux.Handle("/admin/", api)
+
+	return &http.Server{
+		Addr:    cfg.Addr,
+		Handler: requireToken(cfg.Token, users, mux),
+	}
+}
+
+// requireToken checks Authorization: Bearer <token>, or the credentials of an admin user when there
+// are users, with neither the listener is open [it is on localhost by default]
+func requireToken(token string, users storage.UserStorage, next http.Handler) http.Handler {
+	if token == "" && users == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token != "" && ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if users != nil {
+			if user, err := auth.Authenticate(users, r); err == nil && user.Role == types.RoleAdmin {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		response.WriteJson(w, http.StatusUnauthorized, response.GeneralError(errUnauthorized))
+	})
+}
+
+// goroutines dumps the stack of every goroutine as text, same as the goroutine profile with debug=2
+func goroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	//start small, runtime.Stack tells us when the buffer was too short by filling it completely
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			w.Write(buf[:n])
+			return
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+type runtimeStats struct {
+	Uptime       string `json:"uptime"`
+	Goroutines   int    `json:"goroutines"`
+	NumCPU       int    `json:"num_cpu"`
+	GOMAXPROCS   int    `json:"gomaxprocs"`
+	HeapAlloc    uint64 `json:"heap_alloc_bytes"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	Sys          uint64 `json:"sys_bytes"`
+	NumGC        uint32 `json:"num_gc"`
+	LastGC       string `json:"last_gc,omitempty"`
+	PauseTotalNs uint64 `json:"gc_pause_total_ns"`
+}
+
+func runtimeInfo(w http.ResponseWriter, r *http.Request) {
+	//ReadMemStats stops the world for a moment, fine for something an operator calls by hand
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := runtimeStats{
+		Uptime:       time.Since(started).Round(time.Second).String(),
+		Goroutines:   runtime.NumGoroutine(),
+		NumCPU:       runtime.NumCPU(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		HeapAlloc:    mem.HeapAlloc,
+		HeapObjects:  mem.HeapObjects,
+		Sys:          mem.Sys,
+		NumGC:        mem.NumGC,
+		PauseTotalNs: mem.PauseTotalNs,
+	}
+	if mem.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(mem.LastGC)).UTC().Format(time.RFC3339)
+	}
+
+	response.WriteJson(w, http.StatusOK, stats)
+}
+
+type module struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+}
+
+type build struct {
+	buildinfo.Info
+	Path     string            `json:"path,omitempty"`
+	Settings map[string]string `json:"settings,omitempty"` //-ldflags, -tags, vcs.* ...
+	Deps     []module          `json:"deps,omitempty"`
+}
+
+func buildInfo(w http.ResponseWriter, r *http.Request) {
+	out := build{Info: buildinfo.Get()}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		out.Path = info.Path
+		out.Settings = make(map[string]string, len(info.Settings))
+		for _, setting := range info.Settings {
+			out.Settings[setting.Key] = setting.Value
+		}
+		for _, dep := range info.Deps {
+			if dep.Replace != nil {
+				dep = dep.Replace
+			}
+			out.Deps = append(out.Deps, module{Path: dep.Path, Version: dep.Version})
+		}
+	}
+
+	response.WriteJson(w, http.StatusOK, out)
+}
+
+// effectiveConfig is the config after every layer [file, env, flags, reloads], secrets redacted
+func effectiveConfig(current func() *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dump, err := current().Dump()
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(dump)
+	}
+}
@@ -0,0 +1,87 @@
+package admin_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/shivakr07/students-api/internal/apitest"
+	"github.com/shivakr07/students-api/internal/auth"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+const token = "0123456789-admin-token"
+
+func TestToken(t *testing.T) {
+	h := apitest.New(t, apitest.Set("admin.token", token))
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		status        int
+	}{
+		{"no token", http.MethodGet, "/admin/webhooks", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "/admin/backups", "Bearer nope", http.StatusUnauthorized},
+		{"webhooks", http.MethodGet, "/admin/webhooks", "Bearer " + token, http.StatusOK},
+		{"deliveries", http.MethodGet, "/admin/webhooks/deliveries", "Bearer " + token, http.StatusOK},
+		{"backups", http.MethodGet, "/admin/backups", "Bearer " + token, http.StatusOK},
+		{"log level", http.MethodGet, "/admin/log/level", "Bearer " + token, http.StatusOK},
+		{"metrics", http.MethodGet, "/metrics", "Bearer " + token, http.StatusOK},
+		{"health", http.MethodGet, "/healthz", "Bearer " + token, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := h.Request(tt.method, tt.path).Admin()
+			if tt.authorization != "" {
+				req.Header("Authorization", tt.authorization)
+			}
+			req.Do().Status(tt.status)
+		})
+	}
+
+	h.PUT("/admin/log/level").Admin().Header("Authorization", "Bearer "+token).JSON(`{"level": "debug"}`).Do().
+		Status(http.StatusOK).
+		JSON(`{"level": "debug"}`)
+
+	//none of it is on the public listener
+	for _, path := range []string{"/admin/webhooks", "/admin/backups", "/admin/log/level", "/metrics"} {
+		h.GET(path).Header("Authorization", "Bearer "+token).Do().Status(http.StatusNotFound)
+	}
+	h.GET("/healthz").Do().Status(http.StatusOK)
+}
+
+func TestAdminUsers(t *testing.T) {
+	h := apitest.New(t, apitest.Set("admin.token", token), apitest.Set("auth.enabled", "true"))
+
+	key := func(username string, role string) string {
+		id, err := h.Storage.CreateUser(username, "", role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, prefix, hash, err := auth.GenerateAPIKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := h.Storage.CreateAPIKey(id, "test", prefix, hash); err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + key
+	}
+	admin := key("linus", types.RoleAdmin)
+	staff := key("grace", types.RoleStaff)
+
+	h.GET("/admin/webhooks").Admin().Header("Authorization", admin).Do().Status(http.StatusOK)
+	h.GET("/admin/webhooks").Admin().Header("Authorization", "Bearer "+token).Do().Status(http.StatusOK)
+	h.GET("/admin/webhooks").Admin().Header("Authorization", staff).Do().
+		Status(http.StatusUnauthorized).
+		Header("WWW-Authenticate", `Bearer realm="admin"`)
+}
+
+func TestOpen(t *testing.T) {
+	h := apitest.New(t)
+
+	//no token and no users, the listener is only on localhost
+	h.GET("/admin/log/level").Admin().Do().Status(http.StatusOK).JSON(`{"level": "error"}`)
+}
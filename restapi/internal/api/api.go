@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/filter"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// the public api is versioned: /api/v1/..., /api/v2/... and plain /api/... [an API-Version header
+// picks the version there, v1 when it's missing, that's what the old clients call]
+// every version has its own json form of a student [a dto] and maps it onto the shared domain model
+// types.Student, so storage and everything behind it only know one shape
+
+// StudentCodec is one version's json form of a student
+type StudentCodec interface {
+	// Encode is what this version's responses carry for s
+	Encode(s types.Student) any
+	// Decode reads the version's json strictly, validates it and applies it onto current [the zero
+	// Student for a create], so fields the version doesn't know about are kept as they are
+	Decode(data []byte, current types.Student) (types.Student, error)
+	// Fields are the json names ?fields and ?filter accept in this version
+	Fields() map[string]filter.Kind
+}
+
+// VersionHeader picks the version on the unversioned /api/... routes
+const VersionHeader = "API-Version"
+
+// ByHeader sends a request to the version named in the API-Version header, fallback when there is none
+func ByHeader(versions map[string]http.Handler, fallback string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(r.Header.Get(VersionHeader))), "v")
+		if version == "" {
+			version = fallback
+		}
+
+		handler, ok := versions[version]
+		if !ok {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(i18n.Errorf("api.unknown_version", "unknown api version \"%s\"", r.Header.Get(VersionHeader))))
+			return
+		}
+		w.Header().Add("Vary", VersionHeader)
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Deprecate marks every response of a version that is going away: Deprecation [RFC 9745] says since
+// when, Sunset [RFC 8594] when it stops working and Link points at the migration guide
+func Deprecate(d config.Deprecation, next http.Handler) http.Handler {
+	since, _ := time.Parse(types.DateLayout, d.Since)
+	sunset, _ := time.Parse(types.DateLayout, d.Sunset)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !since.IsZero() {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(since.Unix(), 10))
+		}
+		if !sunset.IsZero() {
+			w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.Link != "" {
+			w.Header().Add("Link", "<"+d.Link+`>; rel="deprecation"; type="text/html"`)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,69 @@
+package v1
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shivakr07/students-api/internal/filter"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/request"
+)
+
+// Student is the original json form of a student, one name and an age
+type Student struct {
+	Id    int64  `json:"id"`
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"required"`
+	Age   int    `json:"age" validate:"required"`
+}
+
+type codec struct{}
+
+// Students is the v1 api.StudentCodec
+var Students codec
+
+var fields = map[string]filter.Kind{
+	"id":    filter.Number,
+	"name":  filter.String,
+	"email": filter.String,
+	"age":   filter.Number,
+}
+
+func (codec) Fields() map[string]filter.Kind { return fields }
+
+func (codec) Encode(s types.Student) any {
+	return Student{Id: s.Id, Name: s.Name, Email: s.Email, Age: s.CurrentAge(time.Now())}
+}
+
+func (codec) Decode(data []byte, current types.Student) (types.Student, error) {
+	var dto Student
+	if err := request.UnmarshalStrict(data, &dto); err != nil {
+		return types.Student{}, err
+	}
+	if err := validator.New().Struct(dto); err != nil {
+		return types.Student{}, err
+	}
+
+	return Apply(dto, current), nil
+}
+
+// Apply maps a v1 student onto current, graphql [which speaks v1] uses it too
+func Apply(dto Student, current types.Student) types.Student {
+	s := current
+	s.Id = dto.Id
+	s.Email = dto.Email
+
+	//a new name is split again, an unchanged one keeps the first/last split a v2 client made
+	if dto.Name != current.Name {
+		s.Name = dto.Name
+		s.FirstName, s.LastName = types.SplitName(dto.Name)
+	}
+
+	//an age the date of birth doesn't agree with wins, the date of birth is dropped then
+	if dto.Age != current.CurrentAge(time.Now()) {
+		s.Age = dto.Age
+		s.DateOfBirth = nil
+	}
+
+	return s
+}
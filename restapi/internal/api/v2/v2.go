@@ -0,0 +1,72 @@
+package v2
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shivakr07/students-api/internal/filter"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/request"
+)
+
+// Student splits the name and takes a date of birth instead of an age, age is still returned but
+// worked out on every read, a client can't set it
+type Student struct {
+	Id          int64       `json:"id"`
+	FirstName   string      `json:"first_name" validate:"required"`
+	LastName    string      `json:"last_name"`
+	Email       string      `json:"email" validate:"required"`
+	DateOfBirth *types.Date `json:"date_of_birth" validate:"required"`
+	Age         int         `json:"age"`
+}
+
+type codec struct{}
+
+// Students is the v2 api.StudentCodec
+var Students codec
+
+var fields = map[string]filter.Kind{
+	"id":         filter.Number,
+	"first_name": filter.String,
+	"last_name":  filter.String,
+	"email":      filter.String,
+	"age":        filter.Number,
+}
+
+var errFutureBirth = i18n.New("student.future_birth", "date_of_birth can't be in the future")
+
+func (codec) Fields() map[string]filter.Kind { return fields }
+
+func (codec) Encode(s types.Student) any {
+	return Student{
+		Id:          s.Id,
+		FirstName:   s.FirstName,
+		LastName:    s.LastName,
+		Email:       s.Email,
+		DateOfBirth: s.DateOfBirth,
+		Age:         s.CurrentAge(time.Now()),
+	}
+}
+
+func (codec) Decode(data []byte, current types.Student) (types.Student, error) {
+	var dto Student
+	if err := request.UnmarshalStrict(data, &dto); err != nil {
+		return types.Student{}, err
+	}
+	if err := validator.New().Struct(dto); err != nil {
+		return types.Student{}, err
+	}
+	if dto.DateOfBirth.After(time.Now()) {
+		return types.Student{}, errFutureBirth
+	}
+
+	s := current
+	s.Id = dto.Id
+	s.FirstName, s.LastName = dto.FirstName, dto.LastName
+	s.Email = dto.Email
+	s.DateOfBirth = dto.DateOfBirth
+	s.Normalize(time.Now())
+
+	return s, nil
+}
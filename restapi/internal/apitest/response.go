@@ -0,0 +1,190 @@
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// go test ./... -update rewrites the golden files with what the handlers answer now
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// Response holds what the router answered, every check fails the test with the body in the message
+type Response struct {
+	t        testing.TB
+	Recorder *httptest.ResponseRecorder
+	label    string
+}
+
+func (r *Response) Body() []byte {
+	return r.Recorder.Body.Bytes()
+}
+
+func (r *Response) Status(want int) *Response {
+	r.t.Helper()
+
+	if got := r.Recorder.Code; got != want {
+		r.t.Errorf("%s: status %d, want %d\nbody: %s", r.label, got, want, r.Body())
+	}
+	return r
+}
+
+func (r *Response) Header(key string, want string) *Response {
+	r.t.Helper()
+
+	if got := r.Recorder.Header().Get(key); got != want {
+		r.t.Errorf("%s: header %s is %q, want %q", r.label, key, got, want)
+	}
+	return r
+}
+
+// JSON compares the whole body with want, as json so key order and spacing don't matter
+func (r *Response) JSON(want string) *Response {
+	r.t.Helper()
+
+	got, err := normalize(r.Body())
+	if err != nil {
+		r.t.Fatalf("%s: body is not json: %v\nbody: %s", r.label, err, r.Body())
+	}
+	expected, err := normalize([]byte(want))
+	if err != nil {
+		r.t.Fatalf("%s: expected value is not json: %v", r.label, err)
+	}
+
+	if got != expected {
+		r.t.Errorf("%s: body mismatch\ngot:  %s\nwant: %s", r.label, got, expected)
+	}
+	return r
+}
+
+// Field checks one value in the body, path is dotted with array indexes as numbers: "error", "0.name"
+// want is compared as json, so Field("id", 1) matches the float64 json decodes to
+func (r *Response) Field(path string, want any) *Response {
+	r.t.Helper()
+
+	var body any
+	if err := json.Unmarshal(r.Body(), &body); err != nil {
+		r.t.Fatalf("%s: body is not json: %v\nbody: %s", r.label, err, r.Body())
+	}
+
+	got, ok := lookup(body, path)
+	if !ok {
+		r.t.Errorf("%s: no %q in the body\nbody: %s", r.label, path, r.Body())
+		return r
+	}
+
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if !bytes.Equal(gotJSON, wantJSON) {
+		r.t.Errorf("%s: %s is %s, want %s", r.label, path, gotJSON, wantJSON)
+	}
+	return r
+}
+
+// Contains checks that the body has substr somewhere, for error messages with details we don't pin down
+func (r *Response) Contains(substr string) *Response {
+	r.t.Helper()
+
+	if !strings.Contains(string(r.Body()), substr) {
+		r.t.Errorf("%s: body doesn't contain %q\nbody: %s", r.label, substr, r.Body())
+	}
+	return r
+}
+
+// Decode unmarshals the body into v for checks the helpers don't cover
+func (r *Response) Decode(v any) *Response {
+	r.t.Helper()
+
+	if err := json.Unmarshal(r.Body(), v); err != nil {
+		r.t.Fatalf("%s: can't decode the body: %v\nbody: %s", r.label, err, r.Body())
+	}
+	return r
+}
+
+// Golden compares the body with testdata/golden/<name>.json [relative to the test's package]
+// run the tests with -update to write the file from the current answer
+func (r *Response) Golden(name string) *Response {
+	r.t.Helper()
+
+	path := filepath.Join("testdata", "golden", name+".json")
+
+	got, err := indent(r.Body())
+	if err != nil {
+		r.t.Fatalf("%s: body is not json: %v\nbody: %s", r.label, err, r.Body())
+	}
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			r.t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			r.t.Fatal(err)
+		}
+		return r
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		r.t.Fatalf("%s: %v [run with -update to create it]", r.label, err)
+	}
+	want, err = indent(want)
+	if err != nil {
+		r.t.Fatalf("%s: golden file %s is not json: %v", r.label, path, err)
+	}
+
+	if !bytes.Equal(got, want) {
+		r.t.Errorf("%s: body doesn't match %s [run with -update if the change is intended]\ngot:\n%s\nwant:\n%s", r.label, path, got, want)
+	}
+	return r
+}
+
+// normalize re-encodes json so two documents with the same content compare equal as strings
+func normalize(data []byte) (string, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(v)
+	return string(out), err
+}
+
+func indent(data []byte) ([]byte, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func lookup(v any, path string) (any, bool) {
+	for _, part := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return v, true
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// api keys look like sk_<43 random url-safe chars>, the first 10 characters are kept as a visible prefix
+const (
+	keyPrefix    = "sk_"
+	prefixLength = 10
+)
+
+// GenerateAPIKey returns a new random key, the prefix to show for it and the hash to store
+// the key itself is only ever shown once, to whoever created it
+func GenerateAPIKey() (key string, prefix string, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", "", err
+	}
+
+	key = keyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	return key, key[:prefixLength], HashAPIKey(key), nil
+}
+
+// HashAPIKey is a plain sha256, api keys are long random strings so a slow hash buys nothing
+// and it keeps lookups by hash possible
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+func CheckPassword(hash string, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// the middleware answers 401 when it can't tell who is calling and 403 when the role isn't enough
+var (
+	ErrUnauthorized = i18n.New("auth.unauthorized", "missing or invalid credentials")
+	ErrForbidden    = i18n.New("auth.forbidden", "your role is not allowed to do this")
+)
+
+// unknown usernames are still checked against a hash, so they take as long as a wrong password
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("students-api")
+	return hash
+})
+
+// Authenticate is the user behind the request: Authorization: Bearer <api key>, or basic auth with a
+// username and password
+func Authenticate(users storage.UserStorage, r *http.Request) (types.User, error) {
+	if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		user, err := users.GetUserByAPIKey(HashAPIKey(key))
+		if err != nil {
+			return types.User{}, ErrUnauthorized
+		}
+		return user, nil
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return types.User{}, ErrUnauthorized
+	}
+
+	user, err := users.GetUserByUsername(username)
+	if err != nil {
+		CheckPassword(dummyHash(), password)
+		return types.User{}, ErrUnauthorized
+	}
+	//bcrypt is slow on purpose, api keys are the way for anything that calls often
+	if !CheckPassword(user.PasswordHash, password) {
+		return types.User{}, ErrUnauthorized
+	}
+
+	return user, nil
+}
+
+// Require lets a request through when its user has one of the roles roles returns for it,
+// no roles means the route is public
+func Require(users storage.UserStorage, roles func(r *http.Request) []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed := roles(r)
+		if len(allowed) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, err := Authenticate(users, r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="students-api"`)
+			response.WriteJson(w, http.StatusUnauthorized, response.GeneralError(err))
+			return
+		}
+		if !slices.Contains(allowed, user.Role) {
+			response.WriteJson(w, http.StatusForbidden, response.GeneralError(ErrForbidden))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
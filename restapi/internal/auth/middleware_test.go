@@ -0,0 +1,95 @@
+package auth_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/shivakr07/students-api/internal/apitest"
+	"github.com/shivakr07/students-api/internal/auth"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// user makes a user with one api key and returns the key
+func user(t *testing.T, h *apitest.Harness, username string, role string) string {
+	t.Helper()
+
+	hash, err := auth.HashPassword(username + "-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := h.Storage.CreateUser(username, hash, role)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, prefix, keyHash, err := auth.GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Storage.CreateAPIKey(id, "test", prefix, keyHash); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func basic(username string, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+func TestRequire(t *testing.T) {
+	h := apitest.New(t, apitest.Set("auth.enabled", "true"))
+	staff := "Bearer " + user(t, h, "grace", types.RoleStaff)
+	admin := "Bearer " + user(t, h, "linus", types.RoleAdmin)
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		status        int
+	}{
+		{"health is public", http.MethodGet, "/healthz", "", http.StatusOK},
+		{"no credentials", http.MethodGet, "/api/students", "", http.StatusUnauthorized},
+		{"unknown key", http.MethodGet, "/api/students", "Bearer sk_nope", http.StatusUnauthorized},
+		{"staff key", http.MethodGet, "/api/v2/students", staff, http.StatusOK},
+		{"admin key", http.MethodGet, "/api/students", admin, http.StatusOK},
+		{"staff on graphql", http.MethodGet, "/graphql?query={students{total}}", staff, http.StatusOK},
+		{"staff password", http.MethodGet, "/api/students", basic("grace", "grace-password"), http.StatusOK},
+		{"wrong password", http.MethodGet, "/api/students", basic("grace", "linus-password"), http.StatusUnauthorized},
+		{"unknown user", http.MethodGet, "/api/students", basic("ada", "ada-password"), http.StatusUnauthorized},
+		{"admin api is not public", http.MethodGet, "/admin/webhooks", admin, http.StatusNotFound},
+		{"metrics are not public", http.MethodGet, "/metrics", admin, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := h.Request(tt.method, tt.path)
+			if tt.authorization != "" {
+				req.Header("Authorization", tt.authorization)
+			}
+			req.Do().Status(tt.status)
+		})
+	}
+}
+
+func TestRevokedKey(t *testing.T) {
+	h := apitest.New(t, apitest.Set("auth.enabled", "true"))
+	key := "Bearer " + user(t, h, "grace", types.RoleStaff)
+
+	h.GET("/api/students").Header("Authorization", key).Do().Status(http.StatusOK)
+
+	if err := h.Storage.RevokeAPIKey(1); err != nil {
+		t.Fatal(err)
+	}
+	h.GET("/api/students").Header("Authorization", key).Do().
+		Status(http.StatusUnauthorized).
+		Header("WWW-Authenticate", `Bearer realm="students-api"`).
+		JSON(`{"status": "Error", "error": "missing or invalid credentials"}`)
+}
+
+func TestDisabled(t *testing.T) {
+	h := apitest.New(t)
+
+	h.GET("/api/students").Do().Status(http.StatusOK)
+}
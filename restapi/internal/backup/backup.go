@@ -0,0 +1,257 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/storage/sqlite"
+)
+
+// backups are named after the moment they were taken, so sorting the names sorts them by time
+const (
+	filePrefix = "students-"
+	fileSuffix = ".db"
+	timeLayout = "20060102T150405Z"
+)
+
+// Snapshotter is anything that can write a consistent copy of itself to a file, sqlite.Sqlite is one
+type Snapshotter interface {
+	Backup(ctx context.Context, dest string) error
+}
+
+// Info describes one backup file in the backup directory
+type Info struct {
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	Checksum  string    `json:"checksum"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Manager takes snapshots into cfg.Dir, checksums them and keeps only the newest cfg.Retain
+type Manager struct {
+	db  Snapshotter
+	cfg config.Backup
+
+	//one snapshot at a time, the scheduler and the admin endpoint can race otherwise
+	mu sync.Mutex
+}
+
+func New(db Snapshotter, cfg config.Backup) *Manager {
+	return &Manager{db: db, cfg: cfg}
+}
+
+// Snapshot takes a backup now, writes its sha256 next to it and rotates old backups
+func (m *Manager) Snapshot(ctx context.Context) (Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.cfg.Dir, 0o755); err != nil {
+		return Info{}, err
+	}
+
+	now := time.Now().UTC()
+	path := filepath.Join(m.cfg.Dir, filePrefix+now.Format(timeLayout)+fileSuffix)
+
+	//write to a temp name first, a half written backup must never look like a real one
+	tmp := path + ".partial"
+	if err := m.db.Backup(ctx, tmp); err != nil {
+		os.Remove(tmp)
+		return Info{}, err
+	}
+	if err := sqlite.CheckIntegrity(tmp); err != nil {
+		os.Remove(tmp)
+		return Info{}, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return Info{}, err
+	}
+
+	sum, err := WriteChecksum(path)
+	if err != nil {
+		return Info{}, err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return Info{}, err
+	}
+
+	slog.Info("backup written", slog.String("path", path), slog.String("sha256", sum))
+
+	if err := m.rotate(); err != nil {
+		slog.Error("backup rotation failed", slog.String("error", err.Error()))
+	}
+
+	return Info{Path: path, Size: stat.Size(), Checksum: sum, CreatedAt: now}, nil
+}
+
+// Run takes a snapshot every cfg.Interval until ctx is done
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	slog.Info("scheduled backups enabled", slog.String("interval", m.cfg.Interval.String()), slog.String("dir", m.cfg.Dir))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Snapshot(ctx); err != nil {
+				slog.Error("scheduled backup failed", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+// List returns the backups in the directory, newest first
+func (m *Manager) List() ([]Info, error) {
+	return List(m.cfg.Dir)
+}
+
+func (m *Manager) rotate() error {
+	if m.cfg.Retain <= 0 {
+		return nil
+	}
+
+	backups, err := List(m.cfg.Dir)
+	if err != nil {
+		return err
+	}
+
+	for _, old := range backups[min(m.cfg.Retain, len(backups)):] {
+		slog.Info("removing old backup", slog.String("path", old.Path))
+		os.Remove(old.Path)
+		os.Remove(old.Path + ".sha256")
+	}
+
+	return nil
+}
+
+// List reads the backups in dir, newest first
+func List(dir string) ([]Info, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []Info{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []Info{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+
+		createdAt, err := time.Parse(timeLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(dir, name)
+		sum, _ := readChecksum(path)
+
+		backups = append(backups, Info{Path: path, Size: info.Size(), Checksum: sum, CreatedAt: createdAt})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// At picks the newest backup in dir taken at or before t, for `restore -at`
+// nothing is replayed on top of it, so a restore is only as close to t as backup.interval allows
+func At(dir string, t time.Time) (Info, error) {
+	backups, err := List(dir)
+	if err != nil {
+		return Info{}, err
+	}
+
+	for _, b := range backups {
+		if !b.CreatedAt.After(t) {
+			return b, nil
+		}
+	}
+
+	return Info{}, fmt.Errorf("no backup in %s taken at or before %s", dir, t.Format(time.RFC3339))
+}
+
+// WriteChecksum stores the sha256 of path in path.sha256, in the same format sha256sum uses
+func WriteChecksum(path string) (string, error) {
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return "", err
+	}
+
+	line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(path))
+	return sum, os.WriteFile(path+".sha256", []byte(line), 0o644)
+}
+
+// VerifyChecksum compares path against its .sha256 file, a backup without one is rejected
+func VerifyChecksum(path string) error {
+	want, err := readChecksum(path)
+	if err != nil {
+		return fmt.Errorf("reading checksum of %s: %w", path, err)
+	}
+
+	got, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+
+	if got != want {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", path, want, got)
+	}
+
+	return nil
+}
+
+func readChecksum(path string) (string, error) {
+	raw, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(raw))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file")
+	}
+
+	return fields[0], nil
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,25 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// Store keeps opaque files [photos, thumbnails] under slash separated keys like "students/1/photo"
+// the filesystem one is all we need today, an object store only has to implement these three methods
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get opens a blob, the caller closes it, seeking is there so http.ServeContent can do ranges
+	Get(ctx context.Context, key string) (io.ReadSeekCloser, Info, error)
+	// Delete removes a blob, deleting one that doesn't exist is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+type Info struct {
+	Size    int64
+	ModTime time.Time
+}
+
+var ErrNotFound = errors.New("blob not found")
@@ -1,64 +1,263 @@
 package config
 
 import (
-	"flag"
+	"fmt"
 	"log"
 	"os"
+	"sort"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type HTTPServer struct {
-	Addr string `yaml:"address" env-required:"true"`
+	Addr            string        `yaml:"address" env-required:"true"`
+	MaxBodyBytes    int64         `yaml:"max_body_bytes" env-default:"1048576"` //json bodies only, photo uploads have photos.max_bytes
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"5s"`    //how long open requests get to finish on shutdown
 }
 
-type Config struct {
-	Env         string `yaml:"env" env:"ENV" env-required:"true"` //you can add env-default:"production"
-	StoragePath string `yaml:"storage_path" env-required:"true"`
-	HTTPServer  `yaml:"http_server"`
+// limits for the /graphql endpoint, a single query can fan out a lot so we cap it
+type GraphQL struct {
+	MaxDepth      int `yaml:"max_depth" env-default:"6"`
+	MaxComplexity int `yaml:"max_complexity" env-default:"1000"`
 }
 
-// we will write the logic to parse this //this function must be executed successfully as it is required as as it is configuration
-// means if this function faces some error then we shouln't start our application
-func MustLoad() *Config {
-	var configPath string
-	// we need to pass the location/path of the config file
-	// here we will use os package to get that from the normal env variable
-	configPath = os.Getenv("CONFIG_PATH")
+// webhook dispatcher settings, durations are written like "5s" or "1m"
+type Webhooks struct {
+	Enabled      bool          `yaml:"enabled" env:"WEBHOOKS_ENABLED" env-default:"true"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"2s"`
+	Timeout      time.Duration `yaml:"timeout" env-default:"10s"`
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"8"`
+	BackoffBase  time.Duration `yaml:"backoff_base" env-default:"5s"`
+	BackoffMax   time.Duration `yaml:"backoff_max" env-default:"1h"`
+}
 
-	if configPath == "" {
-		// if not available in environment then we will check other method [it might have been passed via flags/program arguments [we pass this while we run the program]]
-		// like go run cmd/students-api/main.go -config-path xyz
+// settings for the server-sent events feed
+type Stream struct {
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"500ms"`
+	Heartbeat    time.Duration `yaml:"heartbeat" env-default:"15s"`
+	BufferSize   int           `yaml:"buffer_size" env-default:"64"` //events a client may fall behind before it's disconnected
+}
 
-		flags := flag.String("config", "", "path to the configuration file") //flagname config, default value "", usage like program -help [gives all the listof commands]
-		flag.Parse()
+// read-through cache in front of the storage, off unless the deployment turns it on
+type Cache struct {
+	Enabled bool          `yaml:"enabled" env:"CACHE_ENABLED" env-default:"false"`
+	Size    int           `yaml:"size" env-default:"1000"` //max number of students kept
+	TTL     time.Duration `yaml:"ttl" env-default:"1m"`
+}
 
-		configPath = *flags
-		//it is pointer so we need to dereference
+// sqlite tuning, these end up as pragmas on every connection of the pool
+type SQLite struct {
+	JournalMode     string        `yaml:"journal_mode" env-default:"WAL"`
+	BusyTimeout     time.Duration `yaml:"busy_timeout" env-default:"5s"` //how long a writer waits for the lock before "database is locked"
+	Synchronous     string        `yaml:"synchronous" env-default:"NORMAL"`
+	ForeignKeys     bool          `yaml:"foreign_keys" env-default:"true"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env-default:"8"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env-default:"8"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env-default:"0s"`
+	AutoMigrate     bool          `yaml:"auto_migrate" env-default:"true"` //turn off to only migrate through `students-api migrate`
+}
 
-		//if still you didn't get the path
-		if configPath == "" {
-			log.Fatal("config path is not set")
-		}
+// backups of the sqlite file, scheduled ones only run when interval is set
+type Backup struct {
+	Dir      string        `yaml:"dir" env-default:"storage/backups"`
+	Interval time.Duration `yaml:"interval" env-default:"0s"`
+	Retain   int           `yaml:"retain" env-default:"7"` //how many backups rotation keeps
+}
+
+// logging, only the level can be changed without a restart [SIGHUP or PUT /admin/log/level]
+type Log struct {
+	Level  string            `yaml:"level" env:"LOG_LEVEL" env-default:"info" reload:"safe"`
+	Format string            `yaml:"format" env:"LOG_FORMAT" env-default:"text"`   //text or json
+	Output string            `yaml:"output" env:"LOG_OUTPUT" env-default:"stdout"` //stdout, stderr or file
+	File   LogFile           `yaml:"file"`
+	Attrs  map[string]string `yaml:"attrs"` //added to every line, next to the build version and commit
+}
+
+// the log file when output is file, it is rotated once it grows past max_size_mb
+type LogFile struct {
+	Path       string        `yaml:"path" env-default:"storage/logs/students-api.log"`
+	MaxSizeMB  int           `yaml:"max_size_mb" env-default:"100"`
+	MaxAge     time.Duration `yaml:"max_age" env-default:"168h"`  //rotated files older than this are removed, 0 keeps them
+	MaxBackups int           `yaml:"max_backups" env-default:"5"` //how many rotated files are kept, 0 keeps them all
+}
+
+// per client ip token bucket in front of every route
+type RateLimit struct {
+	Enabled           bool    `yaml:"enabled" env:"RATE_LIMIT_ENABLED" env-default:"false"`
+	RequestsPerSecond float64 `yaml:"requests_per_second" env-default:"20"`
+	Burst             int     `yaml:"burst" env-default:"40"`
+}
+
+// who may call the api, users and their api keys are made with `students-api user` and `students-api apikey`
+// with it on /healthz stays open and everything else needs a staff member or an admin [Authorization:
+// Bearer <api key>, or basic auth with the username and password], the admin listener also lets admins in
+type Auth struct {
+	Enabled bool `yaml:"enabled" env:"AUTH_ENABLED" env-default:"false"`
+}
+
+// the admin listener [the admin api, pprof, runtime and build info, the effective config, metrics, health]
+// it is off by default and should never listen on a public address
+type Admin struct {
+	Enabled bool   `yaml:"enabled" env:"ADMIN_ENABLED" env-default:"false"`
+	Addr    string `yaml:"address" env:"ADMIN_ADDRESS" env-default:"localhost:6060"`
+	Token   string `yaml:"token" env:"ADMIN_TOKEN" secret:"true"` //when set, requests need Authorization: Bearer <token>
+}
+
+// field level encryption of student pii [see the fieldcrypt package], off while fields is empty
+// keys are base64 of 32 random bytes, `students-api encryption keygen` makes one
+// to rotate: add a key, make it active_key, and drop the old one after `students-api encryption reencrypt`
+// [or once the background job has caught up]
+type Encryption struct {
+	Fields    []string          `yaml:"fields"`                                             //student fields to encrypt: name, first_name, last_name, email
+	ActiveKey string            `yaml:"active_key" env:"ENCRYPTION_ACTIVE_KEY"`             //id of the key new values are sealed with
+	Keys      map[string]string `yaml:"keys" env:"ENCRYPTION_KEYS" secret:"true"`           //key id -> key, old ones stay until nothing is sealed with them
+	IndexKey  string            `yaml:"index_key" env:"ENCRYPTION_INDEX_KEY" secret:"true"` //hmac key of the email blind index, it can't be rotated
+	Interval  time.Duration     `yaml:"reencrypt_interval" env-default:"1m"`                //how often rows not on the active key are looked for, 0 turns it off
+	BatchSize int               `yaml:"reencrypt_batch" env-default:"100"`                  //rows per transaction, the write lock is held that long
+}
+
+// api versions that are on their way out, keyed by version ["1"], see api.Deprecate
+type API struct {
+	Deprecations map[string]Deprecation `yaml:"deprecations"`
+}
+
+type Deprecation struct {
+	Since  string `yaml:"since"`  //2006-01-02, sent as the Deprecation header
+	Sunset string `yaml:"sunset"` //the day the version stops working, sent as the Sunset header
+	Link   string `yaml:"link"`   //migration guide
+}
+
+// error messages follow Accept-Language, see the i18n package for the catalog format
+type I18n struct {
+	Default   string   `yaml:"default" env-default:"en"`
+	Languages []string `yaml:"languages" env-default:"en,fr,hi"` //en, fr, hi, es or de
+	Dir       string   `yaml:"dir"`                              //extra catalogs for this deployment, every *.json in it is loaded
+}
+
+// GET /api/students/stats, results are cached for ttl [a few seconds stale is fine for dashboards]
+type Stats struct {
+	TTL        time.Duration `yaml:"ttl" env-default:"30s"`
+	AgeBuckets []int         `yaml:"age_buckets" env-default:"18,21,25,30,40"` //default histogram, ?buckets= overrides it
+}
+
+// profile photos, the files go to the blob store in dir and their metadata to the db
+type Photos struct {
+	Dir           string `yaml:"dir" env-default:"storage/blobs"`
+	MaxBytes      int64  `yaml:"max_bytes" env-default:"5242880"`  //largest upload accepted, 5MB
+	ThumbnailSize int    `yaml:"thumbnail_size" env-default:"128"` //thumbnails fit in a square of this many pixels
+}
+
+// GradeStep is one band of a grading scale, a percentage of at least Min earns Letter and Points
+type GradeStep struct {
+	Min    float64 `yaml:"min"`
+	Letter string  `yaml:"letter"`
+	Points float64 `yaml:"points"`
+}
+
+// grading scales by name, a course picks one when it is created [the default one otherwise]
+type Grading struct {
+	DefaultScale string                 `yaml:"default_scale" env-default:"standard"`
+	Scales       map[string][]GradeStep `yaml:"scales"`
+}
+
+// the scale used when the config file doesn't define any
+var StandardScale = []GradeStep{
+	{Min: 93, Letter: "A", Points: 4.0},
+	{Min: 90, Letter: "A-", Points: 3.7},
+	{Min: 87, Letter: "B+", Points: 3.3},
+	{Min: 83, Letter: "B", Points: 3.0},
+	{Min: 80, Letter: "B-", Points: 2.7},
+	{Min: 77, Letter: "C+", Points: 2.3},
+	{Min: 73, Letter: "C", Points: 2.0},
+	{Min: 70, Letter: "C-", Points: 1.7},
+	{Min: 67, Letter: "D+", Points: 1.3},
+	{Min: 60, Letter: "D", Points: 1.0},
+	{Min: 0, Letter: "F", Points: 0},
+}
+
+// sections tagged reload:"safe" are re-applied on SIGHUP, everything else needs a restart
+type Config struct {
+	Env         string `yaml:"env" env:"ENV" env-required:"true"` //you can add env-default:"production"
+	StoragePath string `yaml:"storage_path" env:"STORAGE_PATH" env-required:"true"`
+	SQLite      SQLite `yaml:"sqlite"`
+	HTTPServer  `yaml:"http_server"`
+	Auth        Auth       `yaml:"auth"`
+	Admin       Admin      `yaml:"admin"`
+	GraphQL     GraphQL    `yaml:"graphql"`
+	Webhooks    Webhooks   `yaml:"webhooks"`
+	Stream      Stream     `yaml:"stream"`
+	Cache       Cache      `yaml:"cache"`
+	Backup      Backup     `yaml:"backup"`
+	Log         Log        `yaml:"log"`
+	RateLimit   RateLimit  `yaml:"rate_limit" reload:"safe"`
+	Grading     Grading    `yaml:"grading"`
+	Photos      Photos     `yaml:"photos"`
+	Stats       Stats      `yaml:"stats"`
+	I18n        I18n       `yaml:"i18n"`
+	API         API        `yaml:"api"`
+	Encryption  Encryption `yaml:"encryption"`
+}
+
+// Load builds the config in layers, each one overriding the one before:
+// defaults [env-default tags], the yaml file, environment variables, then overrides [from flags]
+// overrides are keyed by the yaml path, like "http_server.address"
+// it returns errors instead of exiting so it can be used from tests and from `config validate`
+func Load(configPath string, overrides map[string]string) (*Config, error) {
+	if configPath == "" {
+		return nil, fmt.Errorf("config path is not set")
 	}
 
 	//we need to check the availability of the file on the provided path
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		log.Fatalf("config file doesn't exist: %s", configPath)
+		return nil, fmt.Errorf("config file doesn't exist: %s", configPath)
 	}
 
 	// now if file exist
 	var cfg Config
 
+	//cleanenv does the first three layers: defaults, then the file, then env
 	err := cleanenv.ReadConfig(configPath, &cfg)
 	//and on which structure we need to serialize we also need to give so here it is cfg [memory location of that]
 	// if there exist some problem then it returns error
+	if err != nil {
+		return nil, fmt.Errorf("can't read config files: %w", err)
+	}
+
+	if len(cfg.Grading.Scales) == 0 {
+		cfg.Grading.Scales = map[string][]GradeStep{"standard": StandardScale}
+	}
 
+	//sorted so the same bad override always produces the same error
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := cfg.Set(key, overrides[key]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// we will write the logic to parse this //this function must be executed successfully as it is required as as it is configuration
+// means if this function faces some error then we shouln't start our application
+// finding the path [CONFIG_PATH or the -config flag] is the caller's job now, see cmd/students-api
+func MustLoad(configPath string) *Config {
+	cfg, err := Load(configPath, nil)
 	if err != nil {
-		log.Fatalf("can't read config files: %s", err.Error())
+		log.Fatal(err)
 	}
 
-	return &cfg
+	return cfg
 }
 
 // and make sure when you are naming these type of things like MustLoad then make sure you are not returning the error from here, as name suggests it must load it is required for application to run
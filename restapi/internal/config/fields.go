@@ -0,0 +1,191 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// everything in here works on the yaml paths of the fields [like "cache.ttl"], so flags, the
+// dump and the reload diff all talk about settings with the names people write in the file
+
+const redacted = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Set changes one setting from its string form, durations are written like "5s"
+func (c *Config) Set(path string, value string) error {
+	field, err := lookup(reflect.ValueOf(c).Elem(), strings.Split(path, "."))
+	if err != nil {
+		return fmt.Errorf("unknown setting %q", path)
+	}
+
+	if err := setValue(field, value); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func lookup(v reflect.Value, parts []string) (reflect.Value, error) {
+	if len(parts) == 0 {
+		return v, nil
+	}
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("not a section")
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		if yamlName(v.Type().Field(i)) == parts[0] {
+			return lookup(v.Field(i), parts[1:])
+		}
+	}
+
+	return reflect.Value{}, fmt.Errorf("no such field")
+}
+
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("%s settings can't be set from a flag", v.Kind())
+	}
+
+	return nil
+}
+
+// Dump renders the effective config as yaml, fields tagged secret:"true" are replaced by [REDACTED]
+func (c *Config) Dump() ([]byte, error) {
+	node, err := toNode(reflect.ValueOf(*c), false)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(node)
+}
+
+func toNode(v reflect.Value, secret bool) (*yaml.Node, error) {
+	if secret {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: redacted}, nil
+	}
+
+	if v.Type() == durationType {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: v.Interface().(time.Duration).String()}, nil
+	}
+
+	if v.Kind() != reflect.Struct {
+		node := &yaml.Node{}
+		return node, node.Encode(v.Interface())
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		child, err := toNode(v.Field(i), field.Tag.Get("secret") == "true")
+		if err != nil {
+			return nil, err
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: yamlName(field)}, child)
+	}
+
+	return node, nil
+}
+
+// Changes compares two configs and returns the yaml paths that differ, split by whether the
+// running server can pick them up [reload:"safe" on the field or one of its sections] or not
+func Changes(old *Config, next *Config) (safe []string, unsafe []string) {
+	var walk func(a, b reflect.Value, path string, reloadable bool)
+	walk = func(a, b reflect.Value, path string, reloadable bool) {
+		if a.Kind() == reflect.Struct && a.Type() != durationType {
+			for i := 0; i < a.NumField(); i++ {
+				field := a.Type().Field(i)
+				if !field.IsExported() {
+					continue
+				}
+
+				childPath := yamlName(field)
+				if path != "" {
+					childPath = path + "." + childPath
+				}
+				walk(a.Field(i), b.Field(i), childPath, reloadable || field.Tag.Get("reload") == "safe")
+			}
+			return
+		}
+
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return
+		}
+		if reloadable {
+			safe = append(safe, path)
+		} else {
+			unsafe = append(unsafe, path)
+		}
+	}
+
+	walk(reflect.ValueOf(*old), reflect.ValueOf(*next), "", false)
+	return safe, unsafe
+}
+
+// copyReloadable copies the sections and fields tagged reload:"safe" from src into dst
+func copyReloadable(dst *Config, src *Config) {
+	var copyFields func(d, s reflect.Value)
+	copyFields = func(d, s reflect.Value) {
+		for i := 0; i < d.NumField(); i++ {
+			field := d.Type().Field(i)
+			switch {
+			case field.Tag.Get("reload") == "safe":
+				d.Field(i).Set(s.Field(i))
+			case field.Type.Kind() == reflect.Struct && field.Type != durationType:
+				copyFields(d.Field(i), s.Field(i))
+			}
+		}
+	}
+
+	copyFields(reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem())
+}
+
+// yamlName is the key of a field in the yaml file [the tag name, or the lowercased field name]
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
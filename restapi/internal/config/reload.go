@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// ParseLevel maps the log.level setting to a slog level
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.ToUpper(level))); err != nil {
+		return 0, fmt.Errorf("unknown level %q, use debug, info, warn or error", level)
+	}
+
+	return l, nil
+}
+
+// Reloader re-reads the config on demand [main calls it on SIGHUP] and hands the result to
+// the components that can change at runtime, settings that need a restart are only logged
+type Reloader struct {
+	path      string
+	overrides map[string]string
+	apply     []func(*Config)
+
+	mu      sync.Mutex //Current can be called while a reload runs
+	current *Config
+}
+
+func NewReloader(path string, overrides map[string]string, current *Config) *Reloader {
+	return &Reloader{path: path, overrides: overrides, current: current}
+}
+
+// OnReload registers a function that receives every new config that passed validation
+func (r *Reloader) OnReload(fn func(*Config)) {
+	r.apply = append(r.apply, fn)
+}
+
+// Current is the config the server is running with, the reloadable settings included
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Reload loads the config again, an invalid file leaves the running config untouched
+func (r *Reloader) Reload() error {
+	next, err := Load(r.path, r.overrides)
+	if err != nil {
+		return err
+	}
+
+	safe, unsafe := Changes(r.current, next)
+	for _, path := range unsafe {
+		slog.Warn("config change needs a restart to take effect", slog.String("setting", path))
+	}
+	if len(safe) == 0 {
+		slog.Info("config reloaded, nothing to apply")
+		return nil
+	}
+
+	//only the reloadable settings move forward, the rest stays what the server started with
+	applied := *r.current
+	copyReloadable(&applied, next)
+
+	for _, fn := range r.apply {
+		fn(&applied)
+	}
+	//reloads only run one at a time [the SIGHUP loop], the lock is for the readers of Current
+	r.mu.Lock()
+	r.current = &applied
+	r.mu.Unlock()
+
+	slog.Info("config reloaded", slog.String("applied", strings.Join(safe, ", ")))
+	return nil
+}
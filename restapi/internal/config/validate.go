@@ -0,0 +1,310 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate checks the rules the yaml/env parsing can't express, it reports every problem at once
+func (c *Config) Validate() error {
+	var errs []error
+
+	if err := validateAddress(c.Addr); err != nil {
+		errs = append(errs, fmt.Errorf("http_server.address: %w", err))
+	}
+	if c.Admin.Enabled {
+		if err := validateAddress(c.Admin.Addr); err != nil {
+			errs = append(errs, fmt.Errorf("admin.address: %w", err))
+		} else if c.Admin.Addr == c.Addr {
+			errs = append(errs, fmt.Errorf("admin.address: can't be the same as http_server.address"))
+		}
+	}
+	if err := validateWritable(c.StoragePath); err != nil {
+		errs = append(errs, fmt.Errorf("storage_path: %w", err))
+	}
+
+	if !slices.Contains([]string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}, strings.ToUpper(c.SQLite.JournalMode)) {
+		errs = append(errs, fmt.Errorf("sqlite.journal_mode: unsupported mode %q", c.SQLite.JournalMode))
+	}
+	if !slices.Contains([]string{"OFF", "NORMAL", "FULL", "EXTRA"}, strings.ToUpper(c.SQLite.Synchronous)) {
+		errs = append(errs, fmt.Errorf("sqlite.synchronous: unsupported level %q", c.SQLite.Synchronous))
+	}
+	if c.SQLite.MaxOpenConns < 1 {
+		errs = append(errs, fmt.Errorf("sqlite.max_open_conns: must be at least 1"))
+	}
+
+	if c.Webhooks.Enabled {
+		if c.Webhooks.PollInterval <= 0 || c.Webhooks.Timeout <= 0 || c.Webhooks.BackoffBase <= 0 {
+			errs = append(errs, fmt.Errorf("webhooks: poll_interval, timeout and backoff_base must be positive"))
+		}
+		if c.Webhooks.BackoffMax < c.Webhooks.BackoffBase {
+			errs = append(errs, fmt.Errorf("webhooks.backoff_max: can't be smaller than backoff_base"))
+		}
+		if c.Webhooks.MaxAttempts < 1 {
+			errs = append(errs, fmt.Errorf("webhooks.max_attempts: must be at least 1"))
+		}
+	}
+
+	if c.Stream.PollInterval <= 0 || c.Stream.Heartbeat <= 0 || c.Stream.BufferSize < 1 {
+		errs = append(errs, fmt.Errorf("stream: poll_interval, heartbeat and buffer_size must be positive"))
+	}
+
+	if c.Cache.Enabled && (c.Cache.Size < 1 || c.Cache.TTL <= 0) {
+		errs = append(errs, fmt.Errorf("cache: size and ttl must be positive when the cache is enabled"))
+	}
+
+	if c.Backup.Interval < 0 || c.Backup.Retain < 0 {
+		errs = append(errs, fmt.Errorf("backup: interval and retain can't be negative"))
+	}
+	if c.Backup.Interval > 0 {
+		if err := validateWritableDir(c.Backup.Dir); err != nil {
+			errs = append(errs, fmt.Errorf("backup.dir: %w", err))
+		}
+	}
+
+	if _, err := ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	if !slices.Contains([]string{"text", "json"}, c.Log.Format) {
+		errs = append(errs, fmt.Errorf("log.format: unknown format %q, use text or json", c.Log.Format))
+	}
+	switch c.Log.Output {
+	case "stdout", "stderr":
+	case "file":
+		if err := validateWritableDir(filepath.Dir(c.Log.File.Path)); err != nil {
+			errs = append(errs, fmt.Errorf("log.file.path: %w", err))
+		}
+		if c.Log.File.MaxSizeMB < 1 || c.Log.File.MaxBackups < 0 || c.Log.File.MaxAge < 0 {
+			errs = append(errs, fmt.Errorf("log.file: max_size_mb must be positive, max_backups and max_age can't be negative"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("log.output: unknown output %q, use stdout, stderr or file", c.Log.Output))
+	}
+
+	if c.RateLimit.Enabled && (c.RateLimit.RequestsPerSecond <= 0 || c.RateLimit.Burst < 1) {
+		errs = append(errs, fmt.Errorf("rate_limit: requests_per_second and burst must be positive when enabled"))
+	}
+
+	if err := validateWritableDir(c.Photos.Dir); err != nil {
+		errs = append(errs, fmt.Errorf("photos.dir: %w", err))
+	}
+	if c.Photos.MaxBytes < 1 {
+		errs = append(errs, fmt.Errorf("photos.max_bytes: must be positive"))
+	}
+	if c.Photos.ThumbnailSize < 16 || c.Photos.ThumbnailSize > 1024 {
+		errs = append(errs, fmt.Errorf("photos.thumbnail_size: must be between 16 and 1024"))
+	}
+
+	if c.HTTPServer.MaxBodyBytes <= 0 {
+		errs = append(errs, fmt.Errorf("http_server.max_body_bytes: must be positive"))
+	}
+	if c.HTTPServer.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("http_server.shutdown_timeout: must be positive"))
+	}
+
+	if !slices.Contains(c.I18n.Languages, c.I18n.Default) {
+		errs = append(errs, fmt.Errorf("i18n.default: %q is not in i18n.languages", c.I18n.Default))
+	}
+	if c.I18n.Dir != "" {
+		if info, err := os.Stat(c.I18n.Dir); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("i18n.dir: %s is not a directory", c.I18n.Dir))
+		}
+	}
+
+	for version, d := range c.API.Deprecations {
+		if version != "1" && version != "2" {
+			errs = append(errs, fmt.Errorf("api.deprecations: unknown version %q", version))
+		}
+		for name, value := range map[string]string{"since": d.Since, "sunset": d.Sunset} {
+			if _, err := time.Parse("2006-01-02", value); value != "" && err != nil {
+				errs = append(errs, fmt.Errorf("api.deprecations.%s.%s: %q is not a date like 2006-01-02", version, name, value))
+			}
+		}
+	}
+
+	errs = append(errs, validateEncryption(c.Encryption)...)
+
+	if c.Stats.TTL < 0 {
+		errs = append(errs, fmt.Errorf("stats.ttl: can't be negative"))
+	}
+	if err := ValidateBuckets(c.Stats.AgeBuckets); err != nil {
+		errs = append(errs, fmt.Errorf("stats.age_buckets: %w", err))
+	}
+
+	if _, ok := c.Grading.Scales[c.Grading.DefaultScale]; !ok {
+		errs = append(errs, fmt.Errorf("grading.default_scale: no scale named %q", c.Grading.DefaultScale))
+	}
+	for name, scale := range c.Grading.Scales {
+		if err := validateScale(scale); err != nil {
+			errs = append(errs, fmt.Errorf("grading.scales.%s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// EncryptableFields are the student fields encryption.fields may list
+var EncryptableFields = []string{"name", "first_name", "last_name", "email"}
+
+var keyIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+func validateEncryption(e Encryption) []error {
+	var errs []error
+
+	for _, field := range e.Fields {
+		if !slices.Contains(EncryptableFields, field) {
+			errs = append(errs, fmt.Errorf("encryption.fields: %q can't be encrypted, use %s", field, strings.Join(EncryptableFields, ", ")))
+		}
+	}
+	//the three hold the same name, encrypting only some of them would leave it readable
+	names := 0
+	for _, field := range []string{"name", "first_name", "last_name"} {
+		if slices.Contains(e.Fields, field) {
+			names++
+		}
+	}
+	if names != 0 && names != 3 {
+		errs = append(errs, fmt.Errorf("encryption.fields: name, first_name and last_name are encrypted together or not at all"))
+	}
+
+	for id, key := range e.Keys {
+		if !keyIdPattern.MatchString(id) {
+			errs = append(errs, fmt.Errorf("encryption.keys: key id %q may only use letters, digits, '.', '_' and '-'", id))
+		}
+		if err := validateKey(key); err != nil {
+			errs = append(errs, fmt.Errorf("encryption.keys.%s: %w", id, err))
+		}
+	}
+	if e.IndexKey != "" {
+		if err := validateKey(e.IndexKey); err != nil {
+			errs = append(errs, fmt.Errorf("encryption.index_key: %w", err))
+		}
+	}
+
+	if len(e.Fields) > 0 {
+		if _, ok := e.Keys[e.ActiveKey]; !ok {
+			errs = append(errs, fmt.Errorf("encryption.active_key: %q is not in encryption.keys", e.ActiveKey))
+		}
+		if e.IndexKey == "" {
+			errs = append(errs, fmt.Errorf("encryption.index_key: needed when fields are encrypted [email lookups and uniqueness]"))
+		}
+	}
+
+	if e.Interval < 0 || e.BatchSize < 1 {
+		errs = append(errs, fmt.Errorf("encryption: reencrypt_interval can't be negative and reencrypt_batch must be positive"))
+	}
+
+	return errs
+}
+
+// validateKey checks the form of a key, base64 of 32 bytes
+func validateKey(key string) error {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return fmt.Errorf("not base64")
+	}
+	if len(raw) != 32 {
+		return fmt.Errorf("must be 32 bytes, got %d", len(raw))
+	}
+	return nil
+}
+
+// ValidateBuckets checks histogram boundaries, the stats handler uses it for ?buckets= too
+func ValidateBuckets(buckets []int) error {
+	if len(buckets) == 0 || len(buckets) > 50 {
+		return fmt.Errorf("between 1 and 50 boundaries are needed")
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return fmt.Errorf("boundaries must be ascending")
+		}
+	}
+	return nil
+}
+
+// a scale lists its bands from the highest minimum down, and the last one has to catch 0%
+func validateScale(scale []GradeStep) error {
+	if len(scale) == 0 {
+		return fmt.Errorf("no grades")
+	}
+
+	for i, step := range scale {
+		if step.Letter == "" || step.Points < 0 {
+			return fmt.Errorf("grade %d needs a letter and non negative points", i+1)
+		}
+		if i > 0 && step.Min >= scale[i-1].Min {
+			return fmt.Errorf("grades must be ordered from the highest min down")
+		}
+	}
+
+	if scale[len(scale)-1].Min != 0 {
+		return fmt.Errorf("the lowest grade must start at 0")
+	}
+
+	return nil
+}
+
+func validateAddress(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+
+	return nil
+}
+
+// validateWritable makes sure the server will be able to write the db file at path
+// it only asks, validating never creates or changes anything on disk [see writable]
+func validateWritable(path string) error {
+	if path == ":memory:" || strings.Contains(path, "mode=memory") {
+		return nil
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if err := writable(path); err != nil {
+			return fmt.Errorf("not writable: %w", err)
+		}
+		return nil
+	}
+
+	//the file doesn't exist yet, sqlite will create it so the directory has to be writable
+	return validateWritableDir(filepath.Dir(path))
+}
+
+func validateWritableDir(dir string) error {
+	stat, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		//it'll be created, its parent has to be writable then
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		return validateWritableDir(parent)
+	}
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	if err := writable(dir); err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// writable only looks at the permission bits where there is no access(2)
+func writable(path string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if stat.Mode().Perm()&0o222 == 0 {
+		return fmt.Errorf("%s is read-only", path)
+	}
+	return nil
+}
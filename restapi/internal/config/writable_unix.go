@@ -0,0 +1,11 @@
+//go:build unix
+
+package config
+
+import "golang.org/x/sys/unix"
+
+// writable asks the kernel whether the process may write path, without writing anything
+// [access(2), read-only mounts come back as EROFS]
+func writable(path string) error {
+	return unix.Access(path, unix.W_OK)
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// Broker tails the outbox and fans every new event out to the live subscribers
+// it never waits on a subscriber: when one falls behind and its buffer fills up it is dropped
+type Broker struct {
+	store      storage.EventStorage
+	interval   time.Duration
+	bufferSize int
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// Subscription is one listener, Events is closed when the broker drops or shuts it down
+type Subscription struct {
+	Events  chan types.Event
+	dropped bool
+}
+
+func NewBroker(store storage.EventStorage, interval time.Duration, bufferSize int) *Broker {
+	return &Broker{
+		store:      store,
+		interval:   interval,
+		bufferSize: bufferSize,
+		subs:       map[*Subscription]struct{}{},
+	}
+}
+
+// Run polls the outbox until ctx is done, it starts from the newest event so history is
+// only sent to clients that ask for it with Last-Event-ID
+func (b *Broker) Run(ctx context.Context) {
+	lastId, err := b.store.LastEventId()
+	if err != nil {
+		slog.Error("event broker can't read the outbox", slog.String("error", err.Error()))
+		return
+	}
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.Close()
+			return
+		case <-ticker.C:
+		}
+
+		events, err := b.store.EventsAfter(lastId, 100)
+		if err != nil {
+			slog.Error("event broker poll failed", slog.String("error", err.Error()))
+			continue
+		}
+
+		for _, event := range events {
+			b.publish(event)
+			lastId = event.Id
+		}
+	}
+}
+
+func (b *Broker) publish(event types.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		select {
+		case sub.Events <- event:
+		default:
+			//buffer is full, the client isn't reading fast enough so cut it loose
+			slog.Warn("dropping slow event stream subscriber")
+			sub.dropped = true
+			delete(b.subs, sub)
+			close(sub.Events)
+		}
+	}
+}
+
+// Subscribe registers a listener, it returns nil once the broker is closed
+func (b *Broker) Subscribe() *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+
+	sub := &Subscription{Events: make(chan types.Event, b.bufferSize)}
+	b.subs[sub] = struct{}{}
+
+	return sub
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.Events)
+	}
+}
+
+// Dropped reports whether the subscription was cut off for being too slow
+func (b *Broker) Dropped(sub *Subscription) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return sub.dropped
+}
+
+// Close ends every open subscription so the stream handlers return and the server can shut down
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		close(sub.Events)
+	}
+}
@@ -0,0 +1,257 @@
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shivakr07/students-api/internal/config"
+)
+
+// envelope encryption of single values: every value gets its own random data key, the value is
+// sealed with it [AES-256-GCM] and the data key is sealed with a key of the ring [the key encryption key]
+// rotating a key only re-seals the small data keys, the values themselves are never touched
+//
+// a sealed value is plain text so it fits the existing TEXT columns:
+//
+//	enc:v1:<key id>:<sealed data key>:<sealed value>
+//
+// both parts are base64 [raw url] of nonce || ciphertext, the column name is the additional data
+// so a value copied into another column doesn't decrypt
+
+const prefix = "enc:v1:"
+
+const keySize = 32 //AES-256
+
+var ErrUnknownKey = errors.New("value is sealed with a key that isn't in the key ring")
+
+// KeyRing holds every key a value may be sealed with, new values use the active one
+type KeyRing struct {
+	active   string
+	keys     map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// New builds the key ring from encryption.keys, an empty config gives a nil ring [encryption off]
+func New(cfg config.Encryption) (*KeyRing, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, nil
+	}
+
+	ring := &KeyRing{active: cfg.ActiveKey, keys: map[string]cipher.AEAD{}}
+	for id, encoded := range cfg.Keys {
+		key, err := DecodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption.keys.%s: %w", id, err)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		ring.keys[id] = aead
+	}
+
+	if cfg.IndexKey != "" {
+		key, err := DecodeKey(cfg.IndexKey)
+		if err != nil {
+			return nil, fmt.Errorf("encryption.index_key: %w", err)
+		}
+		ring.indexKey = key
+	}
+
+	return ring, nil
+}
+
+// GenerateKey returns a new random key in the form the config takes
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// DecodeKey reads a key from the config, base64 of 32 bytes
+func DecodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("key is not base64: %w", err)
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(key))
+	}
+	return key, nil
+}
+
+// ActiveKey is the id of the key new values are sealed with
+func (k *KeyRing) ActiveKey() string {
+	return k.active
+}
+
+// ActivePrefix is how every value sealed with the active key starts, for finding the ones that aren't
+func (k *KeyRing) ActivePrefix() string {
+	return prefix + k.active + ":"
+}
+
+// IsSealed tells a sealed value from a plain one, rows written before encryption was on are plain
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Seal encrypts value for the given column with the active key
+func (k *KeyRing) Seal(column string, value string) (string, error) {
+	kek, ok := k.keys[k.active]
+	if !ok {
+		return "", fmt.Errorf("no active key to encrypt with")
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	dek, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	sealedKey, err := seal(kek, dataKey, []byte(k.active))
+	if err != nil {
+		return "", err
+	}
+	sealedValue, err := seal(dek, []byte(value), []byte(column))
+	if err != nil {
+		return "", err
+	}
+
+	return k.ActivePrefix() + encode(sealedKey) + ":" + encode(sealedValue), nil
+}
+
+// Open decrypts a sealed value, a plain value comes back as it is
+func (k *KeyRing) Open(column string, value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+
+	_, dataKey, sealedValue, err := k.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+	dek, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := open(dek, sealedValue, []byte(column))
+	if err != nil {
+		return "", fmt.Errorf("can't decrypt %s: %w", column, err)
+	}
+	return string(plain), nil
+}
+
+// Rotate re-seals the data key of a value with the active key, the value part stays as it is
+func (k *KeyRing) Rotate(value string) (string, error) {
+	keyId, dataKey, _, err := k.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+	if keyId == k.active {
+		return value, nil
+	}
+
+	sealedKey, err := seal(k.keys[k.active], dataKey, []byte(k.active))
+	if err != nil {
+		return "", err
+	}
+
+	//the last part is the sealed value, untouched
+	valuePart := value[strings.LastIndex(value, ":")+1:]
+	return k.ActivePrefix() + encode(sealedKey) + ":" + valuePart, nil
+}
+
+// Indexed tells whether the ring has an index_key, without one BlindIndex always returns ""
+func (k *KeyRing) Indexed() bool {
+	return k != nil && k.indexKey != nil
+}
+
+// BlindIndex is a keyed hash of the normalized value, equal values [ignoring case and spaces around]
+// get equal indexes so they can be looked up and checked for uniqueness without decrypting anything
+// it returns "" when the ring has no index_key
+func (k *KeyRing) BlindIndex(value string) string {
+	if !k.Indexed() {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// unwrap splits a sealed value and opens its data key
+func (k *KeyRing) unwrap(value string) (string, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, fmt.Errorf("malformed sealed value")
+	}
+	keyId := parts[0]
+
+	kek, ok := k.keys[keyId]
+	if !ok {
+		return "", nil, nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyId)
+	}
+
+	sealedKey, err := decode(parts[1])
+	if err != nil {
+		return "", nil, nil, err
+	}
+	sealedValue, err := decode(parts[2])
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	dataKey, err := open(kek, sealedKey, []byte(keyId))
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("can't open the data key with key %q: %w", keyId, err)
+	}
+
+	return keyId, dataKey, sealedValue, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns nonce || ciphertext, the nonce is random for every call
+func seal(aead cipher.AEAD, plain []byte, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plain, additional), nil
+}
+
+func open(aead cipher.AEAD, sealed []byte, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("sealed data too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additional)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
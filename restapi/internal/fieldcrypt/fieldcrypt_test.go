@@ -0,0 +1,143 @@
+package fieldcrypt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shivakr07/students-api/internal/config"
+)
+
+// keys generates a key for every id, the same map builds rings that share keys like two configs would
+func keys(t *testing.T, ids ...string) map[string]string {
+	t.Helper()
+
+	keys := map[string]string{}
+	for _, id := range ids {
+		key, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[id] = key
+	}
+	return keys
+}
+
+func newRing(t *testing.T, cfg config.Encryption) *KeyRing {
+	t.Helper()
+
+	ring, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
+
+func TestSealOpen(t *testing.T) {
+	ring := newRing(t, config.Encryption{ActiveKey: "k1", Keys: keys(t, "k1")})
+
+	sealed, err := ring.Seal("students.email", "alan@gmail.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(sealed) || !strings.HasPrefix(sealed, ring.ActivePrefix()) || strings.Contains(sealed, "alan") {
+		t.Fatalf("sealed value %q", sealed)
+	}
+
+	//every seal has its own data key and nonce
+	again, err := ring.Seal("students.email", "alan@gmail.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == sealed {
+		t.Error("sealing the same value twice gave the same text")
+	}
+
+	plain, err := ring.Open("students.email", sealed)
+	if err != nil || plain != "alan@gmail.com" {
+		t.Fatalf("open: got %q, %v", plain, err)
+	}
+
+	//the column is the additional data, the value doesn't open anywhere else
+	if _, err := ring.Open("students.name", sealed); err == nil {
+		t.Error("a value moved to another column opened")
+	}
+
+	//rows from before encryption come back as they are
+	if plain, err := ring.Open("students.email", "ada@example.com"); err != nil || plain != "ada@example.com" {
+		t.Errorf("plain value: got %q, %v", plain, err)
+	}
+
+	other := newRing(t, config.Encryption{ActiveKey: "k2", Keys: keys(t, "k2")})
+	if _, err := other.Open("students.email", sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("open without the key: got %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	keys := keys(t, "k1", "k2")
+	old := newRing(t, config.Encryption{ActiveKey: "k1", Keys: map[string]string{"k1": keys["k1"]}})
+	both := newRing(t, config.Encryption{ActiveKey: "k2", Keys: keys})
+	current := newRing(t, config.Encryption{ActiveKey: "k2", Keys: map[string]string{"k2": keys["k2"]}})
+
+	sealed, err := old.Seal("students.name", "Alan Turing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := both.Rotate(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rotated, both.ActivePrefix()) {
+		t.Fatalf("rotated value %q isn't on k2", rotated)
+	}
+	//only the data key is sealed again, the value part stays as it is
+	if rotated[strings.LastIndex(rotated, ":"):] != sealed[strings.LastIndex(sealed, ":"):] {
+		t.Error("rotate touched the sealed value")
+	}
+
+	//once everything is rotated k1 can leave the ring
+	plain, err := current.Open("students.name", rotated)
+	if err != nil || plain != "Alan Turing" {
+		t.Fatalf("open with k2 only: got %q, %v", plain, err)
+	}
+	if _, err := current.Open("students.name", sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("value not rotated: got %v, want ErrUnknownKey", err)
+	}
+
+	//a value already on the active key is left alone
+	if again, err := current.Rotate(rotated); err != nil || again != rotated {
+		t.Errorf("rotate on the active key: got %q, %v", again, err)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	keys := keys(t, "k1", "index", "other")
+	ring := newRing(t, config.Encryption{ActiveKey: "k1", Keys: map[string]string{"k1": keys["k1"]}, IndexKey: keys["index"]})
+
+	index := ring.BlindIndex("alan@gmail.com")
+	if index == "" {
+		t.Fatal("no index with an index_key")
+	}
+	for _, email := range []string{"Alan@Gmail.com", "  ALAN@GMAIL.COM\t"} {
+		if got := ring.BlindIndex(email); got != index {
+			t.Errorf("%q: got %s, want the index of alan@gmail.com", email, got)
+		}
+	}
+	if ring.BlindIndex("alan@gmail.co") == index {
+		t.Error("different emails got the same index")
+	}
+
+	other := newRing(t, config.Encryption{ActiveKey: "k1", Keys: map[string]string{"k1": keys["k1"]}, IndexKey: keys["other"]})
+	if other.BlindIndex("alan@gmail.com") == index {
+		t.Error("another index_key gave the same index")
+	}
+
+	//without an index_key, or without encryption at all, there is no index
+	noIndex := newRing(t, config.Encryption{ActiveKey: "k1", Keys: map[string]string{"k1": keys["k1"]}})
+	off := newRing(t, config.Encryption{})
+	if noIndex.BlindIndex("alan@gmail.com") != "" || off.BlindIndex("alan@gmail.com") != "" {
+		t.Error("index without an index_key")
+	}
+}
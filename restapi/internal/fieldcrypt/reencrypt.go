@@ -0,0 +1,61 @@
+package fieldcrypt
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/storage"
+)
+
+// Reencryptor moves stored values to the current config in the background: a new active key, a field
+// added to or taken out of encryption.fields, rows from before encryption was turned on
+type Reencryptor struct {
+	store storage.EncryptionStorage
+	cfg   config.Encryption
+}
+
+func NewReencryptor(store storage.EncryptionStorage, cfg config.Encryption) *Reencryptor {
+	return &Reencryptor{store: store, cfg: cfg}
+}
+
+// Run blocks until ctx is cancelled, the first pass starts right away so a rotation doesn't wait an interval
+func (r *Reencryptor) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	slog.Info("re-encryption started", slog.String("interval", r.cfg.Interval.String()))
+
+	for {
+		r.pass(ctx)
+
+		select {
+		case <-ctx.Done():
+			slog.Info("re-encryption stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pass works through batches until nothing is left, every batch is its own short transaction
+// so requests get the write lock in between
+func (r *Reencryptor) pass(ctx context.Context) {
+	total := 0
+	for ctx.Err() == nil {
+		n, err := r.store.Reencrypt(r.cfg.BatchSize)
+		if err != nil {
+			slog.Error("re-encryption failed", slog.String("error", err.Error()), slog.Int("rows", total))
+			return
+		}
+		total += n
+		if n == 0 {
+			break
+		}
+	}
+
+	if total > 0 {
+		slog.Info("re-encrypted rows", slog.Int("rows", total), slog.String("active_key", r.cfg.ActiveKey))
+	}
+}
@@ -0,0 +1,335 @@
+package filter
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// a small filter language for list endpoints, like: age>=18 and (name~"sha" or email="a@b.c")
+//
+//	expr       := and ("or" and)*
+//	and        := unary ("and" unary)*
+//	unary      := "not" unary | "(" expr ")" | comparison
+//	comparison := field op value
+//	op         := = != > >= < <= ~          [~ means contains, strings only]
+//	value      := number | "string"         [\" and \\ escape inside strings]
+//
+// Parse only builds and type checks the AST, turning it into a query is the storage backend's job
+
+// Kind is the type of a field, it decides which values and operators are allowed
+type Kind int
+
+const (
+	Number Kind = iota
+	String
+)
+
+// limits so a query string can't make us do unbounded work
+const (
+	maxLength = 1024
+	maxNodes  = 64
+)
+
+// Expr is a node of the AST: And, Or, Not or Comparison
+type Expr interface {
+	expr()
+}
+
+type And struct{ Left, Right Expr }
+type Or struct{ Left, Right Expr }
+type Not struct{ Expr Expr }
+
+// Comparison is a leaf, Value is an int64 for Number fields and a string for String fields
+type Comparison struct {
+	Field string
+	Op    string
+	Value any
+}
+
+func (And) expr()        {}
+func (Or) expr()         {}
+func (Not) expr()        {}
+func (Comparison) expr() {}
+
+// Error points at the place in the input the parser choked on
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("filter: at position %d: %s", e.Pos+1, e.Msg)
+}
+
+// Parse parses input against the fields that may be filtered on, an empty input gives a nil Expr
+func Parse(input string, fields map[string]Kind) (Expr, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+	if len(input) > maxLength {
+		return nil, &Error{Pos: maxLength, Msg: fmt.Sprintf("filter is longer than %d characters", maxLength)}
+	}
+
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, fields: fields}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+
+	return e, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string // strings are already unescaped
+	pos  int
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(input); {
+		c := rune(input[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '(' || c == ')':
+			kind := tokenLParen
+			if c == ')' {
+				kind = tokenRParen
+			}
+			tokens = append(tokens, token{kind: kind, text: string(c), pos: i})
+			i++
+
+		case strings.ContainsRune("=!<>~", c):
+			start := i
+			i++
+			if i < len(input) && input[i] == '=' && c != '=' && c != '~' {
+				i++
+			}
+			op := input[start:i]
+			if op == "!" {
+				return nil, &Error{Pos: start, Msg: `"!" must be followed by "="`}
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: start})
+
+		case c == '"':
+			start := i
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(input) {
+					return nil, &Error{Pos: start, Msg: "unterminated string"}
+				}
+				if input[i] == '\\' && i+1 < len(input) && (input[i+1] == '"' || input[i+1] == '\\') {
+					b.WriteByte(input[i+1])
+					i += 2
+					continue
+				}
+				if input[i] == '"' {
+					i++
+					break
+				}
+				b.WriteByte(input[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenString, text: b.String(), pos: start})
+
+		case c == '-' || unicode.IsDigit(c):
+			start := i
+			i++
+			for i < len(input) && unicode.IsDigit(rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: input[start:i], pos: start})
+
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(input) && (unicode.IsLetter(rune(input[i])) || unicode.IsDigit(rune(input[i])) || input[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: input[start:i], pos: start})
+
+		default:
+			return nil, &Error{Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, text: "end of filter", pos: len(input)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	nodes  int
+	fields map[string]Kind
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword matches and/or/not case insensitively
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == tokenIdent && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) node(pos int) error {
+	p.nodes++
+	if p.nodes > maxNodes {
+		return &Error{Pos: pos, Msg: fmt.Sprintf("filter has more than %d terms", maxNodes)}
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		pos := p.peek().pos
+		if !p.keyword("or") {
+			return left, nil
+		}
+		if err := p.node(pos); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		pos := p.peek().pos
+		if !p.keyword("and") {
+			return left, nil
+		}
+		if err := p.node(pos); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	t := p.peek()
+	if err := p.node(t.pos); err != nil {
+		return nil, err
+	}
+
+	if p.keyword("not") {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: e}, nil
+	}
+
+	if t.kind == tokenLParen {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, &Error{Pos: closing.pos, Msg: fmt.Sprintf("expected ) but got %q", closing.text)}
+		}
+		return e, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	field := p.next()
+	if field.kind != tokenIdent {
+		return nil, &Error{Pos: field.pos, Msg: fmt.Sprintf("expected a field name but got %q", field.text)}
+	}
+	kind, ok := p.fields[field.text]
+	if !ok {
+		return nil, &Error{Pos: field.pos, Msg: fmt.Sprintf("unknown field %q, use %s", field.text, names(p.fields))}
+	}
+
+	op := p.next()
+	if op.kind != tokenOp {
+		return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("expected an operator after %s but got %q", field.text, op.text)}
+	}
+	if op.text == "~" && kind != String {
+		return nil, &Error{Pos: op.pos, Msg: fmt.Sprintf("~ only works on text fields, %s is a number", field.text)}
+	}
+
+	value := p.next()
+	switch {
+	case kind == Number && value.kind == tokenNumber:
+		n, err := strconv.ParseInt(value.text, 10, 64)
+		if err != nil {
+			return nil, &Error{Pos: value.pos, Msg: fmt.Sprintf("%s is not a valid number", value.text)}
+		}
+		return Comparison{Field: field.text, Op: op.text, Value: n}, nil
+	case kind == String && value.kind == tokenString:
+		return Comparison{Field: field.text, Op: op.text, Value: value.text}, nil
+	case kind == Number:
+		return nil, &Error{Pos: value.pos, Msg: fmt.Sprintf("%s needs a number but got %q", field.text, value.text)}
+	default:
+		return nil, &Error{Pos: value.pos, Msg: fmt.Sprintf("%s needs a quoted string but got %q", field.text, value.text)}
+	}
+}
+
+func names(fields map[string]Kind) string {
+	list := make([]string, 0, len(fields))
+	for name := range fields {
+		list = append(list, name)
+	}
+	slices.Sort(list)
+	return strings.Join(list, ", ")
+}
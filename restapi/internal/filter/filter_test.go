@@ -0,0 +1,120 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var fields = map[string]Kind{"name": String, "email": String, "age": Number}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Expr
+	}{
+		{"empty", "  ", nil},
+		{"number", "age>=18", Comparison{Field: "age", Op: ">=", Value: int64(18)}},
+		{"negative number", "age > -1", Comparison{Field: "age", Op: ">", Value: int64(-1)}},
+		{"string", `name="Ada"`, Comparison{Field: "name", Op: "=", Value: "Ada"}},
+		{"contains", `name~"ov"`, Comparison{Field: "name", Op: "~", Value: "ov"}},
+		{"escaped quote and backslash", `name="a\"b\\c"`, Comparison{Field: "name", Op: "=", Value: `a"b\c`}},
+		//a backslash before anything else is kept as it is
+		{"lone backslash", `name="50\%"`, Comparison{Field: "name", Op: "=", Value: `50\%`}},
+		{"keywords inside a string", `name="a or b"`, Comparison{Field: "name", Op: "=", Value: "a or b"}},
+		{
+			name:  "and binds tighter than or",
+			input: `age<18 or age>60 and name="Ada"`,
+			want: Or{
+				Left: Comparison{Field: "age", Op: "<", Value: int64(18)},
+				Right: And{
+					Left:  Comparison{Field: "age", Op: ">", Value: int64(60)},
+					Right: Comparison{Field: "name", Op: "=", Value: "Ada"},
+				},
+			},
+		},
+		{
+			name:  "parentheses",
+			input: `(age<18 or age>60) and name="Ada"`,
+			want: And{
+				Left: Or{
+					Left:  Comparison{Field: "age", Op: "<", Value: int64(18)},
+					Right: Comparison{Field: "age", Op: ">", Value: int64(60)},
+				},
+				Right: Comparison{Field: "name", Op: "=", Value: "Ada"},
+			},
+		},
+		{
+			name:  "not binds tighter than and",
+			input: `not age=1 and age=2`,
+			want: And{
+				Left:  Not{Expr: Comparison{Field: "age", Op: "=", Value: int64(1)}},
+				Right: Comparison{Field: "age", Op: "=", Value: int64(2)},
+			},
+		},
+		{
+			name:  "left to right",
+			input: `age=1 OR age=2 Or age=3`,
+			want: Or{
+				Left: Or{
+					Left:  Comparison{Field: "age", Op: "=", Value: int64(1)},
+					Right: Comparison{Field: "age", Op: "=", Value: int64(2)},
+				},
+				Right: Comparison{Field: "age", Op: "=", Value: int64(3)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input, fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		pos   int
+		msg   string
+	}{
+		{"unknown field", `nmae="Ada"`, 0, `unknown field "nmae", use age, email, name`},
+		//a column name that isn't in the list is just an unknown field
+		{"sql in a field", `name="a" or 1=1`, 12, `expected a field name but got "1"`},
+		{"string for a number", `age="18"`, 4, `age needs a number but got "18"`},
+		{"number for a string", `name=1`, 5, `name needs a quoted string but got "1"`},
+		{"contains on a number", `age~1`, 3, `~ only works on text fields, age is a number`},
+		{"unterminated string", `name="Ada`, 5, "unterminated string"},
+		{"escaped closing quote", `name="Ada\"`, 5, "unterminated string"},
+		{"bang without equals", `age!18`, 3, `"!" must be followed by "="`},
+		{"stray character", `age=18;`, 6, `unexpected character ';'`},
+		{"missing parenthesis", `(age=18`, 7, `expected ) but got "end of filter"`},
+		{"missing operator", `age 18`, 4, `expected an operator after age but got "18"`},
+		{"trailing tokens", `age=18 age=19`, 7, `unexpected "age"`},
+		{"too long", strings.Repeat(" ", maxLength) + "age=1", maxLength, "filter is longer than 1024 characters"},
+		{"too many terms", strings.Repeat("not ", maxNodes) + "age=1", 4 * maxNodes, "filter has more than 64 terms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input, fields)
+
+			var filterErr *Error
+			if !errors.As(err, &filterErr) {
+				t.Fatalf("got %v, want a *filter.Error", err)
+			}
+			if filterErr.Pos != tt.pos || filterErr.Msg != tt.msg {
+				t.Errorf("got position %d %q, want %d %q", filterErr.Pos, filterErr.Msg, tt.pos, tt.msg)
+			}
+		})
+	}
+}
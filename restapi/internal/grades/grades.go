@@ -0,0 +1,115 @@
+package grades
+
+import (
+	"math"
+
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// the maths behind transcripts, kept away from storage and http so the rules live in one place:
+// - a course percentage is the weighted average of the assessments graded so far
+// - the percentage maps to a letter and grade points through the course's scale
+// - a gpa is the credit weighted average of the grade points, ungraded courses don't count
+
+// Percent is the weighted average of the graded assessments, nil when nothing is graded yet
+func Percent(assessments []types.AssessmentScore) *float64 {
+	var total, weights float64
+	for _, assessment := range assessments {
+		if assessment.Score == nil {
+			continue
+		}
+		total += *assessment.Score * assessment.Weight
+		weights += assessment.Weight
+	}
+
+	if weights == 0 {
+		return nil
+	}
+
+	percent := round(total / weights)
+	return &percent
+}
+
+// Grade finds the band of the scale the percentage falls in, scales are ordered from the highest min down
+func Grade(scale []config.GradeStep, percent float64) config.GradeStep {
+	for _, step := range scale {
+		if percent >= step.Min {
+			return step
+		}
+	}
+
+	//validated scales end at 0 so this is only reached for negative input
+	return scale[len(scale)-1]
+}
+
+// Scale returns the named scale, falling back to the default one for courses without a scale
+// (or with one that was removed from the config since)
+func Scale(cfg config.Grading, name string) []config.GradeStep {
+	if scale, ok := cfg.Scales[name]; ok {
+		return scale
+	}
+	return cfg.Scales[cfg.DefaultScale]
+}
+
+// Build computes the transcript, terms keep the order the student enrolled in them
+func Build(student types.Student, courses []types.CourseGrades, cfg config.Grading) types.Transcript {
+	transcript := types.Transcript{Student: student, Terms: []types.TermSummary{}}
+
+	termIndex := map[string]int{}
+	termPoints := map[string]float64{}
+	var totalPoints float64
+
+	for _, course := range courses {
+		line := types.TranscriptCourse{
+			CourseId: course.Course.Id,
+			Code:     course.Course.Code,
+			Title:    course.Course.Title,
+			Credits:  course.Course.Credits,
+			Percent:  Percent(course.Assessments),
+		}
+
+		term := course.Course.Term
+		i, ok := termIndex[term]
+		if !ok {
+			i = len(transcript.Terms)
+			termIndex[term] = i
+			transcript.Terms = append(transcript.Terms, types.TermSummary{Term: term, Courses: []types.TranscriptCourse{}})
+		}
+
+		if line.Percent != nil {
+			step := Grade(Scale(cfg, course.Course.Scale), *line.Percent)
+			points := step.Points
+			line.Letter = step.Letter
+			line.Points = &points
+
+			transcript.Terms[i].Credits += line.Credits
+			termPoints[term] += points * line.Credits
+			transcript.Credits += line.Credits
+			totalPoints += points * line.Credits
+		}
+
+		transcript.Terms[i].Courses = append(transcript.Terms[i].Courses, line)
+	}
+
+	for i, summary := range transcript.Terms {
+		transcript.Terms[i].GPA = gpa(termPoints[summary.Term], summary.Credits)
+	}
+	transcript.CumulativeGPA = gpa(totalPoints, transcript.Credits)
+
+	return transcript
+}
+
+func gpa(points float64, credits float64) *float64 {
+	if credits == 0 {
+		return nil
+	}
+
+	value := round(points / credits)
+	return &value
+}
+
+// two decimals, like a transcript prints them
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,36 @@
+package backups
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/shivakr07/students-api/internal/backup"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// New takes a consistent snapshot right now, same as the scheduled ones
+func New(manager *backup.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slog.Info("backup requested")
+
+		info, err := manager.Snapshot(r.Context())
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		response.WriteJson(w, http.StatusCreated, info)
+	}
+}
+
+func GetList(manager *backup.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		backups, err := manager.List()
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, backups)
+	}
+}
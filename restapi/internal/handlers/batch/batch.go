@@ -0,0 +1,183 @@
+package batch
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shivakr07/students-api/internal/api"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/request"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// a batch holds the write lock for as long as it runs, so it can't be unbounded
+const maxOperations = 500
+
+// errRolledBack marks the operations that succeeded but were undone because a later one failed
+var errRolledBack = errors.New("rolled back")
+
+// New serves POST /api/batch, the storage has to be able to run transactions [storage.Transactor]
+// students in the operations are in the json form of the api version [codec]
+func New(store storage.Storage, codec api.StudentCodec, reader request.Reader) (http.HandlerFunc, error) {
+	transactor, ok := store.(storage.Transactor)
+	if !ok {
+		return nil, fmt.Errorf("batch: storage %T doesn't support transactions", store)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.BatchRequest
+
+		if err := reader.DecodeJson(w, r, &req); err != nil {
+			response.WriteJson(w, request.Status(err), response.GeneralError(err))
+			return
+		}
+
+		if len(req.Operations) == 0 {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(i18n.Errorf("batch.empty", "no operations")))
+			return
+		}
+		if len(req.Operations) > maxOperations {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(i18n.Errorf("batch.too_many", "at most %d operations per batch", maxOperations)))
+			return
+		}
+
+		slog.Info("running a batch", slog.Int("operations", len(req.Operations)), slog.Bool("continue_on_error", req.ContinueOnError))
+
+		results := make([]types.BatchResult, len(req.Operations))
+		failed := false
+
+		err := transactor.InTx(func(tx storage.TxStorage) error {
+			for i, op := range req.Operations {
+				results[i] = types.BatchResult{Index: i, Op: op.Op}
+
+				var id int64
+				//each operation gets a savepoint, so a failed one leaves nothing half done behind
+				opErr := tx.Savepoint(func() error {
+					var err error
+					id, err = apply(tx, codec, op)
+					return err
+				})
+
+				if opErr != nil {
+					failed = true
+					results[i].Status, results[i].Error = errorStatus(opErr), opErr.Error()
+					if !req.ContinueOnError {
+						return opErr
+					}
+					continue
+				}
+
+				results[i].Id = id
+				results[i].Status = http.StatusOK
+				if op.Op == types.BatchCreate {
+					results[i].Status = http.StatusCreated
+				}
+			}
+			return nil
+		})
+
+		if err != nil {
+			//everything before the failure was undone, everything after it never ran
+			for i := range results {
+				switch {
+				case results[i].Status == 0:
+					results[i] = types.BatchResult{Index: i, Op: req.Operations[i].Op, Status: http.StatusFailedDependency, Error: "not run"}
+				case results[i].Error == "":
+					//the id was never committed, nobody should go looking for it
+					results[i].Id = 0
+					results[i].Status, results[i].Error = http.StatusFailedDependency, errRolledBack.Error()
+				}
+			}
+
+			slog.Info("batch rolled back", slog.String("error", err.Error()))
+			response.WriteJson(w, http.StatusUnprocessableEntity, types.BatchResponse{Committed: false, Results: results})
+			return
+		}
+
+		status := http.StatusOK
+		if failed {
+			status = http.StatusMultiStatus
+		}
+		response.WriteJson(w, status, types.BatchResponse{Committed: true, Results: results})
+	}, nil
+}
+
+// apply runs one operation, with the same decoding and validation the single student endpoints use
+func apply(tx storage.TxStorage, codec api.StudentCodec, op types.BatchOperation) (int64, error) {
+	switch op.Op {
+	case types.BatchCreate, types.BatchUpdate:
+		if len(op.Student) == 0 {
+			return 0, invalid(fmt.Errorf("%s needs a student", op.Op))
+		}
+	case types.BatchDelete:
+	default:
+		return 0, invalid(fmt.Errorf("unknown op %q, use create, update or delete", op.Op))
+	}
+
+	if op.Op != types.BatchCreate && op.Id <= 0 {
+		return 0, invalid(fmt.Errorf("%s needs an id", op.Op))
+	}
+
+	switch op.Op {
+	case types.BatchCreate:
+		s, err := decode(codec, op.Student, types.Student{})
+		if err != nil {
+			return 0, err
+		}
+		return tx.CreateStudent(s)
+	case types.BatchUpdate:
+		//the update is applied onto the stored student, fields this version doesn't have are kept
+		current, err := tx.GetStudentById(op.Id)
+		if err != nil {
+			return 0, err
+		}
+		s, err := decode(codec, op.Student, current)
+		if err != nil {
+			return 0, err
+		}
+		return op.Id, tx.UpdateStudent(op.Id, s)
+	default:
+		return op.Id, tx.DeleteStudent(op.Id)
+	}
+}
+
+func decode(codec api.StudentCodec, data []byte, current types.Student) (types.Student, error) {
+	s, err := codec.Decode(data, current)
+	var validateErrors validator.ValidationErrors
+	if errors.As(err, &validateErrors) {
+		//same wording as a single create gets back
+		return types.Student{}, invalid(errors.New(response.ValidationError(validateErrors).Error))
+	}
+	if err != nil {
+		return types.Student{}, invalid(err)
+	}
+	return s, nil
+}
+
+// invalidError is a request problem [400], from storage a taken email is a 409, a missing student a 404
+// and anything else [a locked database, a failed write] went wrong on our side
+type invalidError struct{ err error }
+
+func (e invalidError) Error() string { return e.err.Error() }
+func (e invalidError) Unwrap() error { return e.err }
+
+func invalid(err error) error { return invalidError{err: err} }
+
+func errorStatus(err error) int {
+	var invalidErr invalidError
+	switch {
+	case errors.As(err, &invalidErr):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrEmailTaken):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrStudentNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,85 @@
+package batch_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/shivakr07/students-api/internal/apitest"
+)
+
+func TestRollback(t *testing.T) {
+	h := apitest.New(t)
+
+	//the update fails, the create before it is undone and the delete after it never runs
+	h.POST("/api/batch").JSON(`{"operations": [
+		{"op": "create", "student": {"name": "Alan Turing", "email": "alan@gmail.com", "age": 25}},
+		{"op": "update", "id": 9, "student": {"name": "Nobody"}},
+		{"op": "delete", "id": 1}
+	]}`).Do().
+		Status(http.StatusUnprocessableEntity).
+		JSON(`{"committed": false, "results": [
+			{"index": 0, "op": "create", "status": 424, "error": "rolled back"},
+			{"index": 1, "op": "update", "status": 404, "error": "no student found with id 9"},
+			{"index": 2, "op": "delete", "status": 424, "error": "not run"}
+		]}`)
+
+	h.GET("/api/students").Do().Status(http.StatusOK).JSON(`[]`)
+}
+
+func TestContinueOnError(t *testing.T) {
+	h := apitest.New(t)
+	h.LoadStudents()
+
+	//the outbox insert comes after the student insert, failing it fails the create half way through
+	if _, err := h.Storage.Db.Exec(`CREATE TRIGGER no_grace BEFORE INSERT ON outbox
+		WHEN NEW.payload LIKE '%grace@example.com%'
+		BEGIN SELECT RAISE(ABORT, 'disk I/O error'); END`); err != nil {
+		t.Fatal(err)
+	}
+
+	h.POST("/api/batch").JSON(`{"continue_on_error": true, "operations": [
+		{"op": "create", "student": {"name": "Edsger Dijkstra", "email": "edsger@example.com", "age": 40}},
+		{"op": "create", "student": {"name": "Alan Again", "email": "alan@gmail.com", "age": 25}},
+		{"op": "delete", "id": 9},
+		{"op": "create", "student": {"name": "Grace Hopper", "email": "grace@example.com", "age": 40}},
+		{"op": "rename", "id": 1},
+		{"op": "update", "id": 2, "student": {"name": "Ada King", "email": "ada@example.com", "age": 36}}
+	]}`).Do().
+		Status(http.StatusMultiStatus).
+		JSON(`{"committed": true, "results": [
+			{"index": 0, "op": "create", "status": 201, "id": 4},
+			{"index": 1, "op": "create", "status": 409, "error": "email is already used by another student"},
+			{"index": 2, "op": "delete", "status": 404, "error": "no student found with id 9"},
+			{"index": 3, "op": "create", "status": 500, "error": "disk I/O error"},
+			{"index": 4, "op": "rename", "status": 400, "error": "unknown op \"rename\", use create, update or delete"},
+			{"index": 5, "op": "update", "status": 200, "id": 2}
+		]}`)
+
+	//the savepoint took the half done create back, the others are committed
+	h.GET("/api/students?fields=id,name").Do().Status(http.StatusOK).JSON(`[
+		{"id": 1, "name": "Alan Turing"},
+		{"id": 2, "name": "Ada King"},
+		{"id": 3, "name": "Grace"},
+		{"id": 4, "name": "Edsger Dijkstra"}
+	]`)
+}
+
+func TestAllCommitted(t *testing.T) {
+	h := apitest.New(t)
+
+	h.POST("/api/batch").JSON(`{"operations": [
+		{"op": "create", "student": {"name": "Alan Turing", "email": "alan@gmail.com", "age": 25}},
+		{"op": "update", "id": 1, "student": {"name": "Alan M. Turing", "email": "alan@gmail.com", "age": 25}},
+		{"op": "delete", "id": 1}
+	]}`).Do().
+		Status(http.StatusOK).
+		JSON(`{"committed": true, "results": [
+			{"index": 0, "op": "create", "status": 201, "id": 1},
+			{"index": 1, "op": "update", "status": 200, "id": 1},
+			{"index": 2, "op": "delete", "status": 200, "id": 1}
+		]}`)
+
+	h.POST("/api/batch").JSON(`{"operations": []}`).Do().
+		Status(http.StatusBadRequest).
+		JSON(`{"status": "Error", "error": "no operations"}`)
+}
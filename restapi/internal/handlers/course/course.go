@@ -0,0 +1,189 @@
+package course
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/request"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// courses and enrollments, same pattern as the student handlers, storage comes in as a dependency
+
+// New needs the grading config to check the scale a course asks for exists
+func New(storage storage.CourseStorage, grading config.Grading, reader request.Reader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slog.Info("creating a course")
+
+		var course types.Course
+
+		if err := reader.DecodeJson(w, r, &course); err != nil {
+			response.WriteJson(w, request.Status(err), response.GeneralError(err))
+			return
+		}
+
+		if err := validator.New().Struct(course); err != nil {
+			validateErrors := err.(validator.ValidationErrors)
+			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrors))
+			return
+		}
+
+		if _, ok := grading.Scales[course.Scale]; course.Scale != "" && !ok {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(i18n.Errorf("course.unknown_scale", "unknown grading scale \"%s\"", course.Scale)))
+			return
+		}
+
+		id, err := storage.CreateCourse(course.Code, course.Title, course.Term, course.Credits, course.Scale, course.Capacity)
+		if err != nil {
+			response.WriteJson(w, storageStatus(err), response.GeneralError(err))
+			return
+		}
+
+		slog.Info("course created", slog.String("id", fmt.Sprint(id)), slog.String("code", course.Code))
+		response.WriteJson(w, http.StatusCreated, map[string]int64{"id": id})
+	}
+}
+
+func GetById(storage storage.CourseStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		course, err := storage.GetCourseById(id)
+		if err != nil {
+			response.WriteJson(w, storageStatus(err), response.GeneralError(err))
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, course)
+	}
+}
+
+func GetList(storage storage.CourseStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		courses, err := storage.GetCourses()
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, courses)
+	}
+}
+
+// Enroll takes {"student_id": 1}, a full course or a second enrollment answers 409
+func Enroll(storage storage.CourseStorage, reader request.Reader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		courseId, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		var enrollment types.Enrollment
+
+		if err := reader.DecodeJson(w, r, &enrollment); err != nil {
+			response.WriteJson(w, request.Status(err), response.GeneralError(err))
+			return
+		}
+
+		if err := validator.New().Struct(enrollment); err != nil {
+			validateErrors := err.(validator.ValidationErrors)
+			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrors))
+			return
+		}
+
+		enrollment, err = storage.Enroll(courseId, enrollment.StudentId)
+		if err != nil {
+			response.WriteJson(w, storageStatus(err), response.GeneralError(err))
+			return
+		}
+
+		slog.Info("student enrolled", slog.String("course", fmt.Sprint(courseId)), slog.String("student", fmt.Sprint(enrollment.StudentId)))
+		response.WriteJson(w, http.StatusCreated, enrollment)
+	}
+}
+
+// Drop marks the enrollment as dropped, the seat becomes free again
+func Drop(storage storage.CourseStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		courseId, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		studentId, err := request.PathId(r, "studentId")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		if err := storage.DropEnrollment(courseId, studentId); err != nil {
+			response.WriteJson(w, storageStatus(err), response.GeneralError(err))
+			return
+		}
+
+		slog.Info("enrollment dropped", slog.String("course", fmt.Sprint(courseId)), slog.String("student", fmt.Sprint(studentId)))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func GetEnrollments(storage storage.CourseStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		courseId, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		enrollments, err := storage.GetCourseEnrollments(courseId)
+		if err != nil {
+			response.WriteJson(w, storageStatus(err), response.GeneralError(err))
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, enrollments)
+	}
+}
+
+// GetStudentCourses serves GET /api/students/{id}/courses
+func GetStudentCourses(storage storage.CourseStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		studentId, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		enrollments, err := storage.GetStudentCourses(studentId)
+		if err != nil {
+			response.WriteJson(w, storageStatus(err), response.GeneralError(err))
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, enrollments)
+	}
+}
+
+// a taken course code and refused enrollments are conflicts, a course, student or enrollment that
+// doesn't exist is a 404 and anything else went wrong on our side
+func storageStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrCourseCodeTaken), errors.Is(err, storage.ErrCourseFull), errors.Is(err, storage.ErrAlreadyEnrolled):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrCourseNotFound), errors.Is(err, storage.ErrStudentNotFound), errors.Is(err, storage.ErrNotEnrolled):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,80 @@
+package course_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/shivakr07/students-api/internal/apitest"
+)
+
+const cs101 = `{"code": "CS101", "title": "Computing", "term": "2026-fall", "credits": 3, "capacity": 1}`
+
+func TestNew(t *testing.T) {
+	h := apitest.New(t)
+
+	h.POST("/api/courses").JSON(cs101).Do().Status(http.StatusCreated).JSON(`{"id": 1}`)
+
+	h.POST("/api/courses").JSON(cs101).Do().
+		Status(http.StatusConflict).
+		JSON(`{"status": "Error", "error": "course code is already used by another course"}`)
+	h.POST("/api/courses").JSON(cs101).Header("Accept-Language", "fr").Do().
+		Status(http.StatusConflict).
+		Contains("déjà utilisé")
+}
+
+func TestEnroll(t *testing.T) {
+	h := apitest.New(t)
+	h.POST("/api/courses").JSON(cs101).Do().Status(http.StatusCreated)
+	h.POST("/api/students").JSON(`{"name": "Alan Turing", "email": "alan@gmail.com", "age": 25}`).Do().Status(http.StatusCreated)
+	h.POST("/api/students").JSON(`{"name": "Ada Lovelace", "email": "ada@example.com", "age": 36}`).Do().Status(http.StatusCreated)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		status  int
+		contain string
+	}{
+		{"enroll", http.MethodPost, "/api/courses/1/enrollments", `{"student_id": 1}`, http.StatusCreated, `"status":"enrolled"`},
+		{"already enrolled", http.MethodPost, "/api/courses/1/enrollments", `{"student_id": 1}`, http.StatusConflict, "already enrolled"},
+		{"course full", http.MethodPost, "/api/courses/1/enrollments", `{"student_id": 2}`, http.StatusConflict, "course is full"},
+		{"no such course", http.MethodPost, "/api/courses/9/enrollments", `{"student_id": 1}`, http.StatusNotFound, "no course found with id 9"},
+		{"no such student", http.MethodPost, "/api/courses/1/enrollments", `{"student_id": 9}`, http.StatusNotFound, "no student found with id 9"},
+		{"drop", http.MethodDelete, "/api/courses/1/enrollments/1", ``, http.StatusNoContent, ""},
+		{"drop again", http.MethodDelete, "/api/courses/1/enrollments/1", ``, http.StatusNotFound, "not enrolled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := h.Request(tt.method, tt.path)
+			if tt.body != "" {
+				req.JSON(tt.body)
+			}
+			res := req.Do().Status(tt.status)
+			if tt.contain != "" {
+				res.Contains(tt.contain)
+			}
+		})
+	}
+}
+
+func TestReads(t *testing.T) {
+	h := apitest.New(t)
+	h.POST("/api/courses").JSON(cs101).Do().Status(http.StatusCreated)
+	h.LoadStudents()
+
+	h.GET("/api/courses/1").Do().Status(http.StatusOK).Field("code", "CS101")
+	h.GET("/api/courses/9").Do().Status(http.StatusNotFound).Contains("no course found with id 9")
+	h.GET("/api/courses/1/enrollments").Do().Status(http.StatusOK).JSON(`[]`)
+	h.GET("/api/courses/9/enrollments").Do().Status(http.StatusNotFound).Contains("no course found with id 9")
+	h.GET("/api/students/1/courses").Do().Status(http.StatusOK).JSON(`[]`)
+	h.GET("/api/students/9/courses").Do().Status(http.StatusNotFound).Contains("no student found with id 9")
+
+	//a failing query isn't a missing course or student
+	if _, err := h.Storage.Db.Exec("DROP TABLE enrollments"); err != nil {
+		t.Fatal(err)
+	}
+	h.GET("/api/courses/1/enrollments").Do().Status(http.StatusInternalServerError)
+	h.GET("/api/students/1/courses").Do().Status(http.StatusInternalServerError)
+}
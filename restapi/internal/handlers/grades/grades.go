@@ -0,0 +1,171 @@
+package grades
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/grades"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/request"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// assessments, scores and the transcript built from them
+
+func NewAssessment(storage storage.GradeStorage, reader request.Reader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		courseId, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		var assessment types.Assessment
+
+		if err := reader.DecodeJson(w, r, &assessment); err != nil {
+			response.WriteJson(w, request.Status(err), response.GeneralError(err))
+			return
+		}
+
+		if err := validator.New().Struct(assessment); err != nil {
+			validateErrors := err.(validator.ValidationErrors)
+			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrors))
+			return
+		}
+
+		id, err := storage.CreateAssessment(courseId, assessment.Name, assessment.Weight)
+		if err != nil {
+			response.WriteJson(w, storageStatus(err), response.GeneralError(err))
+			return
+		}
+
+		slog.Info("assessment created", slog.String("id", fmt.Sprint(id)), slog.String("course", fmt.Sprint(courseId)))
+		response.WriteJson(w, http.StatusCreated, map[string]int64{"id": id})
+	}
+}
+
+func GetAssessments(storage storage.GradeStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		courseId, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		assessments, err := storage.GetAssessments(courseId)
+		if err != nil {
+			response.WriteJson(w, storageStatus(err), response.GeneralError(err))
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, assessments)
+	}
+}
+
+// RecordScore takes {"score": 87.5} for PUT /api/assessments/{id}/scores/{studentId}
+func RecordScore(store storage.GradeStorage, reader request.Reader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assessmentId, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		studentId, err := request.PathId(r, "studentId")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		var score types.Score
+
+		if err := reader.DecodeJson(w, r, &score); err != nil {
+			response.WriteJson(w, request.Status(err), response.GeneralError(err))
+			return
+		}
+
+		if err := validator.New().Struct(score); err != nil {
+			validateErrors := err.(validator.ValidationErrors)
+			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrors))
+			return
+		}
+
+		if err := store.RecordScore(assessmentId, studentId, *score.Score); err != nil {
+			response.WriteJson(w, storageStatus(err), response.GeneralError(err))
+			return
+		}
+
+		score.AssessmentId = assessmentId
+		score.StudentId = studentId
+
+		slog.Info("score recorded", slog.String("assessment", fmt.Sprint(assessmentId)), slog.String("student", fmt.Sprint(studentId)))
+		response.WriteJson(w, http.StatusOK, score)
+	}
+}
+
+// Transcript serves GET /api/students/{id}/transcript, as json by default and as a printable
+// page with ?format=html [or when the browser asks for text/html]
+func Transcript(students storage.Storage, store storage.GradeStorage, grading config.Grading) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		studentId, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		student, err := students.GetStudentById(studentId)
+		if err != nil {
+			response.WriteJson(w, storageStatus(err), response.GeneralError(err))
+			return
+		}
+
+		courses, err := store.GetStudentGrades(studentId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		transcript := grades.Build(student, courses, grading)
+
+		if !wantsHTML(r) {
+			response.WriteJson(w, http.StatusOK, transcript)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := transcriptPage.Execute(w, transcript); err != nil {
+			slog.Error("can't render transcript", slog.String("error", err.Error()))
+		}
+	}
+}
+
+func wantsHTML(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "html":
+		return true
+	case "json":
+		return false
+	}
+
+	//browsers list text/html first, api clients usually send */* or application/json
+	accept := r.Header.Get("Accept")
+	return strings.HasPrefix(accept, "text/html")
+}
+
+// a course, student or assessment that doesn't exist is a 404, grading a student who isn't enrolled
+// is a conflict and anything else went wrong on our side
+func storageStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrCourseNotFound), errors.Is(err, storage.ErrStudentNotFound), errors.Is(err, storage.ErrAssessmentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrNotEnrolled):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
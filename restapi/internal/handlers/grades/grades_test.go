@@ -0,0 +1,79 @@
+package grades_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/shivakr07/students-api/internal/apitest"
+)
+
+func TestRecordScore(t *testing.T) {
+	h := apitest.New(t)
+	h.POST("/api/courses").JSON(`{"code": "CS101", "title": "Computing", "term": "2026-fall", "credits": 3, "capacity": 10}`).Do().Status(http.StatusCreated)
+	h.POST("/api/students").JSON(`{"name": "Alan Turing", "email": "alan@gmail.com", "age": 25}`).Do().Status(http.StatusCreated)
+	h.POST("/api/courses/1/enrollments").JSON(`{"student_id": 1}`).Do().Status(http.StatusCreated)
+	h.POST("/api/courses/1/assessments").JSON(`{"name": "Final", "weight": 1}`).Do().Status(http.StatusCreated)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   string
+	}{
+		{"score", `{"score": 95.5}`, http.StatusOK, `{"assessment_id": 1, "student_id": 1, "score": 95.5}`},
+		{"zero is a score", `{"score": 0}`, http.StatusOK, `{"assessment_id": 1, "student_id": 1, "score": 0}`},
+		{"missing score", `{}`, http.StatusBadRequest, `{"status": "Error", "error": "field Score is required field"}`},
+		{"null score", `{"score": null}`, http.StatusBadRequest, `{"status": "Error", "error": "field Score is required field"}`},
+		{"above 100", `{"score": 101}`, http.StatusBadRequest, `{"status": "Error", "error": "field Score is invalid"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h.PUT("/api/assessments/1/scores/1").JSON(tt.body).Do().Status(tt.status).JSON(tt.want)
+		})
+	}
+}
+
+func TestStorageErrors(t *testing.T) {
+	h := apitest.New(t)
+	h.POST("/api/courses").JSON(`{"code": "CS101", "title": "Computing", "term": "2026-fall", "credits": 3, "capacity": 10}`).Do().Status(http.StatusCreated)
+	h.LoadStudents()
+	h.POST("/api/courses/1/enrollments").JSON(`{"student_id": 1}`).Do().Status(http.StatusCreated)
+	h.POST("/api/courses/1/assessments").JSON(`{"name": "Final", "weight": 1}`).Do().Status(http.StatusCreated)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		status  int
+		contain string
+	}{
+		{"assessment of no course", http.MethodPost, "/api/courses/9/assessments", `{"name": "Final", "weight": 1}`, http.StatusNotFound, "no course found with id 9"},
+		{"assessments of no course", http.MethodGet, "/api/courses/9/assessments", ``, http.StatusNotFound, "no course found with id 9"},
+		{"no such assessment", http.MethodPut, "/api/assessments/9/scores/1", `{"score": 50}`, http.StatusNotFound, "no assessment found with id 9"},
+		{"student not enrolled", http.MethodPut, "/api/assessments/1/scores/2", `{"score": 50}`, http.StatusConflict, "not enrolled"},
+		{"transcript of no student", http.MethodGet, "/api/students/9/transcript", ``, http.StatusNotFound, "no student found with id 9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := h.Request(tt.method, tt.path)
+			if tt.body != "" {
+				req.JSON(tt.body)
+			}
+			req.Do().Status(tt.status).Contains(tt.contain)
+		})
+	}
+
+	//a failing query is neither a missing row nor a conflict
+	if _, err := h.Storage.Db.Exec("DROP TABLE scores"); err != nil {
+		t.Fatal(err)
+	}
+	h.PUT("/api/assessments/1/scores/1").JSON(`{"score": 50}`).Do().Status(http.StatusInternalServerError)
+	if _, err := h.Storage.Db.Exec("DROP TABLE assessments"); err != nil {
+		t.Fatal(err)
+	}
+	h.GET("/api/courses/1/assessments").Do().Status(http.StatusInternalServerError)
+	h.POST("/api/courses/1/assessments").JSON(`{"name": "Final", "weight": 1}`).Do().Status(http.StatusInternalServerError)
+}
@@ -0,0 +1,20 @@
+package grades
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+)
+
+//go:embed transcript.html
+var transcriptHTML string
+
+// the printable transcript, html/template escapes names and titles for us
+var transcriptPage = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"num": func(value *float64) string {
+		if value == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f", *value)
+	},
+}).Parse(transcriptHTML))
@@ -0,0 +1,338 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	v1 "github.com/shivakr07/students-api/internal/api/v1"
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/handlers/student"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/request"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// page size used when the client doesn't ask for one, and the biggest page we hand out
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// body of a graphql request, same shape every graphql client sends
+type gqlRequest struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables"`
+	OperationName string         `json:"operationName"`
+	Extensions    map[string]any `json:"extensions"` //sent by some clients [persisted queries], not used
+}
+
+// studentPage is what the students query returns, HasMore tells the client to ask for the next offset
+type studentPage struct {
+	Items   []types.Student `json:"items"`
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
+	HasMore bool            `json:"hasMore"`
+}
+
+var studentType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Student",
+	//graphql-go reads most fields through the json tags of types.Student, the rest need a resolver
+	Fields: graphql.Fields{
+		"id":    &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"email": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"age": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(types.Student).CurrentAge(time.Now()), nil
+			},
+		},
+		"firstName": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(types.Student).FirstName, nil
+			},
+		},
+		"lastName": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(types.Student).LastName, nil
+			},
+		},
+		"dateOfBirth": &graphql.Field{
+			Type:        graphql.String,
+			Description: "2006-01-02, null for students created through v1",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				if dob := p.Source.(types.Student).DateOfBirth; dob != nil {
+					return dob.String(), nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var studentPageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "StudentPage",
+	Fields: graphql.Fields{
+		"items":   &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(studentType)))},
+		"limit":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"offset":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"hasMore": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+	},
+})
+
+var studentFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "StudentFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":   &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "substring match on name"},
+		"email":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"minAge": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"maxAge": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+	},
+})
+
+var studentInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "StudentInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"email": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"age":   &graphql.InputObjectFieldConfig{Type: graphql.Int},
+	},
+})
+
+// NewSchema builds the graphql schema on top of the Storage interface
+// so whichever backend main wires in is the one graphql talks to
+func NewSchema(storage storage.Storage) (graphql.Schema, error) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"student": &graphql.Field{
+				Type: studentType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := idArg(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					return storage.GetStudentById(id)
+				},
+			},
+			"students": &graphql.Field{
+				Type: graphql.NewNonNull(studentPageType),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: studentFilterType},
+					"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit},
+					"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return listStudents(storage, p.Args)
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createStudent": &graphql.Field{
+				Type: studentType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(studentInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					s, err := studentInput(p.Args)
+					if err != nil {
+						return nil, err
+					}
+
+					id, err := storage.CreateStudent(s)
+					if err != nil {
+						return nil, err
+					}
+					slog.Info("user created successfully", slog.String("userId", fmt.Sprint(id)))
+
+					return storage.GetStudentById(id)
+				},
+			},
+			"updateStudent": &graphql.Field{
+				Type: studentType,
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(studentInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := idArg(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					s, err := studentInput(p.Args)
+					if err != nil {
+						return nil, err
+					}
+
+					//the input is v1 shaped, so the update follows the v1 rules [a date of birth survives an unchanged age]
+					current, err := storage.GetStudentById(id)
+					if err != nil {
+						return nil, err
+					}
+					updated := v1.Apply(v1.Student{Id: id, Name: s.Name, Email: s.Email, Age: s.Age}, current)
+					if err := storage.UpdateStudent(id, updated); err != nil {
+						return nil, err
+					}
+
+					return storage.GetStudentById(id)
+				},
+			},
+			"deleteStudent": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := idArg(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					if err := storage.DeleteStudent(id); err != nil {
+						return nil, err
+					}
+					return true, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+// New returns the /graphql handler, it accepts POST with a json body and GET with ?query=
+func New(storage storage.Storage, cfg config.GraphQL, reader request.Reader) (http.HandlerFunc, error) {
+	schema, err := NewSchema(storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req gqlRequest
+
+		if r.Method == http.MethodGet {
+			req.Query = r.URL.Query().Get("query")
+			req.OperationName = r.URL.Query().Get("operationName")
+			if vars := r.URL.Query().Get("variables"); vars != "" {
+				if err := request.UnmarshalStrict([]byte(vars), &req.Variables); err != nil {
+					response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+					return
+				}
+			}
+		} else {
+			if err := reader.DecodeJson(w, r, &req); err != nil {
+				response.WriteJson(w, request.Status(err), response.GeneralError(err))
+				return
+			}
+		}
+
+		if req.Query == "" {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(i18n.Errorf("graph.query_required", "query is required")))
+			return
+		}
+
+		//reject expensive queries before graphql-go starts resolving anything
+		if err := checkLimits(req.Query, req.Variables, cfg); err != nil {
+			slog.Info("graphql query rejected", slog.String("error", err.Error()))
+			response.WriteJson(w, http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+			return
+		}
+
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  req.Query,
+			VariableValues: req.Variables,
+			OperationName:  req.OperationName,
+			Context:        r.Context(),
+		})
+
+		//graphql reports resolver errors inside the body, the status stays 200 like every graphql server
+		response.WriteJson(w, http.StatusOK, result)
+	}, nil
+}
+
+func listStudents(storage storage.Storage, args map[string]any) (studentPage, error) {
+	limit, _ := args["limit"].(int)
+	offset, _ := args["offset"].(int)
+
+	if limit <= 0 || limit > maxLimit {
+		return studentPage{}, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+	if offset < 0 {
+		return studentPage{}, fmt.Errorf("offset can't be negative")
+	}
+
+	query := types.StudentQuery{Offset: offset}
+	if filter, ok := args["filter"].(map[string]any); ok {
+		query.Name, _ = filter["name"].(string)
+		query.Email, _ = filter["email"].(string)
+		query.MinAge, _ = filter["minAge"].(int)
+		query.MaxAge, _ = filter["maxAge"].(int)
+	}
+
+	//ask for one extra row, if it comes back there is another page
+	query.Limit = limit + 1
+	students, err := storage.ListStudents(query)
+	if err != nil {
+		return studentPage{}, err
+	}
+
+	page := studentPage{Limit: limit, Offset: offset, Items: students}
+	if len(students) > limit {
+		page.Items = students[:limit]
+		page.HasMore = true
+	}
+
+	return page, nil
+}
+
+func idArg(args map[string]any) (int64, error) {
+	raw, _ := args["id"].(string)
+
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %q", raw)
+	}
+
+	return id, nil
+}
+
+// studentInput turns the input argument into a types.Student and runs the same validation as POST /api/students
+func studentInput(args map[string]any) (types.Student, error) {
+	var s types.Student
+
+	input, _ := args["input"].(map[string]any)
+	s.Name, _ = input["name"].(string)
+	s.Email, _ = input["email"].(string)
+	s.Age, _ = input["age"].(int)
+
+	if err := student.Validate(s); err != nil {
+		var validateErrors validator.ValidationErrors
+		if errors.As(err, &validateErrors) {
+			return s, errors.New(response.ValidationError(validateErrors).Error)
+		}
+		return s, err
+	}
+
+	return s, nil
+}
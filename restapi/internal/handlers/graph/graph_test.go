@@ -0,0 +1,41 @@
+package graph_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/shivakr07/students-api/internal/apitest"
+)
+
+func TestLimits(t *testing.T) {
+	h := apitest.New(t)
+
+	//2000000000^3 doesn't fit an int, the cost used to wrap around and come out under the maximum
+	h.POST("/graphql").JSON(map[string]any{
+		"query": `{ a(limit: 2000000000) { b(limit: 2000000000) { c(limit: 2000000000) { d } } } }`,
+	}).Do().
+		Status(http.StatusBadRequest).
+		Contains("query complexity 1010101 exceeds the maximum of 1000")
+
+	h.POST("/graphql").JSON(map[string]any{
+		"query":     `query($n: Int) { a(limit: $n) { b(limit: $n) { c(limit: $n) { d } } } }`,
+		"variables": map[string]any{"n": 1e300},
+	}).Do().
+		Status(http.StatusBadRequest).
+		Contains("query complexity 1010101 exceeds the maximum of 1000")
+}
+
+func TestNameFilter(t *testing.T) {
+	h := apitest.New(t)
+	h.LoadStudents()
+
+	//% and _ are matched as themselves, not as wildcards
+	for _, name := range []string{"%", "_"} {
+		h.POST("/graphql").JSON(map[string]any{
+			"query":     `query($name: String) { students(filter: {name: $name}) { items { id } } }`,
+			"variables": map[string]any{"name": name},
+		}).Do().
+			Status(http.StatusOK).
+			Field("data.students.items", []any{})
+	}
+}
@@ -0,0 +1,147 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/shivakr07/students-api/internal/config"
+)
+
+// checkLimits walks the parsed query and rejects it when it nests too deep or would
+// resolve too many fields, every field costs 1 and fields taking a limit multiply
+// the cost of their children by that limit
+func checkLimits(query string, variables map[string]any, cfg config.GraphQL) error {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		//let graphql.Do report syntax errors in its usual format
+		return nil
+	}
+
+	w := walker{
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: variables,
+	}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			w.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		depth, cost := w.selectionSet(op.SelectionSet, 1, map[string]bool{})
+		if cfg.MaxDepth > 0 && depth > cfg.MaxDepth {
+			return fmt.Errorf("query depth %d exceeds the maximum of %d", depth, cfg.MaxDepth)
+		}
+		if cfg.MaxComplexity > 0 && cost > cfg.MaxComplexity {
+			return fmt.Errorf("query complexity %d exceeds the maximum of %d", cost, cfg.MaxComplexity)
+		}
+	}
+
+	return nil
+}
+
+type walker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+// selectionSet returns the depth and cost of a selection set found at the given depth
+// seen keeps track of the fragments on the current path so a cyclic spread can't loop forever
+func (w walker) selectionSet(set *ast.SelectionSet, depth int, seen map[string]bool) (int, int) {
+	if set == nil {
+		return depth - 1, 0
+	}
+
+	maxDepth, cost := depth, 0
+	for _, selection := range set.Selections {
+		var d, c int
+
+		switch sel := selection.(type) {
+		case *ast.Field:
+			childDepth, childCost := w.selectionSet(sel.SelectionSet, depth+1, seen)
+			d, c = childDepth, addCost(1, mulCost(childCost, w.multiplier(sel)))
+
+		case *ast.InlineFragment:
+			d, c = w.selectionSet(sel.SelectionSet, depth, seen)
+
+		case *ast.FragmentSpread:
+			name := sel.Name.Value
+			fragment, ok := w.fragments[name]
+			if !ok || seen[name] {
+				continue
+			}
+
+			seen[name] = true
+			d, c = w.selectionSet(fragment.SelectionSet, depth, seen)
+			delete(seen, name)
+		}
+
+		maxDepth = max(maxDepth, d)
+		cost = addCost(cost, c)
+	}
+
+	return maxDepth, cost
+}
+
+// multiplier is how many times the children of a field will be resolved
+// list fields are paged so we use the limit the client asked for, or the default page size
+// a limit past maxLimit is refused by the resolver anyway, so it never counts for more than that
+func (w walker) multiplier(field *ast.Field) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil {
+				return pageSize(n)
+			}
+			//too big for an int, still a page of at most maxLimit
+			return maxLimit
+		case *ast.Variable:
+			switch n := w.variables[value.Name.Value].(type) {
+			case float64:
+				return pageSize(int(min(max(n, 1), maxLimit)))
+			case int:
+				return pageSize(n)
+			}
+		}
+
+		return defaultLimit
+	}
+
+	if field.Name.Value == "students" {
+		return defaultLimit
+	}
+
+	return 1
+}
+
+func pageSize(n int) int {
+	return min(max(n, 1), maxLimit)
+}
+
+// addCost and mulCost stop at math.MaxInt instead of wrapping around, a huge query must not come
+// out cheap [or negative] and slip under max_complexity
+func addCost(a int, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+func mulCost(a int, b int) int {
+	if a != 0 && b > math.MaxInt/a {
+		return math.MaxInt
+	}
+	return a * b
+}
@@ -0,0 +1,34 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// a health check shouldn't hang as long as the load balancer polling it
+const pingTimeout = 2 * time.Second
+
+// Pinger is what the health check needs from the database, *sql.DB has it
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// New answers 200 while the database answers, 503 otherwise so the instance is taken out of rotation
+func New(db Pinger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), pingTimeout)
+		defer cancel()
+
+		if err := db.PingContext(ctx); err != nil {
+			slog.Error("health check failed", slog.String("error", err.Error()))
+			response.WriteJson(w, http.StatusServiceUnavailable, response.GeneralError(err))
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, map[string]string{"status": response.StatusOK})
+	}
+}
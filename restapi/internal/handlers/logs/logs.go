@@ -0,0 +1,48 @@
+package logs
+
+import (
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shivakr07/students-api/internal/logging"
+	"github.com/shivakr07/students-api/internal/utils/request"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// admin api for the log level, turning on debug logs for a while doesn't need a restart
+// the level set here lasts until the next restart or a SIGHUP that changes log.level
+
+type level struct {
+	Level string `json:"level" validate:"required"`
+}
+
+func GetLevel(logger *logging.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJson(w, http.StatusOK, level{Level: logger.Level()})
+	}
+}
+
+// SetLevel takes {"level": "debug"}, same values as log.level in the config
+func SetLevel(logger *logging.Logger, reader request.Reader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body level
+
+		if err := reader.DecodeJson(w, r, &body); err != nil {
+			response.WriteJson(w, request.Status(err), response.GeneralError(err))
+			return
+		}
+
+		if err := validator.New().Struct(body); err != nil {
+			validateErrors := err.(validator.ValidationErrors)
+			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrors))
+			return
+		}
+
+		if err := logger.SetLevel(body.Level); err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, level{Level: logger.Level()})
+	}
+}
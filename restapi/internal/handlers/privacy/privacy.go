@@ -0,0 +1,207 @@
+package privacy
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shivakr07/students-api/internal/api"
+	"github.com/shivakr07/students-api/internal/blob"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/photo"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/request"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// data subject requests: a student can get a copy of everything held about them [export] and have
+// it removed [erase], an erased student answers 410 with the tombstone on both routes
+
+// document is the export, the student is in the json form of the api version like everywhere else
+type document struct {
+	ExportedAt  time.Time            `json:"exported_at"`
+	Student     any                  `json:"student"`
+	Enrollments []types.Enrollment   `json:"enrollments"`
+	Scores      []types.StudentScore `json:"scores"`
+	Photo       *types.Photo         `json:"photo"`
+	PhotoFile   string               `json:"photo_file,omitempty"` //name of the photo in the zip
+	Events      []types.Event        `json:"events"`
+	Disclosures []types.Disclosure   `json:"disclosures"`
+}
+
+var errFormat = i18n.New("privacy.format", "format must be json or zip")
+
+// file extensions of the photo types an upload can have
+var photoExtensions = map[string]string{"image/jpeg": ".jpg", "image/png": ".png", "image/gif": ".gif"}
+
+// Export serves GET /api/students/{id}/export, json by default and ?format=zip for an archive with
+// the json and the photo file itself
+func Export(privacy storage.PrivacyStorage, blobs blob.Store, codec api.StudentCodec) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "zip" {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(errFormat))
+			return
+		}
+		slog.Info("exporting a student", slog.String("id", fmt.Sprint(id)), slog.String("format", format))
+
+		export, err := privacy.ExportStudent(id)
+		if err != nil {
+			writeError(w, privacy, id, err)
+			return
+		}
+
+		doc := document{
+			ExportedAt:  time.Now().UTC(),
+			Student:     codec.Encode(export.Student),
+			Enrollments: export.Enrollments,
+			Scores:      export.Scores,
+			Photo:       export.Photo,
+			Events:      export.Events,
+			Disclosures: export.Disclosures,
+		}
+		name := fmt.Sprintf("student-%d-export", id)
+
+		if format != "zip" {
+			w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.json"`)
+			response.WriteJson(w, http.StatusOK, doc)
+			return
+		}
+
+		//the photo is read before anything is written, a missing blob is still an error we can answer
+		var photoFile io.ReadCloser
+		if export.Photo != nil {
+			file, _, err := blobs.Get(r.Context(), photo.Key(id, export.Photo.ETag))
+			switch {
+			case err == nil:
+				photoFile = file
+				defer file.Close()
+				doc.PhotoFile = "photo" + photoExtensions[export.Photo.ContentType]
+			case !errors.Is(err, blob.ErrNotFound):
+				response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.zip"`)
+		w.WriteHeader(http.StatusOK)
+
+		//from here on the status is sent, a failure can only be logged [the client gets a broken zip]
+		if err := writeZip(w, doc, photoFile); err != nil {
+			slog.Error("can't write export", slog.String("id", fmt.Sprint(id)), slog.String("error", err.Error()))
+		}
+	}
+}
+
+func writeZip(w io.Writer, doc document, photoFile io.Reader) error {
+	archive := zip.NewWriter(w)
+
+	f, err := archive.Create("student.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+
+	if photoFile != nil {
+		f, err := archive.Create(doc.PhotoFile)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, photoFile); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// eraseRequest is the optional body of an erasure, the reason is kept in the tombstone so it must
+// not say anything about the student [a ticket number, "request by email"...]
+type eraseRequest struct {
+	Reason string `json:"reason" validate:"max=200"`
+}
+
+// Erase serves POST /api/students/{id}/erase, it answers with the tombstone
+// it goes through the decorators [students], so the cache and the photo blobs are cleaned up too
+func Erase(students storage.Storage, reader request.Reader) (http.HandlerFunc, error) {
+	privacy, ok := students.(storage.PrivacyStorage)
+	if !ok {
+		return nil, fmt.Errorf("privacy: storage %T doesn't support erasure", students)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		var req eraseRequest
+		body, err := reader.ReadBody(w, r)
+		switch {
+		case errors.Is(err, request.ErrEmptyBody):
+			//no body, no reason
+		case err != nil:
+			response.WriteJson(w, request.Status(err), response.GeneralError(err))
+			return
+		default:
+			if err := request.UnmarshalStrict(body, &req); err != nil {
+				response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+				return
+			}
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			validateErrors := err.(validator.ValidationErrors)
+			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrors))
+			return
+		}
+
+		erasure, err := privacy.EraseStudent(id, req.Reason)
+		if err != nil {
+			writeError(w, privacy, id, err)
+			return
+		}
+
+		slog.Info("student erased", slog.String("id", fmt.Sprint(id)), slog.String("digest", erasure.Digest))
+		response.WriteJson(w, http.StatusOK, erasure)
+	}, nil
+}
+
+// writeError answers an erased student with 410 and its tombstone, a student that doesn't exist with
+// 404 [same as the other student routes] and anything else with 500
+func writeError(w http.ResponseWriter, privacy storage.PrivacyStorage, id int64, err error) {
+	if !errors.Is(err, storage.ErrErased) {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrStudentNotFound) {
+			status = http.StatusNotFound
+		}
+		response.WriteJson(w, status, response.GeneralError(err))
+		return
+	}
+
+	erasure, err := privacy.GetErasure(id)
+	if err != nil {
+		response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+		return
+	}
+	response.WriteJson(w, http.StatusGone, erasure)
+}
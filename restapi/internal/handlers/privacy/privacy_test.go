@@ -0,0 +1,153 @@
+package privacy_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/shivakr07/students-api/internal/apitest"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// newHarness has two students, the first one enrolled in a course with one score
+func newHarness(t *testing.T) (*apitest.Harness, []int64) {
+	h := apitest.New(t)
+	ids := h.LoadStudents()
+
+	course, err := h.Storage.CreateCourse("CS101", "Computing", "2026-fall", 3, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Storage.Enroll(course, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	assessment, err := h.Storage.CreateAssessment(course, "Midterm", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Storage.RecordScore(assessment, ids[0], 91); err != nil {
+		t.Fatal(err)
+	}
+
+	return h, ids
+}
+
+func TestExport(t *testing.T) {
+	h, ids := newHarness(t)
+
+	h.GET("/api/students/1/export").Do().
+		Status(http.StatusOK).
+		Header("Content-Disposition", `attachment; filename="student-1-export.json"`).
+		Field("student", map[string]any{"id": ids[0], "name": "Alan Turing", "email": "alan@gmail.com", "age": 25}).
+		Field("enrollments.0.course.code", "CS101").
+		Field("scores.0.assessment", "Midterm").
+		Field("scores.0.score", 91).
+		Field("photo", nil).
+		Field("events.0.type", types.EventStudentCreated)
+
+	//the student is in the form of the api version
+	h.GET("/api/v2/students/2/export").Do().
+		Status(http.StatusOK).
+		Field("student.first_name", "Ada").
+		Field("enrollments", []any{})
+
+	h.GET("/api/students/99/export").Do().
+		Status(http.StatusNotFound).
+		JSON(`{"status": "Error", "error": "no student found with id 99"}`)
+
+	h.GET("/api/students/1/export?format=xml").Do().
+		Status(http.StatusBadRequest).
+		JSON(`{"status": "Error", "error": "format must be json or zip"}`)
+	h.GET("/api/students/1/export?format=xml").Header("Accept-Language", "fr").Do().
+		Status(http.StatusBadRequest).
+		JSON(`{"status": "Error", "error": "le format doit être json ou zip"}`)
+
+	//a read that fails isn't a missing student
+	if _, err := h.Storage.Db.Exec("DROP TABLE scores"); err != nil {
+		t.Fatal(err)
+	}
+	h.GET("/api/students/1/export").Do().Status(http.StatusInternalServerError)
+}
+
+func TestExportZip(t *testing.T) {
+	h, _ := newHarness(t)
+
+	res := h.GET("/api/students/1/export?format=zip").Do().
+		Status(http.StatusOK).
+		Header("Content-Type", "application/zip")
+
+	archive, err := zip.NewReader(bytes.NewReader(res.Body()), int64(len(res.Body())))
+	if err != nil {
+		t.Fatalf("not a zip: %v", err)
+	}
+	if len(archive.File) != 1 || archive.File[0].Name != "student.json" {
+		t.Fatalf("want only student.json without a photo, got %d files", len(archive.File))
+	}
+
+	f, err := archive.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	if !bytes.Contains(data, []byte(`"name": "Alan Turing"`)) {
+		t.Errorf("student.json doesn't have the student:\n%s", data)
+	}
+}
+
+func TestErase(t *testing.T) {
+	h, ids := newHarness(t)
+
+	var erasure types.Erasure
+	h.POST("/api/students/1/erase").JSON(`{"reason": "ticket 4711"}`).Do().
+		Status(http.StatusOK).
+		Field("student_id", ids[0]).
+		Field("reason", "ticket 4711").
+		Field("removed", map[string]any{"students": 1, "enrollments": 1, "scores": 1, "student_photos": 0, "outbox": 1}).
+		Decode(&erasure)
+	if len(erasure.Digest) != 64 {
+		t.Errorf("digest %q isn't a sha256", erasure.Digest)
+	}
+
+	//gone everywhere, and the tombstone is what both privacy routes answer from now on
+	h.GET("/api/students/1/courses").Do().Status(http.StatusNotFound)
+	h.GET("/api/students/1/export").Do().Status(http.StatusGone).Field("digest", erasure.Digest)
+	h.POST("/api/students/1/erase").Do().Status(http.StatusGone).Field("id", erasure.Id)
+
+	//the events stay for the deliveries that point at them, with nothing but the id left
+	events, err := h.Storage.EventsAfter(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen []string
+	for _, event := range events {
+		if event.StudentId != ids[0] {
+			continue
+		}
+		seen = append(seen, event.Type)
+		if bytes.Contains(event.Payload, []byte("Alan")) {
+			t.Errorf("event %d still has the student: %s", event.Id, event.Payload)
+		}
+	}
+	if len(seen) != 2 || seen[1] != types.EventStudentErased {
+		t.Errorf("events of the erased student are %v, want created and erased", seen)
+	}
+
+	//the tombstones are chained, the second one depends on the first
+	h.POST("/api/students/2/erase").Do().Status(http.StatusOK)
+	second, err := h.Storage.GetErasure(ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Digest == erasure.Digest || second.Reason != "" {
+		t.Errorf("second tombstone %+v", second)
+	}
+
+	h.POST("/api/students/99/erase").Do().
+		Status(http.StatusNotFound).
+		JSON(`{"status": "Error", "error": "no student found with id 99"}`)
+	h.POST("/api/students/1/erase").JSON(`{"reason": "x", "extra": 1}`).Do().
+		Status(http.StatusBadRequest)
+}
@@ -0,0 +1,124 @@
+package student
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shivakr07/students-api/internal/api"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/patch"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/request"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// Patch applies a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), picked by Content-Type
+// the read, the patch and the write happen in one transaction when the storage supports it, so a
+// "test" operation really guards the write that follows it
+// the patch works on the json form of the api version [codec], so a v1 client patches "name" and a
+// v2 client patches "first_name"
+func Patch(store storage.Storage, codec api.StudentCodec, reader request.Reader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		slog.Info("patching a student", slog.String("id", fmt.Sprint(id)))
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		var apply func(doc []byte, patch []byte) ([]byte, error)
+		switch mediaType {
+		case patch.MergePatchType:
+			apply = patch.Merge
+		case patch.JSONPatchType:
+			apply = patch.Apply
+		default:
+			w.Header().Set("Accept-Patch", patch.MergePatchType+", "+patch.JSONPatchType)
+			response.WriteJson(w, http.StatusUnsupportedMediaType, response.GeneralError(i18n.Errorf("patch.content_type", "use %s or %s", patch.MergePatchType, patch.JSONPatchType)))
+			return
+		}
+
+		//the patch itself is applied to the generic document, so only the size is checked here
+		body, err := reader.ReadBody(w, r)
+		if err != nil {
+			response.WriteJson(w, request.Status(err), response.GeneralError(err))
+			return
+		}
+
+		var patched types.Student
+		err = inTx(store, func(s storage.Storage) error {
+			current, err := s.GetStudentById(id)
+			if err != nil {
+				return notFound{err}
+			}
+
+			doc, err := json.Marshal(codec.Encode(current))
+			if err != nil {
+				return err
+			}
+
+			out, err := apply(doc, body)
+			if err != nil {
+				return err
+			}
+
+			//fields a student doesn't have are a typo on the client side, not something to drop silently
+			//and the codec validates with the same rules as New, a patch can't produce a student New would refuse
+			patched, err = codec.Decode(out, current)
+			var validateErrors validator.ValidationErrors
+			if errors.As(err, &validateErrors) {
+				return err
+			}
+			if err != nil {
+				return fmt.Errorf("%w: %w", patch.ErrInvalid, err)
+			}
+			if patched.Id != id {
+				return fmt.Errorf("%w: %w", patch.ErrInvalid, i18n.Errorf("patch.id_changed", "id can't be changed"))
+			}
+
+			return s.UpdateStudent(id, patched)
+		})
+
+		var validateErrors validator.ValidationErrors
+		var missing notFound
+		switch {
+		case err == nil:
+			response.WriteJson(w, http.StatusOK, codec.Encode(patched))
+		case errors.As(err, &validateErrors):
+			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrors))
+		case errors.As(err, &missing):
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(err))
+		case errors.Is(err, patch.ErrTestFailed), errors.Is(err, storage.ErrEmailTaken):
+			response.WriteJson(w, http.StatusConflict, response.GeneralError(err))
+		case errors.Is(err, patch.ErrPath):
+			response.WriteJson(w, http.StatusUnprocessableEntity, response.GeneralError(err))
+		case errors.Is(err, patch.ErrInvalid):
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+		default:
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+		}
+	}
+}
+
+type notFound struct{ error }
+
+func (e notFound) Unwrap() error { return e.error }
+
+// inTx runs fn in a transaction when the storage can do that, and directly on it otherwise
+func inTx(store storage.Storage, fn func(s storage.Storage) error) error {
+	transactor, ok := store.(storage.Transactor)
+	if !ok {
+		return fn(store)
+	}
+
+	return transactor.InTx(func(tx storage.TxStorage) error {
+		return fn(tx)
+	})
+}
@@ -0,0 +1,229 @@
+package student
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/blob"
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/photo"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/request"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// photos are personal data: browsers may keep them, shared caches may not, and the etag makes
+// revalidation after the max-age cheap
+const photoCacheControl = "private, max-age=300"
+
+// UploadPhoto takes a multipart form with the image in the "photo" field
+func UploadPhoto(students storage.Storage, store storage.PhotoStorage, blobs blob.Store, cfg config.Photos) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		slog.Info("uploading a photo", slog.String("id", fmt.Sprint(id)))
+
+		//before reading the upload, a photo of nobody isn't worth the work
+		if _, err := students.GetStudentById(id); err != nil {
+			response.WriteJson(w, getStatus(err), response.GeneralError(err))
+			return
+		}
+
+		//the multipart framing needs a bit more than the file itself
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.MaxBytes+64<<10)
+
+		data, err := readPhotoPart(r, cfg.MaxBytes)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.Is(err, errPhotoTooLarge) || errors.As(err, &tooLarge) {
+				response.WriteJson(w, http.StatusRequestEntityTooLarge, response.GeneralError(i18n.Errorf("photo.too_large", "photo is larger than %d bytes", cfg.MaxBytes)))
+				return
+			}
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		processed, err := photo.Process(data, cfg.ThumbnailSize)
+		if err != nil {
+			response.WriteJson(w, http.StatusUnsupportedMediaType, response.GeneralError(err))
+			return
+		}
+
+		meta := types.Photo{
+			StudentId:   id,
+			ContentType: processed.ContentType,
+			Size:        int64(len(data)),
+			Width:       processed.Width,
+			Height:      processed.Height,
+			ETag:        processed.ETag,
+			UpdatedAt:   time.Now().UTC().Truncate(time.Second),
+		}
+
+		//blobs first, under keys of their own [the etag is in them]: the files of the current photo are
+		//only removed once the new row is in, so whatever fails there is never a row without a file
+		if err := blobs.Put(r.Context(), photo.Key(id, meta.ETag), bytes.NewReader(data)); err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if err := blobs.Put(r.Context(), photo.ThumbKey(id, meta.ETag), bytes.NewReader(processed.Thumbnail)); err != nil {
+			discardUpload(r.Context(), store, blobs, meta)
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		replaced, err := store.SetStudentPhoto(meta)
+		if err != nil {
+			discardUpload(r.Context(), store, blobs, meta)
+			response.WriteJson(w, getStatus(err), response.GeneralError(err))
+			return
+		}
+		if replaced.ETag != "" && replaced.ETag != meta.ETag {
+			deleteBlobs(r.Context(), blobs, photo.Keys(replaced))
+		}
+
+		slog.Info("photo stored", slog.String("id", fmt.Sprint(id)), slog.String("type", meta.ContentType))
+		response.WriteJson(w, http.StatusOK, meta)
+	}
+}
+
+var errPhotoTooLarge = errors.New("photo too large")
+
+// discardUpload removes the files of an upload whose row didn't make it in, unless they may be the files
+// of the current photo [the same image has the same etag, so the same keys]
+func discardUpload(ctx context.Context, store storage.PhotoStorage, blobs blob.Store, meta types.Photo) {
+	current, err := store.GetStudentPhoto(meta.StudentId)
+	if err == nil && current.ETag == meta.ETag {
+		return
+	}
+	if err != nil && !errors.Is(err, storage.ErrPhotoNotFound) {
+		return //can't tell, a file too many is better than a row without one
+	}
+
+	deleteBlobs(ctx, blobs, photo.Keys(meta))
+}
+
+// a blob we fail to remove is only wasted disk, so it is logged and the request goes on
+func deleteBlobs(ctx context.Context, blobs blob.Store, keys []string) {
+	for _, key := range keys {
+		if err := blobs.Delete(ctx, key); err != nil {
+			slog.Error("can't delete photo blob", slog.String("key", key), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// readPhotoPart streams the form instead of ParseMultipartForm so nothing ends up in temp files
+func readPhotoPart(r *http.Request, maxBytes int64) ([]byte, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, i18n.Errorf("photo.multipart", "expected a multipart/form-data body")
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, i18n.Errorf("photo.no_field", "no photo field in the form")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if part.FormName() != "photo" {
+			part.Close()
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(part, maxBytes+1))
+		part.Close()
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > maxBytes {
+			return nil, errPhotoTooLarge
+		}
+		if len(data) == 0 {
+			return nil, i18n.Errorf("photo.empty", "empty photo")
+		}
+
+		return data, nil
+	}
+}
+
+// GetPhoto serves the photo, ?size=thumb serves the thumbnail
+// conditional requests [If-None-Match, If-Modified-Since] and ranges are handled by http.ServeContent
+func GetPhoto(store storage.PhotoStorage, blobs blob.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		meta, err := store.GetStudentPhoto(id)
+		if err != nil {
+			response.WriteJson(w, photoStatus(err), response.GeneralError(err))
+			return
+		}
+
+		key, contentType, etag := photo.Key(id, meta.ETag), meta.ContentType, meta.ETag
+		if r.URL.Query().Get("size") == "thumb" {
+			key, contentType, etag = photo.ThumbKey(id, meta.ETag), "image/jpeg", meta.ETag+"-thumb"
+		}
+
+		file, _, err := blobs.Get(r.Context(), key)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, blob.ErrNotFound) {
+				status = http.StatusNotFound
+			}
+			response.WriteJson(w, status, response.GeneralError(err))
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("ETag", `"`+etag+`"`)
+		w.Header().Set("Cache-Control", photoCacheControl)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeContent(w, r, "", meta.UpdatedAt, file)
+	}
+}
+
+func DeletePhoto(store storage.PhotoStorage, blobs blob.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		deleted, err := store.DeleteStudentPhoto(id)
+		if err != nil {
+			response.WriteJson(w, photoStatus(err), response.GeneralError(err))
+			return
+		}
+
+		deleteBlobs(r.Context(), blobs, photo.Keys(deleted))
+
+		slog.Info("photo deleted", slog.String("id", fmt.Sprint(id)))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// photoStatus is 404 for a photo that isn't there and 500 for a database that failed
+func photoStatus(err error) int {
+	if errors.Is(err, storage.ErrPhotoNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,116 @@
+package student_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/shivakr07/students-api/internal/apitest"
+)
+
+// photoForm is a multipart body with a small png of one colour in the "photo" field
+func photoForm(t *testing.T, c color.Color) ([]byte, string) {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 16; y++ {
+			img.Set(x, y, c)
+		}
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("photo", "photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(part, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return body.Bytes(), form.FormDataContentType()
+}
+
+func upload(h *apitest.Harness, path string, body []byte, contentType string) *apitest.Response {
+	return h.PUT(path).JSON(body).Header("Content-Type", contentType).Do()
+}
+
+// blobFiles are the files in the blob dir, the photo and the thumbnail of every stored photo
+func blobFiles(t *testing.T, h *apitest.Harness) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(h.Config.Photos.Dir, "students", "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestUploadPhoto(t *testing.T) {
+	h := apitest.New(t)
+	red, redType := photoForm(t, color.RGBA{R: 0xff, A: 0xff})
+	blue, blueType := photoForm(t, color.RGBA{B: 0xff, A: 0xff})
+
+	//nothing is written for a student that isn't there
+	upload(h, "/api/students/9/photo", red, redType).
+		Status(http.StatusNotFound).
+		JSON(`{"status": "Error", "error": "no student found with id 9"}`)
+	if files := blobFiles(t, h); len(files) != 0 {
+		t.Fatalf("files for a missing student: %v", files)
+	}
+
+	h.LoadStudents()
+	var first struct{ ETag string }
+	upload(h, "/api/students/1/photo", red, redType).Status(http.StatusOK).Decode(&first)
+	h.GET("/api/students/1/photo").Do().Status(http.StatusOK).Header("ETag", `"`+first.ETag+`"`)
+
+	//a new photo replaces the old one, files and all
+	var second struct{ ETag string }
+	upload(h, "/api/students/1/photo", blue, blueType).Status(http.StatusOK).Decode(&second)
+	h.GET("/api/students/1/photo").Do().Status(http.StatusOK).Header("ETag", `"`+second.ETag+`"`)
+	if files := blobFiles(t, h); len(files) != 2 {
+		t.Errorf("after replacing the photo: %v", files)
+	}
+
+	h.DELETE("/api/students/1/photo").Do().Status(http.StatusNoContent)
+	h.GET("/api/students/1/photo").Do().Status(http.StatusNotFound)
+	h.DELETE("/api/students/1/photo").Do().Status(http.StatusNotFound)
+	if files := blobFiles(t, h); len(files) != 0 {
+		t.Errorf("after deleting the photo: %v", files)
+	}
+}
+
+func TestUploadPhotoFailedWrite(t *testing.T) {
+	h := apitest.New(t)
+	h.LoadStudents()
+	red, redType := photoForm(t, color.RGBA{R: 0xff, A: 0xff})
+	blue, blueType := photoForm(t, color.RGBA{B: 0xff, A: 0xff})
+
+	var current struct{ ETag string }
+	upload(h, "/api/students/1/photo", red, redType).Status(http.StatusOK).Decode(&current)
+	before := blobFiles(t, h)
+
+	//the row can't be replaced [a failing database, as far as the handler can tell]
+	if _, err := h.Storage.Db.Exec(`CREATE TRIGGER no_photo_update BEFORE UPDATE ON student_photos
+		BEGIN SELECT RAISE(ABORT, 'disk I/O error'); END`); err != nil {
+		t.Fatal(err)
+	}
+
+	upload(h, "/api/students/1/photo", blue, blueType).Status(http.StatusInternalServerError)
+
+	//the old photo is still there and served, the files of the failed upload are gone
+	h.GET("/api/students/1/photo").Do().Status(http.StatusOK).Header("ETag", `"`+current.ETag+`"`)
+	if after := blobFiles(t, h); !slices.Equal(after, before) {
+		t.Errorf("files: got %v, want %v", after, before)
+	}
+}
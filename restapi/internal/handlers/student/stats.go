@@ -0,0 +1,73 @@
+package student
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shivakr07/students-api/internal/api"
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/filter"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// Stats serves counts, min/max/avg age and an age histogram, all computed by the storage backend
+// ?buckets=18,25,30 sets the histogram boundaries [default from config], ?group_by=email_domain adds
+// counts per value and ?filter= narrows the students the same way the list does
+func Stats(store storage.StatsStorage, codec api.StudentCodec, cfg config.Stats) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slog.Info("getting student stats")
+
+		buckets := cfg.AgeBuckets
+		if raw := r.URL.Query().Get("buckets"); raw != "" {
+			var err error
+			if buckets, err = parseBuckets(raw); err != nil {
+				response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+				return
+			}
+		}
+
+		groupBy := r.URL.Query().Get("group_by")
+		if groupBy != "" && !slices.Contains(types.StatsGroupBy, groupBy) {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(
+				i18n.Errorf("stats.group_by", "can't group by \"%s\", use %s", groupBy, strings.Join(types.StatsGroupBy, " or "))))
+			return
+		}
+
+		where, err := filter.Parse(r.URL.Query().Get("filter"), codec.Fields())
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		stats, err := store.StudentStats(types.StatsQuery{AgeBuckets: buckets, GroupBy: groupBy, Filter: where})
+		if err != nil {
+			writeStorageError(w, err)
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, stats)
+	}
+}
+
+func parseBuckets(raw string) ([]int, error) {
+	var buckets []int
+	for _, part := range strings.Split(raw, ",") {
+		bound, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, i18n.Errorf("stats.bucket_number", "buckets: \"%s\" is not a whole number", part)
+		}
+		buckets = append(buckets, bound)
+	}
+
+	//config's message is for whoever edits the yaml, the client gets one it can read in its language
+	if err := config.ValidateBuckets(buckets); err != nil {
+		return nil, i18n.Errorf("stats.buckets", "buckets: between 1 and 50 boundaries in ascending order are needed")
+	}
+	return buckets, nil
+}
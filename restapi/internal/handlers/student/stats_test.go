@@ -0,0 +1,49 @@
+package student_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/apitest"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// ages come from the date of birth on the day of the query, the stored age [from the last write]
+// only counts for students without one
+func TestStatsAgeFromDateOfBirth(t *testing.T) {
+	h := apitest.New(t, apitest.InMemory())
+	today := time.Now().UTC()
+	born := func(years int, days int) string {
+		return today.AddDate(-years, 0, days).Format(types.DateLayout)
+	}
+
+	h.POST("/api/v2/students").JSON(fmt.Sprintf(`{"first_name": "Ada", "last_name": "Lovelace", "email": "ada@example.com", "date_of_birth": %q}`, born(30, 0))).Do().Status(http.StatusCreated)
+	//twenty tomorrow, so still 19
+	h.POST("/api/v2/students").JSON(fmt.Sprintf(`{"first_name": "Grace", "last_name": "Hopper", "email": "grace@example.com", "date_of_birth": %q}`, born(20, 1))).Do().Status(http.StatusCreated)
+	h.POST("/api/students").JSON(`{"name": "Alan Turing", "email": "alan@gmail.com", "age": 25}`).Do().Status(http.StatusCreated)
+
+	//the stored ages of the first two as if they were written long ago
+	if _, err := h.Storage.Db.Exec("UPDATE students SET age = 1 WHERE date_of_birth IS NOT NULL"); err != nil {
+		t.Fatal(err)
+	}
+
+	var stats types.StudentStats
+	h.GET("/api/v2/students/stats?buckets=20,26&group_by=age").Do().Status(http.StatusOK).Decode(&stats)
+
+	if stats.Count != 3 || *stats.MinAge != 19 || *stats.MaxAge != 30 || *stats.AvgAge != 24.67 {
+		t.Errorf("got count %d, min %d, max %d, avg %v", stats.Count, *stats.MinAge, *stats.MaxAge, *stats.AvgAge)
+	}
+	for i, want := range []int{1, 1, 1} {
+		if stats.AgeBands[i].Count != want {
+			t.Errorf("band %s: got %d, want %d", stats.AgeBands[i].Label, stats.AgeBands[i].Count, want)
+		}
+	}
+	if len(stats.Groups) != 3 {
+		t.Errorf("got groups %+v", stats.Groups)
+	}
+
+	h.GET("/api/v2/students?filter=age<20&fields=first_name").Do().Status(http.StatusOK).JSON(`[{"first_name": "Grace"}]`)
+	h.GET("/api/v2/students/stats?filter=age>=25").Do().Status(http.StatusOK).Field("count", 2)
+}
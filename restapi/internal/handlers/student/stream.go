@@ -0,0 +1,131 @@
+package student
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/events"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// Stream is the server-sent events feed of student changes
+// a client that reconnects with Last-Event-ID first gets everything it missed from the outbox, then the live events
+func Stream(store storage.EventStorage, broker *events.Broker, heartbeat time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var lastId int64
+		resume := false
+
+		//browsers send the header on reconnect, ?last_event_id= is there for clients that can't set headers
+		raw := r.Header.Get("Last-Event-ID")
+		if raw == "" {
+			raw = r.URL.Query().Get("last_event_id")
+		}
+		if raw != "" {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || id < 0 {
+				response.WriteJson(w, http.StatusBadRequest, response.GeneralError(i18n.Errorf("stream.last_event_id", "invalid Last-Event-ID \"%s\"", raw)))
+				return
+			}
+			lastId, resume = id, true
+		}
+
+		//subscribe before replaying so nothing written in between is lost, duplicates are skipped by id below
+		sub := broker.Subscribe()
+		if sub == nil {
+			response.WriteJson(w, http.StatusServiceUnavailable, response.GeneralError(i18n.Errorf("stream.shutting_down", "server is shutting down")))
+			return
+		}
+		defer broker.Unsubscribe(sub)
+
+		rc := http.NewResponseController(w)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		slog.Info("event stream opened", slog.String("remote", r.RemoteAddr), slog.String("last_event_id", raw))
+
+		if resume {
+			for {
+				missed, err := store.EventsAfter(lastId, 100)
+				if err != nil {
+					slog.Error("event stream replay failed", slog.String("error", err.Error()))
+					return
+				}
+				if len(missed) == 0 {
+					break
+				}
+
+				for _, event := range missed {
+					if err := writeEvent(w, event); err != nil {
+						return
+					}
+					lastId = event.Id
+				}
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				slog.Info("event stream closed by client", slog.String("remote", r.RemoteAddr))
+				return
+
+			case <-ticker.C:
+				//comment lines keep proxies from timing out an idle connection
+				if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+
+			case event, ok := <-sub.Events:
+				if !ok {
+					if broker.Dropped(sub) {
+						io.WriteString(w, "event: dropped\ndata: {\"error\":\"consumer too slow\"}\n\n")
+						rc.Flush()
+					}
+					slog.Info("event stream ended", slog.String("remote", r.RemoteAddr))
+					return
+				}
+				if event.Id <= lastId {
+					continue
+				}
+
+				if err := writeEvent(w, event); err != nil {
+					return
+				}
+				lastId = event.Id
+			}
+
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(w io.Writer, event types.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Id, event.Type, data)
+	return err
+}
@@ -4,14 +4,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/shivakr07/students-api/internal/api"
+	"github.com/shivakr07/students-api/internal/filter"
+	"github.com/shivakr07/students-api/internal/i18n"
 	"github.com/shivakr07/students-api/internal/storage"
 	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/request"
 	"github.com/shivakr07/students-api/internal/utils/response"
 )
 
@@ -21,60 +27,39 @@ import (
 // func(w http.ResponseWriter, r *http.Request) { .. this func
 // and at that place we just need to give reference of this func
 
-func New(storage storage.Storage) http.HandlerFunc {
+// the codec is the api version's json form of a student [see internal/api], the same handler serves every version
+func New(storage storage.Storage, codec api.StudentCodec, reader request.Reader) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		slog.Info("creating a student")
 
-		var student types.Student
-
-		err := json.NewDecoder(r.Body).Decode(&student)
-		if errors.Is(err, io.EOF) {
-			//we can directly return the response using write but we want to return json response
-			//we make one more package response in the utils
-
-			// response.WriteJson(w, http.StatusBadRequest, err.Error())
-			// instead of the err.Error we will call that function
-			// response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
-
-			// or you can be more specific about the error till now we were getting EOF in the error but we can be specific
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("empty body")))
-			return
-			//returning : which make sure no further execution after this
-		}
-		// this errors package matches the error which we pass so here EOF means end of file [means we have got the empty object / no data from the request body]
-		//this NewDecoder accepts interface of type io.Reader and this request we are getting implements that so we can pass that
-
-		//what if we get some other except EOF we need to catch that too
+		//the body is limited, must be a single json value and can't have fields a student doesn't have
+		//[a typo like "emial" would otherwise just look like a missing email]
+		//we return json errors through the response package, and request gives us the status [400, or 413 when too large]
+		body, err := reader.ReadBody(w, r)
 		if err != nil {
-			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			response.WriteJson(w, request.Status(err), response.GeneralError(err))
 			return
+			//returning : which make sure no further execution after this
 		}
 
 		//VALIDATE THE REQUEST [don't believe on client][0 trust policy]
-		//REQUEST VALIDATION
-		// we can do it manually but we will use package [validator] : golang request valiation playground
-		if err := validator.New().Struct(student); err != nil {
-			//since ValidationError function accepts a different type than err so we need to typecaste that
-			validateErrors := err.(validator.ValidationErrors)
-			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrors))
+		//the codec decodes and validates the version's dto, then maps it onto a new domain student
+		student, err := codec.Decode(body, types.Student{})
+		if err != nil {
+			writeDecodeError(w, err)
 			return
 		}
 
 		//create student
 		//since we are receiving it as dependency then we can use that in this way
-		lastId, err := storage.CreateStudent(
-			student.Name,
-			student.Email,
-			student.Age,
-		)
-
-		slog.Info("user created successfully", slog.String("userId", fmt.Sprint(lastId)))
-
+		lastId, err := storage.CreateStudent(student)
 		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, err)
+			writeStorageError(w, err)
 			return
 		}
 
+		slog.Info("user created successfully", slog.String("userId", fmt.Sprint(lastId)))
+
 		//we need to serialize the json data we will get from request, so that we can use that
 
 		// response.WriteJson(w, http.StatusCreated, map[string]string{"sucess": "OK"})
@@ -95,16 +80,45 @@ func New(storage storage.Storage) http.HandlerFunc {
 	}
 }
 
+// Validate holds the validation rules of the domain student
+// entry points without a versioned dto (graphql) call this so the rules can't drift from New
+func Validate(student types.Student) error {
+	return validator.New().Struct(student)
+}
+
+// writeDecodeError answers a codec.Decode error, everything it returns is the client's mistake
+func writeDecodeError(w http.ResponseWriter, err error) {
+	var validateErrors validator.ValidationErrors
+	if errors.As(err, &validateErrors) {
+		response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrors))
+		return
+	}
+	response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+}
+
+// writeStorageError answers a storage error, the ones the client caused get a 4xx and the rest a 500
+func writeStorageError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, storage.ErrEmailTaken):
+		status = http.StatusConflict
+	case errors.Is(err, storage.ErrEncryptedField):
+		//filtering or grouping on an encrypted field, sql can't look inside it
+		status = http.StatusBadRequest
+	}
+	response.WriteJson(w, status, response.GeneralError(err))
+}
+
 //any dependency for the New function will be defined here as definition is not separated to make the clean everything
 //we will inject the dependency here -> DEPENDENCY INJECTION
 
-func GetById(storage storage.Storage) http.HandlerFunc {
+func GetById(storage storage.Storage, codec api.StudentCodec) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		//we will get dynamic id here
 		id := r.PathValue("id")
 		slog.Info("getting a student", slog.String("id", id))
 
-		intId, err := strconv.ParseInt(id, 10, 64)
+		intId, err := request.PathId(r, "id")
 		//since our id is in string but we want that in the int64
 		if err != nil {
 			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
@@ -114,25 +128,154 @@ func GetById(storage storage.Storage) http.HandlerFunc {
 		student, err := storage.GetStudentById(intId)
 		if err != nil {
 			slog.Error("error getting user", slog.String("id", id))
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			response.WriteJson(w, getStatus(err), response.GeneralError(err))
 			return
 		}
 
-		response.WriteJson(w, http.StatusOK, student)
+		response.WriteJson(w, http.StatusOK, codec.Encode(student))
 	}
 
 }
 
-func GetList(storage storage.Storage) http.HandlerFunc {
+// getStatus is 404 for a student that isn't there and 500 for a database that failed, for the
+// handlers that read or write a single student
+func getStatus(err error) int {
+	if errors.Is(err, storage.ErrStudentNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// the biggest page ?limit= can ask for
+const maxPageSize = 100
+
+// GetList takes optional ?filter=age>=18 and name~"sha" [see the filter package for the grammar]
+// and ?fields=id,name to only return some fields of each student
+// ?limit= and ?offset= page through the list in id order, without a limit every student comes back
+func GetList(storage storage.Storage, codec api.StudentCodec) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		slog.Info("getting all the students")
 
-		students, err := storage.GetStudents()
+		fields, err := parseFields(r.URL.Query().Get("fields"), codec.Fields())
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		where, err := filter.Parse(r.URL.Query().Get("filter"), codec.Fields())
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		limit, offset, err := parsePage(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		students, err := storage.ListStudents(types.StudentQuery{Filter: where, Limit: limit, Offset: offset})
+		if err != nil {
+			writeStorageError(w, err)
+			return
+		}
+
+		encoded := make([]any, len(students))
+		for i, student := range students {
+			encoded[i] = codec.Encode(student)
+		}
+
+		if fields == nil {
+			response.WriteJson(w, http.StatusOK, encoded)
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, project(encoded, fields))
+	}
+}
+
+// parsePage reads ?limit and ?offset, a missing limit is 0 [no limit]
+func parsePage(rawLimit string, rawOffset string) (int, int, error) {
+	var limit, offset int
+	var err error
+
+	if rawLimit != "" {
+		limit, err = strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return 0, 0, i18n.Errorf("request.limit", "limit must be between 1 and %d", maxPageSize)
+		}
+	}
+	if rawOffset != "" {
+		offset, err = strconv.Atoi(rawOffset)
+		if err != nil || offset < 0 {
+			return 0, 0, i18n.Errorf("request.offset", "offset can't be negative")
+		}
+	}
+
+	return limit, offset, nil
+}
+
+// parseFields checks a ?fields= list against the student's json names, nil means every field
+func parseFields(value string, known map[string]filter.Kind) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if _, ok := known[field]; !ok {
+			return nil, i18n.Errorf("request.unknown_fields_param", "unknown field \"%s\" in fields, use %s", field, fieldNames(known))
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields, nil
+}
+
+func fieldNames(known map[string]filter.Kind) string {
+	names := slices.Sorted(maps.Keys(known))
+	return strings.Join(names, ", ")
+}
+
+// project keeps only the requested fields, going through json so the names match the full response
+func project(students []any, fields []string) []map[string]any {
+	out := make([]map[string]any, 0, len(students))
+	for _, student := range students {
+		data, _ := json.Marshal(student)
+
+		var all map[string]any
+		json.Unmarshal(data, &all)
+
+		row := make(map[string]any, len(fields))
+		for _, field := range fields {
+			row[field] = all[field]
+		}
+		out = append(out, row)
+	}
+
+	return out
+}
+
+// Delete removes a student, whatever hangs off it [enrollments, photo...] goes with it
+func Delete(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		slog.Info("deleting a student", slog.String("id", id))
+
+		intId, err := request.PathId(r, "id")
 		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, err)
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		if err := storage.DeleteStudent(intId); err != nil {
+			response.WriteJson(w, getStatus(err), response.GeneralError(err))
 			return
 		}
 
-		response.WriteJson(w, http.StatusOK, students)
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
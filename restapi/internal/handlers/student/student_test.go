@@ -0,0 +1,360 @@
+package student_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/apitest"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		opts    []apitest.Option
+		status  int
+		want    string //the whole body, checked when set
+		contain string //part of the body, for errors with details
+	}{
+		{
+			name:   "v1 student",
+			path:   "/api/students",
+			body:   `{"name": "Alan Turing", "email": "alan@gmail.com", "age": 25}`,
+			status: http.StatusCreated,
+			want:   `{"id": 1}`,
+		},
+		{
+			name:   "v2 student",
+			path:   "/api/v2/students",
+			body:   `{"first_name": "Ada", "last_name": "Lovelace", "email": "ada@example.com", "date_of_birth": "2000-12-10"}`,
+			status: http.StatusCreated,
+			want:   `{"id": 1}`,
+		},
+		{
+			name:   "empty body",
+			path:   "/api/students",
+			body:   ``,
+			status: http.StatusBadRequest,
+			want:   `{"status": "Error", "error": "empty body"}`,
+		},
+		{
+			name:    "malformed json",
+			path:    "/api/students",
+			body:    `{"name": "Alan",`,
+			status:  http.StatusBadRequest,
+			contain: "malformed json",
+		},
+		{
+			name:   "unknown field",
+			path:   "/api/students",
+			body:   `{"name": "Alan", "emial": "alan@gmail.com", "age": 25}`,
+			status: http.StatusBadRequest,
+			want:   `{"status": "Error", "error": "unknown field \"emial\""}`,
+		},
+		{
+			name:    "wrong type",
+			path:    "/api/students",
+			body:    `{"name": "Alan", "email": "alan@gmail.com", "age": "25"}`,
+			status:  http.StatusBadRequest,
+			contain: `field \"age\" must be a number`,
+		},
+		{
+			name:    "two json values",
+			path:    "/api/students",
+			body:    `{"name": "Alan", "email": "alan@gmail.com", "age": 25} {}`,
+			status:  http.StatusBadRequest,
+			contain: "single json value",
+		},
+		{
+			name:   "missing fields",
+			path:   "/api/students",
+			body:   `{"name": "Alan"}`,
+			status: http.StatusBadRequest,
+			want:   `{"status": "Error", "error": "field Email is required field, field Age is required field"}`,
+		},
+		{
+			name:   "v2 without date of birth",
+			path:   "/api/v2/students",
+			body:   `{"first_name": "Ada", "email": "ada@example.com"}`,
+			status: http.StatusBadRequest,
+			want:   `{"status": "Error", "error": "field DateOfBirth is required field"}`,
+		},
+		{
+			name:   "v2 date of birth in the future",
+			path:   "/api/v2/students",
+			body:   `{"first_name": "Ada", "email": "ada@example.com", "date_of_birth": "` + time.Now().AddDate(1, 0, 0).Format("2006-01-02") + `"}`,
+			status: http.StatusBadRequest,
+			want:   `{"status": "Error", "error": "date_of_birth can't be in the future"}`,
+		},
+		{
+			name:    "body too large",
+			path:    "/api/students",
+			body:    `{"name": "Alan Turing", "email": "alan@gmail.com", "age": 25}`,
+			opts:    []apitest.Option{apitest.Set("http_server.max_body_bytes", "16")},
+			status:  http.StatusRequestEntityTooLarge,
+			contain: "larger than 16 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := apitest.New(t, append([]apitest.Option{apitest.InMemory()}, tt.opts...)...)
+
+			res := h.POST(tt.path).JSON(tt.body).Do().Status(tt.status)
+			if tt.want != "" {
+				res.JSON(tt.want)
+			}
+			if tt.contain != "" {
+				res.Contains(tt.contain)
+			}
+
+			//nothing is stored when the request is refused
+			students, err := h.Storage.GetStudents()
+			if err != nil {
+				t.Fatal(err)
+			}
+			created := 0
+			if tt.status == http.StatusCreated {
+				created = 1
+			}
+			if len(students) != created {
+				t.Errorf("%d students stored, want %d", len(students), created)
+			}
+		})
+	}
+}
+
+// the body limit and the error catalog belong to each app, two of them in one process don't mix
+func TestNewSeparateApps(t *testing.T) {
+	small := apitest.New(t, apitest.InMemory(),
+		apitest.Set("http_server.max_body_bytes", "16"),
+		apitest.Set("i18n.default", "fr"))
+	large := apitest.New(t, apitest.InMemory())
+
+	body := `{"name": "Alan Turing", "email": "alan@gmail.com", "age": 25}`
+	large.POST("/api/students").JSON(body).Do().Status(http.StatusCreated)
+	small.POST("/api/students").JSON(body).Do().
+		Status(http.StatusRequestEntityTooLarge).
+		Header("Content-Language", "fr").
+		JSON(`{"status": "Error", "error": "le corps de la requête dépasse 16 octets"}`)
+	large.POST("/api/students").JSON(`{}`).Do().
+		Status(http.StatusBadRequest).
+		Header("Content-Language", "en").
+		JSON(`{"status": "Error", "error": "field Name is required field, field Email is required field, field Age is required field"}`)
+}
+
+// a student created through one version reads back through the other
+func TestNewRoundTrip(t *testing.T) {
+	h := apitest.New(t, apitest.InMemory())
+
+	h.POST("/api/v2/students").
+		JSON(`{"first_name": "Ada", "last_name": "King Lovelace", "email": "ada@example.com", "date_of_birth": "2000-12-10"}`).
+		Do().Status(http.StatusCreated)
+
+	h.GET("/api/v1/students/1").Do().
+		Status(http.StatusOK).
+		Field("name", "Ada King Lovelace").
+		Field("email", "ada@example.com")
+
+	h.GET("/api/v2/students/1").Do().
+		Status(http.StatusOK).
+		Field("first_name", "Ada").
+		Field("last_name", "King Lovelace").
+		Field("date_of_birth", "2000-12-10")
+}
+
+func TestGetById(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		version string
+		lang    string
+		status  int
+		golden  string
+		want    string
+	}{
+		{name: "v1", path: "/api/v1/students/1", status: http.StatusOK, golden: "get_by_id_v1"},
+		{name: "v2", path: "/api/v2/students/1", status: http.StatusOK, golden: "get_by_id_v2"},
+		{name: "no version is v1", path: "/api/students/1", status: http.StatusOK, golden: "get_by_id_v1"},
+		{name: "version header", path: "/api/students/1", version: "2", status: http.StatusOK, golden: "get_by_id_v2"},
+		{
+			name:    "unknown version",
+			path:    "/api/students/1",
+			version: "9",
+			status:  http.StatusBadRequest,
+			want:    `{"status": "Error", "error": "unknown api version \"9\""}`,
+		},
+		{
+			name:    "unknown version in french",
+			path:    "/api/students/1",
+			version: "9",
+			lang:    "fr",
+			status:  http.StatusBadRequest,
+			want:    `{"status": "Error", "error": "version d'api inconnue \"9\""}`,
+		},
+		{
+			name:   "id is not a number",
+			path:   "/api/students/abc",
+			status: http.StatusBadRequest,
+			want:   `{"status": "Error", "error": "invalid id \"abc\""}`,
+		},
+		{
+			name:   "id is not a number in hindi",
+			path:   "/api/students/abc",
+			lang:   "hi",
+			status: http.StatusBadRequest,
+			want:   `{"status": "Error", "error": "अमान्य id \"abc\""}`,
+		},
+		{
+			name:   "missing student",
+			path:   "/api/students/99",
+			status: http.StatusNotFound,
+			want:   `{"status": "Error", "error": "no student found with id 99"}`,
+		},
+		{
+			name:   "missing student in french",
+			path:   "/api/students/99",
+			lang:   "fr",
+			status: http.StatusNotFound,
+			want:   `{"status": "Error", "error": "aucun étudiant avec l'id 99"}`,
+		},
+	}
+
+	h := apitest.New(t)
+	h.LoadStudents()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := h.GET(tt.path)
+			if tt.version != "" {
+				req.Version(tt.version)
+			}
+			if tt.lang != "" {
+				req.Header("Accept-Language", tt.lang)
+			}
+
+			res := req.Do().Status(tt.status)
+			if tt.golden != "" {
+				res.Golden(tt.golden)
+			}
+			if tt.want != "" {
+				res.JSON(tt.want)
+			}
+		})
+	}
+}
+
+func TestGetList(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		golden string
+		want   string
+	}{
+		{name: "v1", path: "/api/v1/students", status: http.StatusOK, golden: "list_v1"},
+		{name: "v2", path: "/api/v2/students", status: http.StatusOK, golden: "list_v2"},
+		{
+			name:   "filter",
+			path:   `/api/students?filter=age>=21%20and%20email~"gmail"`,
+			status: http.StatusOK,
+			want:   `[{"id": 1, "name": "Alan Turing", "email": "alan@gmail.com", "age": 25}]`,
+		},
+		{
+			name:   "filter matching nothing",
+			path:   "/api/students?filter=age>100",
+			status: http.StatusOK,
+			want:   `[]`,
+		},
+		{
+			name:   "fields",
+			path:   "/api/students?fields=id,name",
+			status: http.StatusOK,
+			want:   `[{"id": 1, "name": "Alan Turing"}, {"id": 2, "name": "Ada Lovelace"}, {"id": 3, "name": "Grace"}]`,
+		},
+		{
+			name:   "v2 fields and filter",
+			path:   `/api/v2/students?fields=first_name,last_name&filter=last_name=""`,
+			status: http.StatusOK,
+			want:   `[{"first_name": "Grace", "last_name": ""}]`,
+		},
+		{
+			name:   "page",
+			path:   "/api/students?fields=id&limit=2&offset=1",
+			status: http.StatusOK,
+			want:   `[{"id": 2}, {"id": 3}]`,
+		},
+		{
+			name:   "page past the end",
+			path:   "/api/students?limit=2&offset=3",
+			status: http.StatusOK,
+			want:   `[]`,
+		},
+		{
+			name:   "limit too big",
+			path:   "/api/students?limit=1000",
+			status: http.StatusBadRequest,
+			want:   `{"status": "Error", "error": "limit must be between 1 and 100"}`,
+		},
+		{
+			name:   "unknown field in fields",
+			path:   "/api/students?fields=id,first_name",
+			status: http.StatusBadRequest,
+			want:   `{"status": "Error", "error": "unknown field \"first_name\" in fields, use age, email, id, name"}`,
+		},
+		{
+			name:   "v1 field in a v2 filter",
+			path:   `/api/v2/students?filter=name="Grace"`,
+			status: http.StatusBadRequest,
+			want:   `{"status": "Error", "error": "filter: at position 1: unknown field \"name\", use age, email, first_name, id, last_name"}`,
+		},
+		{
+			name:   "broken filter",
+			path:   "/api/students?filter=age>=",
+			status: http.StatusBadRequest,
+		},
+	}
+
+	h := apitest.New(t)
+	h.LoadStudents()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := h.GET(tt.path).Do().Status(tt.status)
+			if tt.golden != "" {
+				res.Golden(tt.golden)
+			}
+			if tt.want != "" {
+				res.JSON(tt.want)
+			}
+			if tt.status == http.StatusBadRequest {
+				res.Field("status", "Error")
+			}
+		})
+	}
+}
+
+func TestGetListEmpty(t *testing.T) {
+	h := apitest.New(t, apitest.InMemory())
+
+	h.GET("/api/students").Do().Status(http.StatusOK).JSON(`[]`)
+}
+
+func TestDelete(t *testing.T) {
+	h := apitest.New(t)
+	h.LoadStudents()
+
+	h.DELETE("/api/students/1").Do().Status(http.StatusNoContent)
+	h.DELETE("/api/students/1").Do().
+		Status(http.StatusNotFound).
+		JSON(`{"status": "Error", "error": "no student found with id 1"}`)
+
+	//a write that fails isn't a missing student
+	if _, err := h.Storage.Db.Exec(`CREATE TRIGGER no_delete BEFORE DELETE ON students
+		BEGIN SELECT RAISE(ABORT, 'disk I/O error'); END`); err != nil {
+		t.Fatal(err)
+	}
+	h.DELETE("/api/students/2").Do().Status(http.StatusInternalServerError)
+}
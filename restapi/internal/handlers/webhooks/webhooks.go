@@ -0,0 +1,125 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/request"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// admin api for webhook subscriptions and their deliveries
+// same pattern as the student handlers, storage comes in as a dependency
+
+func New(storage storage.WebhookStorage, reader request.Reader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slog.Info("registering a webhook")
+
+		var webhook types.Webhook
+
+		if err := reader.DecodeJson(w, r, &webhook); err != nil {
+			response.WriteJson(w, request.Status(err), response.GeneralError(err))
+			return
+		}
+
+		if err := validator.New().Struct(webhook); err != nil {
+			validateErrors := err.(validator.ValidationErrors)
+			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrors))
+			return
+		}
+
+		id, err := storage.CreateWebhook(webhook.URL, webhook.Secret)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		slog.Info("webhook registered", slog.String("id", fmt.Sprint(id)), slog.String("url", webhook.URL))
+		response.WriteJson(w, http.StatusCreated, map[string]int64{"id": id})
+	}
+}
+
+func GetList(storage storage.WebhookStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		webhooks, err := storage.GetWebhooks()
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, webhooks)
+	}
+}
+
+func Delete(storage storage.WebhookStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		if err := storage.DeleteWebhook(id); err != nil {
+			response.WriteJson(w, storageStatus(err), response.GeneralError(err))
+			return
+		}
+
+		slog.Info("webhook deleted", slog.String("id", fmt.Sprint(id)))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetDeliveries lists deliveries, ?status=dead shows the dead letters
+func GetDeliveries(storage storage.WebhookStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := r.URL.Query().Get("status")
+
+		switch status {
+		case "", types.DeliveryPending, types.DeliveryDelivered, types.DeliveryDead:
+		default:
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(i18n.Errorf("webhooks.unknown_status", "unknown status \"%s\"", status)))
+			return
+		}
+
+		deliveries, err := storage.GetDeliveries(status)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		response.WriteJson(w, http.StatusOK, deliveries)
+	}
+}
+
+// Redeliver queues a delivery again, mostly used to replay dead letters once the receiver is fixed
+func Redeliver(storage storage.WebhookStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := request.PathId(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		if err := storage.RedeliverDelivery(id); err != nil {
+			response.WriteJson(w, storageStatus(err), response.GeneralError(err))
+			return
+		}
+
+		slog.Info("delivery queued again", slog.String("id", fmt.Sprint(id)))
+		response.WriteJson(w, http.StatusAccepted, map[string]string{"status": response.StatusOK})
+	}
+}
+
+// storageStatus is 404 for a webhook or delivery that isn't there and 500 for a database that failed
+func storageStatus(err error) int {
+	if errors.Is(err, storage.ErrWebhookNotFound) || errors.Is(err, storage.ErrDeliveryNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
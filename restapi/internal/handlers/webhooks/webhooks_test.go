@@ -0,0 +1,26 @@
+package webhooks_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/shivakr07/students-api/internal/apitest"
+)
+
+func TestNotFound(t *testing.T) {
+	h := apitest.New(t)
+
+	h.DELETE("/admin/webhooks/9").Admin().Do().
+		Status(http.StatusNotFound).
+		JSON(`{"status": "Error", "error": "no webhook found with id 9"}`)
+	h.POST("/admin/webhooks/deliveries/9/redeliver").Admin().Header("Accept-Language", "fr").Do().
+		Status(http.StatusNotFound).
+		JSON(`{"status": "Error", "error": "aucune livraison avec l'id 9"}`)
+
+	//a write that fails isn't a missing row
+	if _, err := h.Storage.Db.Exec("DROP TABLE webhook_deliveries"); err != nil {
+		t.Fatal(err)
+	}
+	h.DELETE("/admin/webhooks/9").Admin().Do().Status(http.StatusInternalServerError)
+	h.POST("/admin/webhooks/deliveries/9/redeliver").Admin().Do().Status(http.StatusInternalServerError)
+}
@@ -0,0 +1,54 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error is an error with a catalog key, its Error() is the english message so nothing changes for
+// code that only logs or compares it
+type Error struct {
+	Key    string
+	Params []string
+	msg    string
+}
+
+func (e *Error) Error() string { return e.msg }
+
+// Is matches errors by key, so errors.Is(err, sentinel) holds for a message made with Errorf and
+// the sentinel's key [the id in the message doesn't matter]
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Key == e.Key
+}
+
+// New is errors.New with a key, for sentinel errors
+func New(key string, msg string) *Error {
+	return &Error{Key: key, msg: msg}
+}
+
+// Errorf formats the english message with format, and keeps args as the {0}, {1}... of the translation
+func Errorf(key string, format string, args ...any) *Error {
+	params := make([]string, len(args))
+	for i, arg := range args {
+		params[i] = fmt.Sprint(arg)
+	}
+	return &Error{Key: key, Params: params, msg: fmt.Sprintf(format, args...)}
+}
+
+// Translate returns err's message in lang, when err is or wraps an *Error only that part is translated
+// [a prefix added by fmt.Errorf("...: %w") stays as it is], anything else is returned unchanged
+func (c *Catalog) Translate(lang string, err error) string {
+	msg := err.Error()
+
+	var keyed *Error
+	if !errors.As(err, &keyed) {
+		return msg
+	}
+	translated, ok := c.T(lang, keyed.Key, keyed.Params...)
+	if !ok {
+		return msg
+	}
+	return strings.Replace(msg, keyed.msg, translated, 1)
+}
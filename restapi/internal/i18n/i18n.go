@@ -0,0 +1,180 @@
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	"github.com/go-playground/locales/hi"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/shivakr07/students-api/internal/config"
+)
+
+// error messages in the language of the client, picked from Accept-Language
+//
+// messages live in catalogs: json files in the universal-translator format, one entry per message
+//
+//	[{"locale": "fr", "key": "request.empty_body", "trans": "corps de requête vide"}]
+//
+// the built-in ones [catalogs/*.json] are embedded, a deployment can add files in i18n.dir to translate
+// more messages or add "override": true to reword a built-in one
+// placeholders are {0}, {1}... in the order the go code passes the values
+
+//go:embed catalogs/*.json
+var builtin embed.FS
+
+// the languages a deployment can turn on, plural rules and such come from the locales package
+var known = map[string]func() locales.Translator{
+	"en": en.New,
+	"fr": fr.New,
+	"hi": hi.New,
+	"es": es.New,
+	"de": de.New,
+}
+
+type Catalog struct {
+	uni       *ut.UniversalTranslator
+	languages []string
+	fallback  string
+}
+
+// Load reads the built-in catalogs and then the ones in cfg.Dir
+func Load(cfg config.I18n) (*Catalog, error) {
+	translators := make([]locales.Translator, 0, len(cfg.Languages))
+	for _, lang := range cfg.Languages {
+		newTranslator, ok := known[lang]
+		if !ok {
+			return nil, fmt.Errorf("i18n: unknown language %q", lang)
+		}
+		translators = append(translators, newTranslator())
+	}
+
+	c := &Catalog{
+		uni:       ut.New(known[cfg.Default](), translators...),
+		languages: cfg.Languages,
+		fallback:  cfg.Default,
+	}
+
+	files, err := fs.Glob(builtin, "catalogs/*.json")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range files {
+		//a built-in catalog for a language the deployment didn't turn on is skipped
+		if !slices.Contains(cfg.Languages, strings.TrimSuffix(strings.TrimPrefix(name, "catalogs/"), ".json")) {
+			continue
+		}
+		f, err := builtin.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		err = c.uni.ImportByReader(ut.FormatJSON, f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", name, err)
+		}
+	}
+
+	if cfg.Dir != "" {
+		if err := c.uni.Import(ut.FormatJSON, cfg.Dir); err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", cfg.Dir, err)
+		}
+	}
+
+	return c, nil
+}
+
+// T translates key, false means the language has no such message and the caller keeps its english text
+func (c *Catalog) T(lang string, key string, params ...string) (string, bool) {
+	translator, ok := c.uni.GetTranslator(lang)
+	if !ok {
+		return "", false
+	}
+	text, err := translator.T(key, params...)
+	if err != nil {
+		return "", false
+	}
+	return text, true
+}
+
+// Negotiate picks the best language for an Accept-Language header, like "fr-CA,fr;q=0.9,en;q=0.5"
+// a region falls back to its base language and anything unknown gets the default
+func (c *Catalog) Negotiate(header string) string {
+	type choice struct {
+		tag string
+		q   float64
+	}
+
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag == "" || q <= 0 {
+			continue
+		}
+		choices = append(choices, choice{tag: strings.ToLower(tag), q: q})
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+
+	for _, choice := range choices {
+		if choice.tag == "*" {
+			return c.fallback
+		}
+		base, _, _ := strings.Cut(choice.tag, "-")
+		if slices.Contains(c.languages, base) {
+			return base
+		}
+	}
+	return c.fallback
+}
+
+// Middleware negotiates the language once and puts it in Content-Language, which is where
+// response.WriteJson looks for it, the catalog goes down with the response writer [see From]
+func (c *Catalog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Language", c.Negotiate(r.Header.Get("Accept-Language")))
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(&writer{ResponseWriter: w, catalog: c}, r)
+	})
+}
+
+// writer carries the catalog of the app the request came through, so two apps in one process
+// [tests] each translate with their own
+type writer struct {
+	http.ResponseWriter
+	catalog *Catalog
+}
+
+// Unwrap lets http.ResponseController reach the flusher of the writer below
+func (w *writer) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// From is the catalog of the Middleware w went through, nil when it went through none
+func From(w http.ResponseWriter) *Catalog {
+	for {
+		switch rw := w.(type) {
+		case *writer:
+			return rw.catalog
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// the manager starts the parts of the server in the order they were added and stops them in reverse,
+// so whatever a component depends on [the database, the workers] is up before it and still there while it drains
+
+// DefaultStopTimeout is how long a component gets to stop when it doesn't set its own
+const DefaultStopTimeout = 5 * time.Second
+
+// what Run returns wraps one of these, serve turns them into exit codes
+var (
+	ErrStart    = errors.New("startup failed")
+	ErrFailed   = errors.New("component failed")
+	ErrShutdown = errors.New("shutdown incomplete")
+)
+
+// Component is one part of the server, Start and Stop are both optional
+type Component struct {
+	Name string
+	//Start returns once the component is up, anything long running goes in a goroutine and
+	//reports a later failure through fail [that stops the whole server]
+	Start func(fail func(error)) error
+	//Stop gets a context that expires after StopTimeout
+	Stop        func(ctx context.Context) error
+	StopTimeout time.Duration
+}
+
+type Manager struct {
+	components []Component
+}
+
+func New() *Manager {
+	return &Manager{}
+}
+
+func (m *Manager) Add(c Component) {
+	m.components = append(m.components, c)
+}
+
+// Run starts every component and blocks until ctx is done [a signal] or a component fails, then stops
+// them all, a component that failed to start stops the ones before it right away
+func (m *Manager) Run(ctx context.Context) error {
+	//one slot per component, so a failing component never blocks on reporting it
+	failed := make(chan error, len(m.components))
+
+	started := 0
+	for _, c := range m.components {
+		if c.Start != nil {
+			name := c.Name
+			fail := func(err error) {
+				select {
+				case failed <- fmt.Errorf("%s: %w", name, err):
+				default:
+				}
+			}
+
+			if err := c.Start(fail); err != nil {
+				slog.Error("failed to start", slog.String("component", c.Name), slog.String("error", err.Error()))
+				return errors.Join(fmt.Errorf("%w: %s: %w", ErrStart, c.Name, err), m.stop(started))
+			}
+			slog.Info("started", slog.String("component", c.Name))
+		}
+		started++
+	}
+
+	var cause error
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down", slog.String("reason", context.Cause(ctx).Error()))
+	case err := <-failed:
+		slog.Error("shutting down after a failure", slog.String("error", err.Error()))
+		cause = fmt.Errorf("%w: %w", ErrFailed, err)
+	}
+
+	return errors.Join(cause, m.stop(started))
+}
+
+// stop stops the first n components in reverse order, each one with its own deadline
+// a component that overruns it is reported and the next one is stopped anyway
+func (m *Manager) stop(n int) error {
+	var errs []error
+
+	for i := n - 1; i >= 0; i-- {
+		c := m.components[i]
+		if c.Stop == nil {
+			continue
+		}
+
+		timeout := c.StopTimeout
+		if timeout <= 0 {
+			timeout = DefaultStopTimeout
+		}
+
+		//the parent context is already done at this point, every deadline starts fresh
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		start := time.Now()
+		err := c.Stop(ctx)
+		cancel()
+
+		if err != nil {
+			slog.Error("failed to stop", slog.String("component", c.Name), slog.String("error", err.Error()))
+			errs = append(errs, fmt.Errorf("%w: %s: %w", ErrShutdown, c.Name, err))
+			continue
+		}
+		slog.Info("stopped", slog.String("component", c.Name), slog.String("took", time.Since(start).Round(time.Millisecond).String()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// HTTPServer binds srv.Addr when it starts, so a port that is taken is a startup error and not
+// something that shows up later, Stop drains the open requests and cuts them off at the deadline
+func HTTPServer(name string, srv *http.Server, timeout time.Duration) Component {
+	return Component{
+		Name: name,
+		Start: func(fail func(error)) error {
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+
+			go func() {
+				//ErrServerClosed is what Serve returns after Shutdown, the normal way out
+				if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+					fail(err)
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			if err := srv.Shutdown(ctx); err != nil {
+				srv.Close()
+				return err
+			}
+			return nil
+		},
+		StopTimeout: timeout,
+	}
+}
+
+// Worker runs a background loop until it is stopped, Stop cancels its context and waits for run to return
+func Worker(name string, run func(ctx context.Context), timeout time.Duration) Component {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	return Component{
+		Name: name,
+		Start: func(fail func(error)) error {
+			go func() {
+				defer close(done)
+				run(ctx)
+			}()
+			return nil
+		},
+		Stop: func(stopCtx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-stopCtx.Done():
+				return fmt.Errorf("still running: %w", stopCtx.Err())
+			}
+		},
+		StopTimeout: timeout,
+	}
+}
+
+// Closer is for what was opened before the manager ran [the database], it only has a Stop
+func Closer(name string, close func() error) Component {
+	return Component{
+		Name: name,
+		Stop: func(ctx context.Context) error {
+			return close()
+		},
+	}
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/shivakr07/students-api/internal/buildinfo"
+	"github.com/shivakr07/students-api/internal/config"
+)
+
+// Logger is the slog logger the server runs with, built from the log section of the config
+// the level sits in a LevelVar so SIGHUP and the admin endpoint can change it while we serve
+type Logger struct {
+	*slog.Logger
+	level *slog.LevelVar
+	out   io.Writer
+}
+
+// New builds the logger, every line carries the build version and commit plus log.attrs
+func New(cfg config.Log) (*Logger, error) {
+	level := new(slog.LevelVar)
+	l, err := config.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	level.Set(l)
+
+	var out io.Writer
+	switch cfg.Output {
+	case "stderr":
+		out = os.Stderr
+	case "file":
+		out, err = NewRotatingFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		out = os.Stdout
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler = slog.NewTextHandler(out, opts)
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(out, opts)
+	}
+
+	build := buildinfo.Get()
+	attrs := []slog.Attr{slog.String("version", build.Version), slog.String("commit", build.Commit)}
+
+	//sorted so the extra attributes come out in the same order on every line
+	keys := make([]string, 0, len(cfg.Attrs))
+	for key := range cfg.Attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		attrs = append(attrs, slog.String(key, cfg.Attrs[key]))
+	}
+
+	return &Logger{Logger: slog.New(handler.WithAttrs(attrs)), level: level, out: out}, nil
+}
+
+// Level is the current level in the form the config uses ["info"]
+func (l *Logger) Level() string {
+	return levelName(l.level.Level())
+}
+
+// SetLevel takes the same values as log.level [debug, info, warn, error]
+func (l *Logger) SetLevel(level string) error {
+	parsed, err := config.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	if previous := l.level.Level(); previous != parsed {
+		//logged before the switch, raising the level would hide the line otherwise
+		l.Info("log level changed", slog.String("from", levelName(previous)), slog.String("to", levelName(parsed)))
+		l.level.Set(parsed)
+	}
+	return nil
+}
+
+// Close closes the log file, stdout and stderr are left alone
+func (l *Logger) Close() error {
+	if f, ok := l.out.(*RotatingFile); ok {
+		return f.Close()
+	}
+	return nil
+}
+
+// levelName turns slog's "INFO" back into the lowercase form of the config
+func levelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
@@ -0,0 +1,179 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/config"
+)
+
+// rotated files are named after the time they were rotated, students-api-20060102T150405.000.log
+const rotateLayout = "20060102T150405.000"
+
+// RotatingFile is an io.Writer over a log file that moves the file aside once it grows past
+// max_size_mb and starts a new one, old files are pruned by max_backups and max_age
+type RotatingFile struct {
+	mu     sync.Mutex
+	cfg    config.LogFile
+	file   *os.File //nil after Close, or when reopening after a rotation failed [the next Write tries again]
+	size   int64
+	closed bool
+}
+
+func NewRotatingFile(cfg config.LogFile) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, err
+	}
+
+	r := &RotatingFile{cfg: cfg}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	//a single line bigger than the limit still goes in, into a file of its own
+	var rotateErr error
+	if r.size > 0 && r.size+int64(len(p)) > r.maxBytes() {
+		rotateErr = r.rotate()
+		if r.file == nil {
+			return 0, rotateErr
+		}
+	}
+
+	//when the rotation failed the line still goes into the file we have, the error says why it grows
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return n, err
+}
+
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *RotatingFile) maxBytes() int64 {
+	return int64(r.cfg.MaxSizeMB) * 1024 * 1024
+}
+
+// open appends to the existing file, a restart doesn't start a new file
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("can't open log file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	r.file, r.size = f, info.Size()
+	return nil
+}
+
+// rotate moves the file aside and opens a new one, when the move fails the same file is opened
+// again so logging goes on [r.file is only left nil when even that fails]
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	if err := os.Rename(r.cfg.Path, r.backupPath(time.Now().UTC())); err != nil {
+		if openErr := r.open(); openErr != nil {
+			return fmt.Errorf("rotating log file: %w, reopening it: %w", err, openErr)
+		}
+		return fmt.Errorf("rotating log file: %w", err)
+	}
+
+	if err := r.open(); err != nil {
+		return err
+	}
+
+	r.prune()
+	return nil
+}
+
+// backupPath is the name to rotate to at the given time, rename would replace a file rotated in the
+// same millisecond so the time moves on until the name is free [it stays a name prune can parse]
+func (r *RotatingFile) backupPath(at time.Time) string {
+	ext := filepath.Ext(r.cfg.Path)
+	base := strings.TrimSuffix(r.cfg.Path, ext)
+
+	for {
+		path := base + "-" + at.Format(rotateLayout) + ext
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path
+		}
+		at = at.Add(time.Millisecond)
+	}
+}
+
+// prune removes rotated files past max_backups or older than max_age, a failure here only
+// means a few files too many so it doesn't fail the write
+func (r *RotatingFile) prune() {
+	ext := filepath.Ext(r.cfg.Path)
+	base := strings.TrimSuffix(r.cfg.Path, ext)
+
+	matches, err := filepath.Glob(base + "-*" + ext)
+	if err != nil {
+		return
+	}
+
+	type rotated struct {
+		path string
+		at   time.Time
+	}
+	var files []rotated
+	for _, path := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(path, base+"-"), ext)
+		at, err := time.Parse(rotateLayout, stamp)
+		if err != nil {
+			continue //not one of ours
+		}
+		files = append(files, rotated{path, at})
+	}
+
+	//newest first, so everything past max_backups is the oldest
+	sort.Slice(files, func(i, j int) bool { return files[i].at.After(files[j].at) })
+
+	for i, f := range files {
+		tooMany := r.cfg.MaxBackups > 0 && i >= r.cfg.MaxBackups
+		tooOld := r.cfg.MaxAge > 0 && time.Since(f.at) > r.cfg.MaxAge
+		if tooMany || tooOld {
+			os.Remove(f.path)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shivakr07/students-api/internal/config"
+)
+
+func newRotatingFile(t *testing.T) (*RotatingFile, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "students-api.log")
+	r, err := NewRotatingFile(config.LogFile{Path: path, MaxSizeMB: 1, MaxBackups: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r, path
+}
+
+func backups(t *testing.T, path string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(strings.TrimSuffix(path, ".log") + "-*.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestRotateSameMillisecond(t *testing.T) {
+	r, path := newRotatingFile(t)
+
+	//rotations back to back, well within a millisecond of each other, each keeps its own backup
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+		r.mu.Lock()
+		err := r.rotate()
+		r.mu.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := backups(t, path)
+	if len(files) != 3 {
+		t.Fatalf("got backups %v, want 3", files)
+	}
+	var content []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content = append(content, string(data))
+	}
+	if got := strings.Join(content, ""); got != "first\nsecond\nthird\n" {
+		t.Errorf("backups hold %q", got)
+	}
+}
+
+func TestRotateFailure(t *testing.T) {
+	r, path := newRotatingFile(t)
+
+	if _, err := r.Write([]byte("before\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	//the file is gone, so it can't be moved aside
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	r.mu.Lock()
+	err := r.rotate()
+	r.mu.Unlock()
+	if err == nil {
+		t.Fatal("rotating a file that doesn't exist worked")
+	}
+
+	//the original path was opened again and logging goes on
+	if _, err := r.Write([]byte("after\n")); err != nil {
+		t.Fatalf("write after a failed rotation: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "after\n" {
+		t.Errorf("got %q", data)
+	}
+	if files := backups(t, path); len(files) != 0 {
+		t.Errorf("got backups %v", files)
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	r, _ := newRotatingFile(t)
+	r.Close()
+
+	if _, err := r.Write([]byte("late\n")); err != os.ErrClosed {
+		t.Errorf("got %v, want os.ErrClosed", err)
+	}
+}
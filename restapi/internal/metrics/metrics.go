@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// a very small metrics registry, enough to expose counters and gauges in the prometheus text format
+// without pulling a client library into the project
+
+type Counter struct {
+	value atomic.Int64
+}
+
+func (c *Counter) Inc()         { c.value.Add(1) }
+func (c *Counter) Add(n int64)  { c.value.Add(n) }
+func (c *Counter) Value() int64 { return c.value.Load() }
+
+// GaugeFunc is read when the metrics are scraped, handy for values that already live somewhere else
+type GaugeFunc func() float64
+
+type metric struct {
+	help    string
+	kind    string
+	counter *Counter
+	gauge   GaugeFunc
+}
+
+var (
+	mu       sync.Mutex
+	registry = map[string]metric{}
+)
+
+// NewCounter registers a counter, registering the same name twice returns the existing one
+func NewCounter(name string, help string) *Counter {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if m, ok := registry[name]; ok && m.counter != nil {
+		return m.counter
+	}
+
+	c := &Counter{}
+	registry[name] = metric{help: help, kind: "counter", counter: c}
+	return c
+}
+
+func NewGauge(name string, help string, fn GaugeFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	registry[name] = metric{help: help, kind: "gauge", gauge: fn}
+}
+
+// Handler writes every registered metric, sorted by name
+func Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		names := make([]string, 0, len(registry))
+		for name := range registry {
+			names = append(names, name)
+		}
+		snapshot := make(map[string]metric, len(registry))
+		for name, m := range registry {
+			snapshot[name] = m
+		}
+		mu.Unlock()
+
+		sort.Strings(names)
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for _, name := range names {
+			m := snapshot[name]
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, m.help, name, m.kind)
+			if m.counter != nil {
+				fmt.Fprintf(w, "%s %d\n", name, m.counter.Value())
+			} else {
+				fmt.Fprintf(w, "%s %g\n", name, m.gauge())
+			}
+		}
+	}
+}
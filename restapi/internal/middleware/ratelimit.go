@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/utils/response"
+)
+
+// clients that haven't been seen for this long are forgotten
+const idleClient = 10 * time.Minute
+
+// RateLimiter is a token bucket per client ip, its settings can be swapped at runtime with Update
+type RateLimiter struct {
+	mu        sync.Mutex
+	cfg       config.RateLimit
+	clients   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+func NewRateLimiter(cfg config.RateLimit) *RateLimiter {
+	return &RateLimiter{
+		cfg:       cfg,
+		clients:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Update applies new limits [config reload], existing buckets keep their tokens
+func (l *RateLimiter) Update(cfg config.RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cfg = cfg
+}
+
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, retryAfter := l.allow(clientIP(r), time.Now())
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			response.WriteJson(w, http.StatusTooManyRequests, response.GeneralError(i18n.New("request.rate_limited", "rate limit exceeded")))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allow takes a token from the client's bucket, when there is none it says how long until the next one
+func (l *RateLimiter) allow(ip string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.cfg.Enabled {
+		return true, 0
+	}
+
+	if now.Sub(l.lastSweep) > idleClient {
+		for key, b := range l.clients {
+			if now.Sub(b.lastSeen) > idleClient {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	burst := float64(l.cfg.Burst)
+
+	b, ok := l.clients[ip]
+	if !ok {
+		b = &bucket{tokens: burst, lastSeen: now}
+		l.clients[ip] = b
+	}
+
+	//refill for the time since the last request, never above the burst size
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.lastSeen).Seconds()*l.cfg.RequestsPerSecond)
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.cfg.RequestsPerSecond * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
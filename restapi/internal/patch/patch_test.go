@@ -0,0 +1,43 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyValue(t *testing.T) {
+	doc := `{"name":"Alan","date_of_birth":null,"tags":["a"]}`
+
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+		err   error
+	}{
+		{"add null", `[{"op":"add","path":"/email","value":null}]`, `{"date_of_birth":null,"email":null,"name":"Alan","tags":["a"]}`, nil},
+		{"replace with null", `[{"op":"replace","path":"/name","value":null}]`, `{"date_of_birth":null,"name":null,"tags":["a"]}`, nil},
+		{"test null", `[{"op":"test","path":"/date_of_birth","value":null}]`, `{"date_of_birth":null,"name":"Alan","tags":["a"]}`, nil},
+		{"test null fails", `[{"op":"test","path":"/name","value":null}]`, ``, ErrTestFailed},
+		{"add missing value", `[{"op":"add","path":"/email"}]`, ``, ErrInvalid},
+		{"test missing value", `[{"op":"test","path":"/date_of_birth"}]`, ``, ErrInvalid},
+		{"remove needs none", `[{"op":"remove","path":"/tags/0"}]`, `{"date_of_birth":null,"name":"Alan","tags":[]}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(doc), []byte(tt.patch))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package photo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/shivakr07/students-api/internal/blob"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// Cleanup wraps a storage.Storage so deleting a student also deletes its photo blobs
+// same decorator idea as the cache: every entry point that deletes students [rest, graphql...] gets it
+type Cleanup struct {
+	storage.Storage
+	blobs blob.Store
+}
+
+func WithCleanup(inner storage.Storage, blobs blob.Store) *Cleanup {
+	return &Cleanup{Storage: inner, blobs: blobs}
+}
+
+func (c *Cleanup) DeleteStudent(id int64) error {
+	keys := photoKeys(c.Storage, id)
+	if err := c.Storage.DeleteStudent(id); err != nil {
+		return err
+	}
+
+	c.deleteBlobs(keys)
+	return nil
+}
+
+// EraseStudent removes the photo blobs of an erased student, the rows are gone with the erasure itself
+// ExportStudent and GetErasure only pass through
+func (c *Cleanup) EraseStudent(id int64, reason string) (types.Erasure, error) {
+	inner, ok := c.Storage.(storage.PrivacyStorage)
+	if !ok {
+		return types.Erasure{}, fmt.Errorf("storage backend doesn't support erasure")
+	}
+
+	keys := photoKeys(c.Storage, id)
+	erasure, err := inner.EraseStudent(id, reason)
+	if err != nil {
+		return types.Erasure{}, err
+	}
+
+	c.deleteBlobs(keys)
+	return erasure, nil
+}
+
+func (c *Cleanup) ExportStudent(id int64) (types.StudentExport, error) {
+	inner, ok := c.Storage.(storage.PrivacyStorage)
+	if !ok {
+		return types.StudentExport{}, fmt.Errorf("storage backend doesn't support export")
+	}
+	return inner.ExportStudent(id)
+}
+
+func (c *Cleanup) GetErasure(studentId int64) (types.Erasure, error) {
+	inner, ok := c.Storage.(storage.PrivacyStorage)
+	if !ok {
+		return types.Erasure{}, fmt.Errorf("storage backend doesn't support erasure")
+	}
+	return inner.GetErasure(studentId)
+}
+
+// photoKeys looks the photo of a student up before the student goes [the row goes with them and the
+// keys have its etag in them], a failed lookup only leaves files behind so it is logged
+func photoKeys(s any, id int64) []string {
+	photos, ok := s.(storage.PhotoStorage)
+	if !ok {
+		return nil
+	}
+
+	meta, err := photos.GetStudentPhoto(id)
+	if err != nil {
+		if !errors.Is(err, storage.ErrPhotoNotFound) {
+			slog.Error("can't look up photo of deleted student", slog.String("id", fmt.Sprint(id)), slog.String("error", err.Error()))
+		}
+		return nil
+	}
+	return Keys(meta)
+}
+
+// the student is gone at this point, a blob we fail to remove is only wasted disk so it is logged
+func (c *Cleanup) deleteBlobs(keys []string) {
+	for _, key := range keys {
+		if err := c.blobs.Delete(context.Background(), key); err != nil {
+			slog.Error("can't delete photo of deleted student", slog.String("key", key), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// InTx deletes the photos of the students a transaction deleted, once it has committed
+func (c *Cleanup) InTx(fn func(tx storage.TxStorage) error) error {
+	inner, ok := c.Storage.(storage.Transactor)
+	if !ok {
+		return fmt.Errorf("storage backend doesn't support transactions")
+	}
+
+	tracked := &txCleanup{}
+	err := inner.InTx(func(tx storage.TxStorage) error {
+		tracked.TxStorage = tx
+		return fn(tracked)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.deleteBlobs(tracked.deleted)
+
+	return nil
+}
+
+// txCleanup collects the photo blobs of the students deleted in a transaction, minus those rolled back
+// by a savepoint
+type txCleanup struct {
+	storage.TxStorage
+	deleted []string
+}
+
+func (t *txCleanup) DeleteStudent(id int64) error {
+	keys := photoKeys(t.TxStorage, id)
+	if err := t.TxStorage.DeleteStudent(id); err != nil {
+		return err
+	}
+
+	t.deleted = append(t.deleted, keys...)
+	return nil
+}
+
+func (t *txCleanup) Savepoint(fn func() error) error {
+	n := len(t.deleted)
+
+	err := t.TxStorage.Savepoint(fn)
+	if err != nil {
+		t.deleted = t.deleted[:n]
+	}
+
+	return err
+}
@@ -0,0 +1,122 @@
+package photo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"slices"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// only formats the standard library can decode, we need the pixels for the thumbnail
+var allowed = []string{"image/jpeg", "image/png", "image/gif"}
+
+// anything bigger than this many pixels is refused before decoding [a tiny png can claim to be huge]
+const maxPixels = 40_000_000
+
+// Processed is an upload that passed the checks, ready to be stored
+type Processed struct {
+	ContentType string
+	Width       int
+	Height      int
+	ETag        string
+	Thumbnail   []byte // always a jpeg
+}
+
+// Process sniffs the real content type [the one the client sends means nothing], decodes the image
+// and renders a thumbnail that fits in a size x size square
+func Process(data []byte, size int) (Processed, error) {
+	contentType := mimetype.Detect(data).String()
+	if !slices.Contains(allowed, contentType) {
+		return Processed{}, fmt.Errorf("unsupported image type %s, use jpeg, png or gif", contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, fmt.Errorf("can't read image: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return Processed{}, fmt.Errorf("image is too large (%dx%d)", cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Processed{}, fmt.Errorf("can't read image: %w", err)
+	}
+
+	var thumb bytes.Buffer
+	if err := jpeg.Encode(&thumb, Thumbnail(img, size), &jpeg.Options{Quality: 85}); err != nil {
+		return Processed{}, err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return Processed{
+		ContentType: contentType,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+		ETag:        hex.EncodeToString(sum[:16]),
+		Thumbnail:   thumb.Bytes(),
+	}, nil
+}
+
+// Thumbnail scales img down to fit in size x size keeping its aspect ratio, every output pixel is
+// the average of the source pixels it covers and transparency is flattened onto white
+func Thumbnail(img image.Image, size int) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	//never scale up, small images only get flattened
+	scale := min(1, float64(size)/float64(max(w, h)))
+	tw, th := max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < max(y1, y0+1); sy++ {
+				for sx := x0; sx < max(x1, x0+1); sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+
+			//the values are premultiplied, so adding white for the transparent part is enough
+			white := 0xffff - a/n
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r/n + white) >> 8),
+				G: uint8((g/n + white) >> 8),
+				B: uint8((bl/n + white) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+
+	return dst
+}
+
+// blob keys of a student's photo and its thumbnail, the etag is in them so a new upload never
+// overwrites the files the stored row still points to
+func Key(studentId int64, etag string) string {
+	return fmt.Sprintf("students/%d/photo-%s", studentId, etag)
+}
+
+func ThumbKey(studentId int64, etag string) string {
+	return fmt.Sprintf("students/%d/photo-%s-thumb.jpg", studentId, etag)
+}
+
+// Keys are both blobs of a stored photo
+func Keys(meta types.Photo) []string {
+	return []string{Key(meta.StudentId, meta.ETag), ThumbKey(meta.StudentId, meta.ETag)}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/shivakr07/students-api/internal/api"
+	v1 "github.com/shivakr07/students-api/internal/api/v1"
+	v2 "github.com/shivakr07/students-api/internal/api/v2"
+	"github.com/shivakr07/students-api/internal/blob"
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/events"
+	"github.com/shivakr07/students-api/internal/handlers/batch"
+	"github.com/shivakr07/students-api/internal/handlers/course"
+	"github.com/shivakr07/students-api/internal/handlers/grades"
+	"github.com/shivakr07/students-api/internal/handlers/privacy"
+	"github.com/shivakr07/students-api/internal/handlers/student"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/storage/sqlite"
+	"github.com/shivakr07/students-api/internal/utils/request"
+)
+
+// apiDeps is what the routes of every api version are built from
+type apiDeps struct {
+	cfg      *config.Config
+	storage  *sqlite.Sqlite       // the backend itself, for everything that isn't a student
+	students storage.Storage      // students through the cache and photo cleanup decorators
+	stats    storage.StatsStorage // shared, so the versions share the ttl cache too
+	blobs    blob.Store
+	broker   *events.Broker
+	reader   request.Reader // json bodies up to http_server.max_body_bytes
+}
+
+// each version registers its routes on its own mux, without the /api/vN prefix
+var versions = map[string]func(mux *http.ServeMux, d apiDeps) error{
+	"1": registerV1,
+	"2": registerV2,
+}
+
+// registerV1 is the original api, a student has one name and an age
+func registerV1(mux *http.ServeMux, d apiDeps) error {
+	return registerResources(mux, d, v1.Students)
+}
+
+// registerV2 splits the name into first_name/last_name and takes a date_of_birth instead of an age
+func registerV2(mux *http.ServeMux, d apiDeps) error {
+	return registerResources(mux, d, v2.Students)
+}
+
+// registerResources registers what every version has, the json form of a student [codec] is the
+// only thing that differs between them so far
+func registerResources(mux *http.ServeMux, d apiDeps, codec api.StudentCodec) error {
+	cfg, storage, students, reader := d.cfg, d.storage, d.students, d.reader
+
+	mux.HandleFunc("POST /students", student.New(students, codec, reader))
+	mux.HandleFunc("GET /students/{id}", student.GetById(students, codec))
+	mux.HandleFunc("GET /students", student.GetList(students, codec))
+	//aggregates for dashboards, computed in sql and cached for stats.ttl [the literal path wins over {id}]
+	mux.HandleFunc("GET /students/stats", student.Stats(d.stats, codec, cfg.Stats))
+	mux.HandleFunc("DELETE /students/{id}", student.Delete(students))
+	//partial edits: merge patch or json patch, chosen by Content-Type
+	mux.HandleFunc("PATCH /students/{id}", student.Patch(students, codec, reader))
+
+	//many creates/updates/deletes in one transaction, through the same decorators as the single routes
+	batchHandler, err := batch.New(students, codec, reader)
+	if err != nil {
+		return err
+	}
+	mux.HandleFunc("POST /batch", batchHandler)
+
+	//profile photo, multipart upload [field "photo"], GET serves it with ?size=thumb for the thumbnail
+	mux.HandleFunc("PUT /students/{id}/photo", student.UploadPhoto(students, storage, d.blobs, cfg.Photos))
+	mux.HandleFunc("GET /students/{id}/photo", student.GetPhoto(storage, d.blobs))
+	mux.HandleFunc("DELETE /students/{id}/photo", student.DeletePhoto(storage, d.blobs))
+
+	//data subject requests [gdpr]: a copy of everything about a student, and erasure with a tombstone
+	eraseHandler, err := privacy.Erase(students, reader)
+	if err != nil {
+		return err
+	}
+	mux.HandleFunc("GET /students/{id}/export", privacy.Export(storage, d.blobs, codec))
+	mux.HandleFunc("POST /students/{id}/erase", eraseHandler)
+
+	//courses and enrollments [registrar], capacity is checked in the same transaction as the enrollment
+	mux.HandleFunc("POST /courses", course.New(storage, cfg.Grading, reader))
+	mux.HandleFunc("GET /courses", course.GetList(storage))
+	mux.HandleFunc("GET /courses/{id}", course.GetById(storage))
+	mux.HandleFunc("POST /courses/{id}/enrollments", course.Enroll(storage, reader))
+	mux.HandleFunc("GET /courses/{id}/enrollments", course.GetEnrollments(storage))
+	mux.HandleFunc("DELETE /courses/{id}/enrollments/{studentId}", course.Drop(storage))
+	mux.HandleFunc("GET /students/{id}/courses", course.GetStudentCourses(storage))
+
+	//weighted assessments per course, the transcript turns the scores into per term and cumulative gpa
+	mux.HandleFunc("POST /courses/{id}/assessments", grades.NewAssessment(storage, reader))
+	mux.HandleFunc("GET /courses/{id}/assessments", grades.GetAssessments(storage))
+	mux.HandleFunc("PUT /assessments/{id}/scores/{studentId}", grades.RecordScore(storage, reader))
+	mux.HandleFunc("GET /students/{id}/transcript", grades.Transcript(students, storage, cfg.Grading))
+
+	//live change feed [server-sent events], the broker tails the outbox
+	mux.HandleFunc("GET /students/stream", student.Stream(storage, d.broker, cfg.Stream.Heartbeat))
+
+	return nil
+}
+
+// mountAPI puts every version under /api/vN/, and /api/... [no version] goes by the API-Version
+// header with v1 as the default so the clients from before versioning keep working
+// versions listed in api.deprecations get Deprecation and Sunset headers on every response
+func mountAPI(router *http.ServeMux, d apiDeps) error {
+	handlers := map[string]http.Handler{}
+	for version, register := range versions {
+		mux := http.NewServeMux()
+		if err := register(mux, d); err != nil {
+			return err
+		}
+
+		var handler http.Handler = mux
+		if deprecation, ok := d.cfg.API.Deprecations[version]; ok {
+			handler = api.Deprecate(deprecation, handler)
+		}
+		handlers[version] = handler
+
+		prefix := "/api/v" + version
+		router.Handle(prefix+"/", http.StripPrefix(prefix, handler))
+	}
+
+	router.Handle("/api/", http.StripPrefix("/api", api.ByHeader(handlers, "1")))
+	return nil
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/shivakr07/students-api/internal/auth"
+	"github.com/shivakr07/students-api/internal/backup"
+	"github.com/shivakr07/students-api/internal/blob"
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/events"
+	"github.com/shivakr07/students-api/internal/handlers/backups"
+	"github.com/shivakr07/students-api/internal/handlers/graph"
+	"github.com/shivakr07/students-api/internal/handlers/health"
+	"github.com/shivakr07/students-api/internal/handlers/logs"
+	"github.com/shivakr07/students-api/internal/handlers/webhooks"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/logging"
+	"github.com/shivakr07/students-api/internal/middleware"
+	"github.com/shivakr07/students-api/internal/photo"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/storage/cache"
+	"github.com/shivakr07/students-api/internal/storage/sqlite"
+	"github.com/shivakr07/students-api/internal/types"
+	"github.com/shivakr07/students-api/internal/utils/request"
+)
+
+// App is every route with its middleware [the public ones, and the admin api the admin listener
+// mounts], plus the parts serve has to start, stop or reload [the tests boot the same thing, see the apitest package]
+type App struct {
+	Handler http.Handler
+	Admin   http.Handler        //the /admin api, mounted on the admin listener
+	Users   storage.UserStorage //nil unless auth is enabled, admin users can use the admin listener too
+	Health  http.HandlerFunc    //also served on the admin listener
+	Broker  *events.Broker      //has to run for the stream routes to get events
+	Backups *backup.Manager
+	Limiter *middleware.RateLimiter //rate_limit is reloadable
+}
+
+// New wires every route on top of the storage, it doesn't start anything
+func New(cfg *config.Config, storage *sqlite.Sqlite, logger *logging.Logger) (*App, error) {
+	//we will use net/http inbuilt package
+	router := http.NewServeMux()
+	//every json body is read through the request package, this is the most it will read
+	reader := request.NewReader(cfg.HTTPServer.MaxBodyBytes)
+
+	//error messages in the client's language [Accept-Language], built-in catalogs plus i18n.dir
+	//the catalog's middleware hands it to response.WriteJson with the response writer
+	catalog, err := i18n.Load(cfg.I18n)
+	if err != nil {
+		return nil, err
+	}
+	//now we can make url's
+	// router.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+	// 	w.Write([]byte("Welcome to students api"))
+	// 	//in write method we can add bytes
+	// })
+
+	//since now we will use reference of that function here which is defined in the student.go using New
+	//we keep resources as plural in the REST
+	// router.HandleFunc("POST /api/students", student.New())
+
+	//we want to use the database in the new function now so we need to receive this as a dependency [in the func definition]
+	//student reads/writes go through the cache when it is enabled in config [decorator over the same interface]
+	//photos live in a blob store, deleting a student through any route removes them too
+	blobs, err := blob.NewFS(cfg.Photos.Dir)
+	if err != nil {
+		return nil, err
+	}
+	students := cache.Wrap(photo.WithCleanup(storage, blobs), cfg.Cache)
+
+	//live change feed [server-sent events], the broker tails the outbox
+	broker := events.NewBroker(storage, cfg.Stream.PollInterval, cfg.Stream.BufferSize)
+
+	//the public api, one set of routes per version [see routes.go]
+	err = mountAPI(router, apiDeps{
+		cfg:      cfg,
+		storage:  storage,
+		students: students,
+		stats:    cache.WrapStats(storage, cfg.Stats.TTL),
+		blobs:    blobs,
+		broker:   broker,
+		reader:   reader,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	//graphql endpoint sits on the same storage, so dashboards can fetch a whole view in one round-trip
+	graphqlHandler, err := graph.New(students, cfg.GraphQL, reader)
+	if err != nil {
+		return nil, err
+	}
+	router.HandleFunc("POST /graphql", graphqlHandler)
+	router.HandleFunc("GET /graphql", graphqlHandler)
+
+	//the admin api is only served on the admin listener [admin.address, behind admin.token]
+	admin := http.NewServeMux()
+
+	//webhook subscriptions, downstream systems get student events pushed instead of polling
+	admin.HandleFunc("POST /admin/webhooks", webhooks.New(storage, reader))
+	admin.HandleFunc("GET /admin/webhooks", webhooks.GetList(storage))
+	admin.HandleFunc("DELETE /admin/webhooks/{id}", webhooks.Delete(storage))
+	admin.HandleFunc("GET /admin/webhooks/deliveries", webhooks.GetDeliveries(storage))
+	admin.HandleFunc("POST /admin/webhooks/deliveries/{id}/redeliver", webhooks.Redeliver(storage))
+
+	//consistent snapshots of the sqlite file [online backup api, safe while we keep serving]
+	backupManager := backup.New(storage, cfg.Backup)
+	admin.HandleFunc("POST /admin/backups", backups.New(backupManager))
+	admin.HandleFunc("GET /admin/backups", backups.GetList(backupManager))
+
+	//log level at runtime, {"level": "debug"}
+	admin.HandleFunc("GET /admin/log/level", logs.GetLevel(logger))
+	admin.HandleFunc("PUT /admin/log/level", logs.SetLevel(logger, reader))
+
+	//for load balancers, 503 once the database stops answering
+	healthHandler := health.New(storage.Db)
+	router.HandleFunc("GET /healthz", healthHandler)
+
+	//every request passes the per client rate limit first [a no-op unless enabled in config]
+	limiter := middleware.NewRateLimiter(cfg.RateLimit)
+
+	//then who is calling, after the rate limit so guessing credentials is limited too
+	var handler http.Handler = router
+	if cfg.Auth.Enabled {
+		handler = auth.Require(storage, roles, router)
+	}
+
+	app := &App{
+		Handler: catalog.Middleware(limiter.Middleware(handler)),
+		Admin:   catalog.Middleware(admin),
+		Health:  healthHandler,
+		Broker:  broker,
+		Backups: backupManager,
+		Limiter: limiter,
+	}
+	if cfg.Auth.Enabled {
+		app.Users = storage
+	}
+
+	return app, nil
+}
+
+// roles are who may call a route when auth is enabled, nil is public
+// [the admin listener checks its own callers, see the admin package]
+func roles(r *http.Request) []string {
+	if r.URL.Path == "/healthz" {
+		return nil
+	}
+	return []string{types.RoleStaff, types.RoleAdmin}
+}
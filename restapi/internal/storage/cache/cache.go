@@ -0,0 +1,237 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/metrics"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"golang.org/x/sync/singleflight"
+)
+
+// Cache wraps any storage.Storage and keeps recently read students in memory
+// it is a decorator: it implements the same interface, so handlers don't know it's there
+//
+// reads by id go through a bounded LRU with a TTL, concurrent misses for the same id share one
+// backend call, and every mutation drops the entries it touched
+type Cache struct {
+	storage.Storage
+
+	size int
+	ttl  time.Duration
+
+	mu         sync.Mutex
+	entries    map[int64]*list.Element
+	order      *list.List // front is the most recently used
+	generation uint64     // bumped on every invalidation, see load
+
+	group singleflight.Group
+}
+
+type entry struct {
+	student   types.Student
+	expiresAt time.Time
+}
+
+var (
+	hits      = metrics.NewCounter("students_cache_hits_total", "student lookups answered from the cache")
+	misses    = metrics.NewCounter("students_cache_misses_total", "student lookups that went to the storage backend")
+	evictions = metrics.NewCounter("students_cache_evictions_total", "entries removed because the cache was full")
+)
+
+func New(inner storage.Storage, cfg config.Cache) *Cache {
+	c := &Cache{
+		Storage: inner,
+		size:    cfg.Size,
+		ttl:     cfg.TTL,
+		entries: map[int64]*list.Element{},
+		order:   list.New(),
+	}
+
+	metrics.NewGauge("students_cache_entries", "students currently held in the cache", func() float64 {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return float64(c.order.Len())
+	})
+
+	return c
+}
+
+func (c *Cache) GetStudentById(id int64) (types.Student, error) {
+	if student, ok := c.get(id); ok {
+		hits.Inc()
+		return student, nil
+	}
+	misses.Inc()
+
+	//only one goroutine hits the backend for a given id, the others wait for its result
+	v, err, _ := c.group.Do(strconv.FormatInt(id, 10), func() (any, error) {
+		return c.load(id)
+	})
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	return v.(types.Student), nil
+}
+
+func (c *Cache) UpdateStudent(id int64, student types.Student) error {
+	//invalidate even when the update fails, we can't be sure what the backend did
+	defer c.invalidate(id)
+	return c.Storage.UpdateStudent(id, student)
+}
+
+func (c *Cache) DeleteStudent(id int64) error {
+	defer c.invalidate(id)
+	return c.Storage.DeleteStudent(id)
+}
+
+// load reads from the backend and stores the result, unless something was invalidated while we were
+// reading: then the value might already be stale so it is returned but not cached
+func (c *Cache) load(id int64) (types.Student, error) {
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	student, err := c.Storage.GetStudentById(id)
+	if err != nil {
+		//errors (not found included) are never cached
+		return types.Student{}, err
+	}
+
+	c.mu.Lock()
+	if c.generation == generation {
+		c.put(student)
+	}
+	c.mu.Unlock()
+
+	return student, nil
+}
+
+func (c *Cache) get(id int64) (types.Student, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[id]
+	if !ok {
+		return types.Student{}, false
+	}
+
+	e := el.Value.(*entry)
+	if time.Now().After(e.expiresAt) {
+		c.order.Remove(el)
+		delete(c.entries, id)
+		return types.Student{}, false
+	}
+
+	c.order.MoveToFront(el)
+	return e.student, true
+}
+
+// put must be called with mu held
+func (c *Cache) put(student types.Student) {
+	if el, ok := c.entries[student.Id]; ok {
+		el.Value = &entry{student: student, expiresAt: time.Now().Add(c.ttl)}
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[student.Id] = c.order.PushFront(&entry{student: student, expiresAt: time.Now().Add(c.ttl)})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).student.Id)
+		evictions.Inc()
+	}
+}
+
+func (c *Cache) invalidate(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if el, ok := c.entries[id]; ok {
+		c.order.Remove(el)
+		delete(c.entries, id)
+	}
+}
+
+// Wrap returns inner behind a cache when caching is enabled in the config, and inner itself otherwise
+func Wrap(inner storage.Storage, cfg config.Cache) storage.Storage {
+	if !cfg.Enabled || cfg.Size <= 0 {
+		return inner
+	}
+
+	return New(inner, cfg)
+}
+
+// InTx passes transactions through to the backend, every student the transaction updated or deleted
+// is dropped from the cache afterwards [committed or not, same as UpdateStudent]
+func (c *Cache) InTx(fn func(tx storage.TxStorage) error) error {
+	inner, ok := c.Storage.(storage.Transactor)
+	if !ok {
+		return fmt.Errorf("storage backend doesn't support transactions")
+	}
+
+	tracked := &txCache{}
+	defer func() {
+		for _, id := range tracked.touched {
+			c.invalidate(id)
+		}
+	}()
+
+	return inner.InTx(func(tx storage.TxStorage) error {
+		tracked.TxStorage = tx
+		return fn(tracked)
+	})
+}
+
+// EraseStudent, ExportStudent and GetErasure pass the privacy requests through to the backend,
+// an erased student is dropped from the cache like a deleted one
+func (c *Cache) EraseStudent(id int64, reason string) (types.Erasure, error) {
+	inner, ok := c.Storage.(storage.PrivacyStorage)
+	if !ok {
+		return types.Erasure{}, fmt.Errorf("storage backend doesn't support erasure")
+	}
+
+	defer c.invalidate(id)
+	return inner.EraseStudent(id, reason)
+}
+
+func (c *Cache) ExportStudent(id int64) (types.StudentExport, error) {
+	inner, ok := c.Storage.(storage.PrivacyStorage)
+	if !ok {
+		return types.StudentExport{}, fmt.Errorf("storage backend doesn't support export")
+	}
+	return inner.ExportStudent(id)
+}
+
+func (c *Cache) GetErasure(studentId int64) (types.Erasure, error) {
+	inner, ok := c.Storage.(storage.PrivacyStorage)
+	if !ok {
+		return types.Erasure{}, fmt.Errorf("storage backend doesn't support erasure")
+	}
+	return inner.GetErasure(studentId)
+}
+
+// txCache only remembers which students a transaction changed
+type txCache struct {
+	storage.TxStorage
+	touched []int64
+}
+
+func (t *txCache) UpdateStudent(id int64, student types.Student) error {
+	t.touched = append(t.touched, id)
+	return t.TxStorage.UpdateStudent(id, student)
+}
+
+func (t *txCache) DeleteStudent(id int64) error {
+	t.touched = append(t.touched, id)
+	return t.TxStorage.DeleteStudent(id)
+}
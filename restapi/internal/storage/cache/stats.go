@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/metrics"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+	"golang.org/x/sync/singleflight"
+)
+
+// Stats keeps aggregate results for a short ttl, dashboards poll the same few queries over and over
+// there is no invalidation: a result can be up to ttl old, which is fine for counts and histograms
+type Stats struct {
+	inner storage.StatsStorage
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	results map[string]statsEntry
+
+	group singleflight.Group
+}
+
+type statsEntry struct {
+	stats     types.StudentStats
+	expiresAt time.Time
+}
+
+var (
+	statsHits   = metrics.NewCounter("students_stats_cache_hits_total", "stats requests answered from the cache")
+	statsMisses = metrics.NewCounter("students_stats_cache_misses_total", "stats requests computed by the storage backend")
+)
+
+// the filter is part of the key, so the number of distinct queries is unbounded
+const maxStatsEntries = 256
+
+// WrapStats returns inner itself when the ttl is 0, same as Wrap does for a disabled cache
+func WrapStats(inner storage.StatsStorage, ttl time.Duration) storage.StatsStorage {
+	if ttl <= 0 {
+		return inner
+	}
+	return &Stats{inner: inner, ttl: ttl, results: map[string]statsEntry{}}
+}
+
+func (c *Stats) StudentStats(query types.StatsQuery) (types.StudentStats, error) {
+	//the ast is plain structs, so %#v prints the same for the same filter
+	key := fmt.Sprintf("%v|%s|%#v", query.AgeBuckets, query.GroupBy, query.Filter)
+
+	c.mu.Lock()
+	e, ok := c.results[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(e.expiresAt) {
+		statsHits.Inc()
+		return e.stats, nil
+	}
+	statsMisses.Inc()
+
+	v, err, _ := c.group.Do(key, func() (any, error) {
+		stats, err := c.inner.StudentStats(query)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		now := time.Now()
+		if len(c.results) >= maxStatsEntries {
+			for k, e := range c.results {
+				if now.After(e.expiresAt) {
+					delete(c.results, k)
+				}
+			}
+			//still full of live entries, start over rather than track usage
+			if len(c.results) >= maxStatsEntries {
+				clear(c.results)
+			}
+		}
+		c.results[key] = statsEntry{stats: stats, expiresAt: now.Add(c.ttl)}
+		return stats, nil
+	})
+	if err != nil {
+		return types.StudentStats{}, err
+	}
+
+	return v.(types.StudentStats), nil
+}
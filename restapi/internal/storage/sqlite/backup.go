@@ -0,0 +1,133 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// pages copied per backup step, between steps the source lock is released so writers can go on
+const backupPagesPerStep = 256
+
+// Backup writes a consistent copy of the live database to dest using sqlite's online backup api
+// unlike copying the file this is safe while the server is writing, dest must not exist yet
+func (s *Sqlite) Backup(ctx context.Context, dest string) error {
+	return copyDatabase(ctx, s.Db, dest, false)
+}
+
+// Restore replaces the contents of the database at storagePath with the backup at src
+// the backup is checked with integrity_check first so a damaged file never overwrites good data
+func Restore(ctx context.Context, src string, storagePath string) error {
+	if err := CheckIntegrity(src); err != nil {
+		return err
+	}
+
+	srcDb, err := sql.Open("sqlite3", "file:"+src+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer srcDb.Close()
+
+	return copyDatabase(ctx, srcDb, storagePath, true)
+}
+
+// CheckIntegrity runs PRAGMA integrity_check on the database file at path
+func CheckIntegrity(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("integrity check of %s: %w", path, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity check of %s failed: %s", path, result)
+	}
+
+	return nil
+}
+
+// copyDatabase runs the backup api from srcDb into the file at dest
+// overwrite is false for snapshots (never clobber an older backup) and true for restores
+func copyDatabase(ctx context.Context, srcDb *sql.DB, dest string, overwrite bool) error {
+	if !overwrite {
+		if _, err := os.Stat(dest); err == nil {
+			return fmt.Errorf("backup destination %s already exists", dest)
+		}
+	}
+
+	destDb, err := sql.Open("sqlite3", dest)
+	if err != nil {
+		return err
+	}
+	defer destDb.Close()
+
+	srcConn, err := srcDb.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	destConn, err := destDb.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	//database/sql hides the driver connection, Raw gives us the go-sqlite3 one that has Backup on it
+	err = destConn.Raw(func(destRaw any) error {
+		return srcConn.Raw(func(srcRaw any) error {
+			dst, ok := destRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", destRaw)
+			}
+			src, ok := srcRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", srcRaw)
+			}
+
+			backup, err := dst.Backup("main", src, "main")
+			if err != nil {
+				return err
+			}
+
+			for {
+				done, err := backup.Step(backupPagesPerStep)
+				if err != nil {
+					backup.Finish()
+					return err
+				}
+				if done {
+					break
+				}
+
+				select {
+				case <-ctx.Done():
+					backup.Finish()
+					return ctx.Err()
+				case <-time.After(5 * time.Millisecond):
+				}
+			}
+
+			return backup.Finish()
+		})
+	})
+	if err != nil || overwrite {
+		return err
+	}
+
+	//the copy inherits WAL mode from the live db, switch it back so the backup is one self-contained file
+	_, err = destConn.ExecContext(ctx, "PRAGMA journal_mode=DELETE")
+	return err
+}
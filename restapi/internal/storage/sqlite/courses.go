@@ -0,0 +1,215 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// the enrolled count only includes active enrollments, dropped ones free their seat
+const selectCourse = `SELECT c.id, c.code, c.title, c.term, c.credits, c.scale, c.capacity, c.created_at,
+	(SELECT COUNT(*) FROM enrollments e WHERE e.course_id = c.id AND e.status = 'enrolled')
+	FROM courses c`
+
+func (s *Sqlite) CreateCourse(code string, title string, term string, credits float64, scale string, capacity int) (int64, error) {
+	result, err := s.Db.Exec("INSERT INTO courses (code, title, term, credits, scale, capacity, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		code, title, term, credits, scale, capacity, time.Now().UTC())
+	//the code is the only unique column
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return 0, storage.ErrCourseCodeTaken
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (s *Sqlite) GetCourseById(id int64) (types.Course, error) {
+	return getCourse(s.Db.QueryRow(selectCourse+" WHERE c.id = ?", id), id)
+}
+
+func (s *Sqlite) GetCourses() ([]types.Course, error) {
+	rows, err := s.Db.Query(selectCourse + " ORDER BY c.id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	courses := []types.Course{}
+	for rows.Next() {
+		var course types.Course
+
+		if err := rows.Scan(&course.Id, &course.Code, &course.Title, &course.Term, &course.Credits, &course.Scale, &course.Capacity, &course.CreatedAt, &course.Enrolled); err != nil {
+			return nil, err
+		}
+
+		courses = append(courses, course)
+	}
+
+	return courses, rows.Err()
+}
+
+// Enroll runs the capacity check and the insert in one transaction, the dsn opens transactions
+// with BEGIN IMMEDIATE so two requests can't both see the last free seat
+func (s *Sqlite) Enroll(courseId int64, studentId int64) (types.Enrollment, error) {
+	var enrollment types.Enrollment
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		course, err := getCourse(tx.QueryRow(selectCourse+" WHERE c.id = ?", courseId), courseId)
+		if err != nil {
+			return err
+		}
+
+		var exists int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM students WHERE id = ?", studentId).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			return i18n.Errorf("storage.student_not_found", "no student found with id %d", studentId)
+		}
+
+		var status string
+		err = tx.QueryRow("SELECT status FROM enrollments WHERE course_id = ? AND student_id = ?", courseId, studentId).Scan(&status)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if status == types.EnrollmentActive {
+			return storage.ErrAlreadyEnrolled
+		}
+
+		if course.Enrolled >= course.Capacity {
+			return storage.ErrCourseFull
+		}
+
+		//a dropped enrollment comes back to life instead of failing on the unique key
+		now := time.Now().UTC()
+		_, err = tx.Exec(`INSERT INTO enrollments (student_id, course_id, status, enrolled_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT (student_id, course_id) DO UPDATE SET status = excluded.status, enrolled_at = excluded.enrolled_at, dropped_at = NULL`,
+			studentId, courseId, types.EnrollmentActive, now)
+		if err != nil {
+			return err
+		}
+
+		return tx.QueryRow("SELECT id, student_id, course_id, status, enrolled_at FROM enrollments WHERE course_id = ? AND student_id = ?", courseId, studentId).
+			Scan(&enrollment.Id, &enrollment.StudentId, &enrollment.CourseId, &enrollment.Status, &enrollment.EnrolledAt)
+	})
+
+	return enrollment, err
+}
+
+func (s *Sqlite) DropEnrollment(courseId int64, studentId int64) error {
+	result, err := s.Db.Exec("UPDATE enrollments SET status = ?, dropped_at = ? WHERE course_id = ? AND student_id = ? AND status = ?",
+		types.EnrollmentDropped, time.Now().UTC(), courseId, studentId, types.EnrollmentActive)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return storage.ErrNotEnrolled
+	}
+
+	return nil
+}
+
+func (s *Sqlite) GetCourseEnrollments(courseId int64) ([]types.Enrollment, error) {
+	if _, err := s.GetCourseById(courseId); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Db.Query(`SELECT id, student_id, course_id, status, enrolled_at, dropped_at
+		FROM enrollments WHERE course_id = ? ORDER BY id`, courseId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	enrollments := []types.Enrollment{}
+	for rows.Next() {
+		enrollment, err := scanEnrollment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		enrollments = append(enrollments, enrollment)
+	}
+
+	return enrollments, rows.Err()
+}
+
+func (s *Sqlite) GetStudentCourses(studentId int64) ([]types.Enrollment, error) {
+	if _, err := s.GetStudentById(studentId); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Db.Query(`SELECT e.id, e.student_id, e.course_id, e.status, e.enrolled_at, e.dropped_at,
+		c.code, c.title, c.term, c.credits, c.scale, c.capacity, c.created_at,
+		(SELECT COUNT(*) FROM enrollments o WHERE o.course_id = c.id AND o.status = 'enrolled')
+		FROM enrollments e JOIN courses c ON c.id = e.course_id
+		WHERE e.student_id = ? ORDER BY e.id`, studentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	enrollments := []types.Enrollment{}
+	for rows.Next() {
+		var enrollment types.Enrollment
+		var course types.Course
+		var droppedAt sql.NullTime
+
+		err := rows.Scan(&enrollment.Id, &enrollment.StudentId, &enrollment.CourseId, &enrollment.Status, &enrollment.EnrolledAt, &droppedAt,
+			&course.Code, &course.Title, &course.Term, &course.Credits, &course.Scale, &course.Capacity, &course.CreatedAt, &course.Enrolled)
+		if err != nil {
+			return nil, err
+		}
+		if droppedAt.Valid {
+			enrollment.DroppedAt = &droppedAt.Time
+		}
+
+		course.Id = enrollment.CourseId
+		enrollment.Course = &course
+		enrollments = append(enrollments, enrollment)
+	}
+
+	return enrollments, rows.Err()
+}
+
+func getCourse(row *sql.Row, id int64) (types.Course, error) {
+	var course types.Course
+
+	err := row.Scan(&course.Id, &course.Code, &course.Title, &course.Term, &course.Credits, &course.Scale, &course.Capacity, &course.CreatedAt, &course.Enrolled)
+	if err == sql.ErrNoRows {
+		return types.Course{}, i18n.Errorf("storage.course_not_found", "no course found with id %d", id)
+	}
+	if err != nil {
+		return types.Course{}, fmt.Errorf("qeury error : %w", err)
+	}
+
+	return course, nil
+}
+
+func scanEnrollment(rows *sql.Rows) (types.Enrollment, error) {
+	var enrollment types.Enrollment
+	var droppedAt sql.NullTime
+
+	if err := rows.Scan(&enrollment.Id, &enrollment.StudentId, &enrollment.CourseId, &enrollment.Status, &enrollment.EnrolledAt, &droppedAt); err != nil {
+		return types.Enrollment{}, err
+	}
+	if droppedAt.Valid {
+		enrollment.DroppedAt = &droppedAt.Time
+	}
+
+	return enrollment, nil
+}
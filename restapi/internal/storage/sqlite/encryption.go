@@ -0,0 +1,265 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shivakr07/students-api/internal/fieldcrypt"
+	"github.com/shivakr07/students-api/internal/storage"
+)
+
+// field level encryption: the columns in encryption.fields are stored sealed [see fieldcrypt], everything
+// else about the table stays the same so the rest of the package only sees plain students
+// the email also gets a blind index [email_index], that is what lookups and the uniqueness check use
+
+// the additional data of the outbox payload, a payload has the whole student in it so it's sealed too
+const payloadColumn = "outbox.payload"
+
+var errNoKeys = errors.New("the database has encrypted values but no encryption keys are configured")
+
+// sealColumn encrypts a students column when it's one of encryption.fields, empty values stay empty
+func (s *Sqlite) sealColumn(column string, value string) (string, error) {
+	if !s.sealed[column] || value == "" {
+		return value, nil
+	}
+	return s.ring.Seal("students."+column, value)
+}
+
+// openColumn decrypts a students column, rows written before the column was encrypted are plain
+func (s *Sqlite) openColumn(column string, value string) (string, error) {
+	if !fieldcrypt.IsSealed(value) {
+		return value, nil
+	}
+	if s.ring == nil {
+		return "", errNoKeys
+	}
+	return s.ring.Open("students."+column, value)
+}
+
+// studentValues returns name, first_name, last_name, email as they are written, and the email_index
+func (s *Sqlite) studentValues(name, firstName, lastName, email string) ([]any, any, error) {
+	values := make([]any, 0, 4)
+	for _, column := range []struct{ name, value string }{
+		{"name", name}, {"first_name", firstName}, {"last_name", lastName}, {"email", email},
+	} {
+		sealed, err := s.sealColumn(column.name, column.value)
+		if err != nil {
+			return nil, nil, err
+		}
+		values = append(values, sealed)
+	}
+
+	return values, indexValue(s.ring.BlindIndex(email)), nil
+}
+
+// indexValue is what an email_index is stored as, NULL when there is no index key
+func indexValue(index string) any {
+	if index == "" {
+		return nil
+	}
+	return index
+}
+
+// emailCondition matches one email, through the blind index for encrypted rows and case insensitive
+// on the plain ones [rows not re-encrypted yet]
+func (s *Sqlite) emailCondition(email string) (string, []any) {
+	if index := s.ring.BlindIndex(email); index != "" {
+		//IS and not =, so a row without an index is false and not NULL [matters under a NOT]
+		return "(email_index IS ? OR lower(email) = lower(?))", []any{index, email}
+	}
+	return "lower(email) = lower(?)", []any{email}
+}
+
+// checkEmail fails with storage.ErrEmailTaken when another student than id already has the email
+func (s *Sqlite) checkEmail(tx *sql.Tx, id int64, email string) error {
+	condition, args := s.emailCondition(email)
+
+	var other int64
+	err := tx.QueryRow("SELECT id FROM students WHERE id <> ? AND "+condition+" LIMIT 1", append([]any{id}, args...)...).Scan(&other)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return storage.ErrEmailTaken
+}
+
+// sealPayload and openPayload do the same for the outbox, sealed as soon as any student field is
+func (s *Sqlite) sealPayload(payload string) (string, error) {
+	if len(s.sealed) == 0 {
+		return payload, nil
+	}
+	return s.ring.Seal(payloadColumn, payload)
+}
+
+func (s *Sqlite) openPayload(payload string) (string, error) {
+	if !fieldcrypt.IsSealed(payload) {
+		return payload, nil
+	}
+	if s.ring == nil {
+		return "", errNoKeys
+	}
+	return s.ring.Open(payloadColumn, payload)
+}
+
+// reencrypt brings one stored value in line with the config: sealed with the active key when its
+// column is encrypted, plain when it isn't [the column was taken out of encryption.fields]
+func (s *Sqlite) reencrypt(column string, value string, seal bool) (string, error) {
+	switch {
+	case fieldcrypt.IsSealed(value) && seal:
+		return s.ring.Rotate(value)
+	case fieldcrypt.IsSealed(value):
+		return s.ring.Open(column, value)
+	case seal && value != "":
+		return s.ring.Seal(column, value)
+	default:
+		return value, nil
+	}
+}
+
+// Reencrypt rewrites up to limit students and limit outbox events that aren't stored the way the
+// config says: plain values of encrypted fields, values sealed with an older key, sealed values of
+// fields no longer encrypted and emails without a blind index
+// it returns how many rows it rewrote, 0 means everything is up to date
+func (s *Sqlite) Reencrypt(limit int) (int, error) {
+	if s.ring == nil {
+		return 0, fmt.Errorf("encryption isn't configured, there are no keys to re-encrypt with")
+	}
+
+	var done int
+	err := s.withTx(func(tx *sql.Tx) error {
+		students, err := s.reencryptStudents(tx, limit)
+		if err != nil {
+			return err
+		}
+		events, err := s.reencryptOutbox(tx, limit)
+		if err != nil {
+			return err
+		}
+		done = students + events
+		return nil
+	})
+
+	return done, err
+}
+
+var sealableColumns = []string{"name", "first_name", "last_name", "email"}
+
+func (s *Sqlite) reencryptStudents(tx *sql.Tx, limit int) (int, error) {
+	active := s.ring.ActivePrefix() + "*"
+
+	var needs []string
+	var args []any
+	for _, column := range sealableColumns {
+		//only column names from the list above are written into the sql
+		if s.sealed[column] {
+			needs = append(needs, "("+column+" <> '' AND "+column+" NOT GLOB ?)")
+			args = append(args, active)
+		} else {
+			needs = append(needs, column+" GLOB 'enc:*'")
+		}
+	}
+	if s.ring.Indexed() {
+		needs = append(needs, "(email <> '' AND email_index IS NULL)")
+	} else {
+		needs = append(needs, "email_index IS NOT NULL")
+	}
+
+	rows, err := tx.Query("SELECT id, name, first_name, last_name, email FROM students WHERE "+
+		strings.Join(needs, " OR ")+" ORDER BY id LIMIT ?", append(args, limit)...)
+	if err != nil {
+		return 0, err
+	}
+
+	type row struct {
+		id     int64
+		values [4]string
+	}
+	var pending []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.values[0], &r.values[1], &r.values[2], &r.values[3]); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, r := range pending {
+		values := make([]any, 0, 6)
+		for i, column := range sealableColumns {
+			value, err := s.reencrypt("students."+column, r.values[i], s.sealed[column])
+			if err != nil {
+				return 0, fmt.Errorf("student %d, %s: %w", r.id, column, err)
+			}
+			values = append(values, value)
+		}
+
+		email, err := s.openColumn("email", r.values[3])
+		if err != nil {
+			return 0, fmt.Errorf("student %d, email: %w", r.id, err)
+		}
+		values = append(values, indexValue(s.ring.BlindIndex(email)), r.id)
+
+		//no outbox event, the student itself didn't change
+		_, err = tx.Exec("UPDATE students SET name = ?, first_name = ?, last_name = ?, email = ?, email_index = ? WHERE id = ?", values...)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(pending), nil
+}
+
+func (s *Sqlite) reencryptOutbox(tx *sql.Tx, limit int) (int, error) {
+	seal := len(s.sealed) > 0
+
+	query := "SELECT id, payload FROM outbox WHERE payload GLOB 'enc:*' ORDER BY id LIMIT ?"
+	args := []any{limit}
+	if seal {
+		query = "SELECT id, payload FROM outbox WHERE payload NOT GLOB ? ORDER BY id LIMIT ?"
+		args = []any{s.ring.ActivePrefix() + "*", limit}
+	}
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	type row struct {
+		id      int64
+		payload string
+	}
+	var pending []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.payload); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, r := range pending {
+		payload, err := s.reencrypt(payloadColumn, r.payload, seal)
+		if err != nil {
+			return 0, fmt.Errorf("outbox event %d: %w", r.id, err)
+		}
+		if _, err := tx.Exec("UPDATE outbox SET payload = ? WHERE id = ?", payload, r.id); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(pending), nil
+}
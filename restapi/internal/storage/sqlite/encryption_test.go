@@ -0,0 +1,177 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/fieldcrypt"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+func generateKeys(t *testing.T, ids ...string) map[string]string {
+	t.Helper()
+
+	keys := map[string]string{}
+	for _, id := range ids {
+		key, err := fieldcrypt.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[id] = key
+	}
+	return keys
+}
+
+// TestReencrypt starts with a plain row, then a row sealed with k1 in a field that is later taken out of
+// encryption.fields, and checks Reencrypt brings both to k2 so k1 can be dropped
+func TestReencrypt(t *testing.T) {
+	dir := t.TempDir()
+	keys := generateKeys(t, "k1", "k2", "index")
+
+	s := openStorage(t, dir, config.Encryption{})
+	plain, err := s.CreateStudent(types.Student{Name: "Alan Turing", Email: "alan@gmail.com", Age: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s = openStorage(t, dir, config.Encryption{
+		Fields:    []string{"name", "last_name", "email"},
+		ActiveKey: "k1",
+		Keys:      map[string]string{"k1": keys["k1"]},
+		IndexKey:  keys["index"],
+	})
+	oldKey, err := s.CreateStudent(types.Student{Name: "Ada Lovelace", Email: "ada@example.com", Age: 36})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	current := config.Encryption{
+		Fields:    []string{"name", "email"},
+		ActiveKey: "k2",
+		Keys:      map[string]string{"k2": keys["k2"]},
+		IndexKey:  keys["index"],
+	}
+	rotating := current
+	rotating.Keys = map[string]string{"k1": keys["k1"], "k2": keys["k2"]}
+
+	s = openStorage(t, dir, rotating)
+	//a batch of one row at a time, two students and their two outbox events
+	passes := 0
+	for ; passes < 10; passes++ {
+		n, err := s.Reencrypt(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+	if passes != 2 {
+		t.Errorf("re-encrypted in %d passes, want 2", passes)
+	}
+
+	prefix := s.ring.ActivePrefix()
+	rows, err := s.Db.Query("SELECT id, name, last_name, email, email_index FROM students")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id int64
+		var name, lastName, email string
+		var index sql.NullString
+		if err := rows.Scan(&id, &name, &lastName, &email, &index); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(name, prefix) || !strings.HasPrefix(email, prefix) {
+			t.Errorf("student %d: name or email not sealed with k2", id)
+		}
+		if fieldcrypt.IsSealed(lastName) {
+			t.Errorf("student %d: last_name still sealed", id)
+		}
+		if !index.Valid {
+			t.Errorf("student %d: no email_index", id)
+		}
+	}
+	rows.Close()
+
+	var stale int
+	if err := s.Db.QueryRow("SELECT count(*) FROM outbox WHERE payload NOT GLOB ?", prefix+"*").Scan(&stale); err != nil {
+		t.Fatal(err)
+	}
+	if stale != 0 {
+		t.Errorf("%d outbox events not sealed with k2", stale)
+	}
+	s.Close()
+
+	//nothing is left on k1
+	s = openStorage(t, dir, current)
+	for id, want := range map[int64]types.Student{
+		plain:  {Name: "Alan Turing", LastName: "Turing", Email: "alan@gmail.com"},
+		oldKey: {Name: "Ada Lovelace", LastName: "Lovelace", Email: "ada@example.com"},
+	} {
+		got, err := s.GetStudentById(id)
+		if err != nil {
+			t.Fatalf("student %d: %v", id, err)
+		}
+		if got.Name != want.Name || got.LastName != want.LastName || got.Email != want.Email {
+			t.Errorf("student %d: got %+v", id, got)
+		}
+	}
+	if n, err := s.Reencrypt(100); err != nil || n != 0 {
+		t.Errorf("second run: rewrote %d rows, %v", n, err)
+	}
+}
+
+func TestCheckEmail(t *testing.T) {
+	keys := generateKeys(t, "k1", "index")
+	s := openStorage(t, t.TempDir(), config.Encryption{
+		Fields:    []string{"email"},
+		ActiveKey: "k1",
+		Keys:      map[string]string{"k1": keys["k1"]},
+		IndexKey:  keys["index"],
+	})
+
+	alan, err := s.CreateStudent(types.Student{Name: "Alan Turing", Email: "alan@gmail.com", Age: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//a row from before encryption, plain and without an index
+	if _, err := s.Db.Exec(benchInsert, "Grace", "Grace", "", "grace@navy.mil", 31, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		id    int64
+		email string
+		taken bool
+	}{
+		{"sealed row", 0, "alan@gmail.com", true},
+		{"sealed row, other case and spaces", 0, " Alan@Gmail.COM ", true},
+		{"own email", alan, "alan@gmail.com", false},
+		{"plain row", 0, "GRACE@navy.mil", true},
+		{"free email", 0, "ada@example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.withTx(func(tx *sql.Tx) error {
+				return s.checkEmail(tx, tt.id, tt.email)
+			})
+			if taken := errors.Is(err, storage.ErrEmailTaken); taken != tt.taken || (err != nil && !taken) {
+				t.Errorf("got %v, taken %v", err, tt.taken)
+			}
+		})
+	}
+
+	_, err = s.CreateStudent(types.Student{Name: "Alan Again", Email: "ALAN@gmail.com", Age: 25})
+	if !errors.Is(err, storage.ErrEmailTaken) {
+		t.Errorf("create with a taken email: got %v, want ErrEmailTaken", err)
+	}
+}
@@ -0,0 +1,42 @@
+package sqlite
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// EventsAfter returns outbox events with an id greater than the given one, oldest first
+func (s *Sqlite) EventsAfter(id int64, limit int) ([]types.Event, error) {
+	rows, err := s.Db.Query("SELECT id, event_type, student_id, payload, created_at FROM outbox WHERE id > ? ORDER BY id LIMIT ?", id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []types.Event{}
+	for rows.Next() {
+		var event types.Event
+		var payload string
+
+		if err := rows.Scan(&event.Id, &event.Type, &event.StudentId, &payload, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		if payload, err = s.openPayload(payload); err != nil {
+			return nil, fmt.Errorf("event %d: %w", event.Id, err)
+		}
+
+		event.Payload = json.RawMessage(payload)
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (s *Sqlite) LastEventId() (int64, error) {
+	var id int64
+
+	err := s.Db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM outbox").Scan(&id)
+	return id, err
+}
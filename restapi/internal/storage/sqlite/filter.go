@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shivakr07/students-api/internal/filter"
+	"github.com/shivakr07/students-api/internal/storage"
+)
+
+// currentAge is types.Student.CurrentAge in sql: whole years since the date of birth [on today's
+// date in utc] when there is one, the stored age only for students without it
+// the stored age of the others is from their last write and goes stale on every birthday
+const currentAge = `(CASE WHEN date_of_birth IS NULL THEN age ELSE
+	CAST(strftime('%Y', 'now') AS INTEGER) - CAST(strftime('%Y', date_of_birth) AS INTEGER)
+	- (strftime('%m-%d', 'now') < strftime('%m-%d', date_of_birth)) END)`
+
+// columns a filter may use, keyed by the name in the api; nothing else is ever written into the sql
+var studentColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"email":      "email",
+	"age":        currentAge,
+}
+
+var sqlOps = map[string]string{"=": "=", "!=": "<>", ">": ">", ">=": ">=", "<": "<", "<=": "<="}
+
+// compileFilter turns a filter AST into a WHERE condition, values only ever go in as placeholders
+func (s *Sqlite) compileFilter(e filter.Expr, columns map[string]string) (string, []any, error) {
+	switch e := e.(type) {
+	case filter.And:
+		return s.compileBinary(e.Left, e.Right, "AND", columns)
+	case filter.Or:
+		return s.compileBinary(e.Left, e.Right, "OR", columns)
+	case filter.Not:
+		cond, args, err := s.compileFilter(e.Expr, columns)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + cond + ")", args, nil
+	case filter.Comparison:
+		column, ok := columns[e.Field]
+		if !ok {
+			return "", nil, fmt.Errorf("can't filter on %q", e.Field)
+		}
+
+		//an encrypted column can only be compared through its blind index, and only the email has one
+		if s.sealed[column] {
+			if column != "email" || (e.Op != "=" && e.Op != "!=") {
+				return "", nil, fmt.Errorf("%s: %w", e.Field, storage.ErrEncryptedField)
+			}
+			cond, args := s.emailCondition(fmt.Sprint(e.Value))
+			if e.Op == "!=" {
+				cond = "NOT " + cond
+			}
+			return cond, args, nil
+		}
+
+		//~ is "contains", % and _ in the value are matched literally
+		if e.Op == "~" {
+			value, _ := e.Value.(string)
+			return column + ` LIKE ? ESCAPE '\'`, []any{"%" + escapeLike(value) + "%"}, nil
+		}
+
+		op, ok := sqlOps[e.Op]
+		if !ok {
+			return "", nil, fmt.Errorf("unsupported operator %q", e.Op)
+		}
+		return column + " " + op + " ?", []any{e.Value}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported filter node %T", e)
+	}
+}
+
+func (s *Sqlite) compileBinary(left filter.Expr, right filter.Expr, op string, columns map[string]string) (string, []any, error) {
+	l, largs, err := s.compileFilter(left, columns)
+	if err != nil {
+		return "", nil, err
+	}
+	r, rargs, err := s.compileFilter(right, columns)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return "(" + l + " " + op + " " + r + ")", append(largs, rargs...), nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+func (s *Sqlite) CreateAssessment(courseId int64, name string, weight float64) (int64, error) {
+	if _, err := s.GetCourseById(courseId); err != nil {
+		return 0, err
+	}
+
+	result, err := s.Db.Exec("INSERT INTO assessments (course_id, name, weight) VALUES (?, ?, ?)", courseId, name, weight)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (s *Sqlite) GetAssessments(courseId int64) ([]types.Assessment, error) {
+	if _, err := s.GetCourseById(courseId); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Db.Query("SELECT id, course_id, name, weight FROM assessments WHERE course_id = ? ORDER BY id", courseId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assessments := []types.Assessment{}
+	for rows.Next() {
+		var assessment types.Assessment
+
+		if err := rows.Scan(&assessment.Id, &assessment.CourseId, &assessment.Name, &assessment.Weight); err != nil {
+			return nil, err
+		}
+
+		assessments = append(assessments, assessment)
+	}
+
+	return assessments, rows.Err()
+}
+
+func (s *Sqlite) RecordScore(assessmentId int64, studentId int64, score float64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		var courseId int64
+		err := tx.QueryRow("SELECT course_id FROM assessments WHERE id = ?", assessmentId).Scan(&courseId)
+		if err == sql.ErrNoRows {
+			return i18n.Errorf("storage.assessment_not_found", "no assessment found with id %d", assessmentId)
+		}
+		if err != nil {
+			return err
+		}
+
+		var enrolled int
+		err = tx.QueryRow("SELECT COUNT(*) FROM enrollments WHERE course_id = ? AND student_id = ? AND status = ?",
+			courseId, studentId, types.EnrollmentActive).Scan(&enrolled)
+		if err != nil {
+			return err
+		}
+		if enrolled == 0 {
+			return storage.ErrNotEnrolled
+		}
+
+		_, err = tx.Exec(`INSERT INTO scores (assessment_id, student_id, score, graded_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT (assessment_id, student_id) DO UPDATE SET score = excluded.score, graded_at = excluded.graded_at`,
+			assessmentId, studentId, score, time.Now().UTC())
+		return err
+	})
+}
+
+func (s *Sqlite) GetStudentGrades(studentId int64) ([]types.CourseGrades, error) {
+	enrollments, err := s.GetStudentCourses(studentId)
+	if err != nil {
+		return nil, err
+	}
+
+	//every assessment of the courses the student is in, with their score when there is one
+	rows, err := s.Db.Query(`SELECT a.id, a.course_id, a.name, a.weight, sc.score
+		FROM assessments a
+		JOIN enrollments e ON e.course_id = a.course_id AND e.student_id = ? AND e.status = ?
+		LEFT JOIN scores sc ON sc.assessment_id = a.id AND sc.student_id = e.student_id
+		ORDER BY a.id`, studentId, types.EnrollmentActive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byCourse := map[int64][]types.AssessmentScore{}
+	for rows.Next() {
+		var assessment types.AssessmentScore
+		var score sql.NullFloat64
+
+		if err := rows.Scan(&assessment.Id, &assessment.CourseId, &assessment.Name, &assessment.Weight, &score); err != nil {
+			return nil, err
+		}
+		if score.Valid {
+			assessment.Score = &score.Float64
+		}
+
+		byCourse[assessment.CourseId] = append(byCourse[assessment.CourseId], assessment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	grades := []types.CourseGrades{}
+	for _, enrollment := range enrollments {
+		if enrollment.Status != types.EnrollmentActive {
+			continue
+		}
+
+		grades = append(grades, types.CourseGrades{
+			Course:      *enrollment.Course,
+			Assessments: byCourse[enrollment.CourseId],
+		})
+	}
+
+	return grades, nil
+}
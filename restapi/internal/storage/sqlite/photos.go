@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+func (s *Sqlite) SetStudentPhoto(photo types.Photo) (types.Photo, error) {
+	var replaced types.Photo
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		var exists int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM students WHERE id = ?", photo.StudentId).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			return i18n.Errorf("storage.student_not_found", "no student found with id %d", photo.StudentId)
+		}
+
+		//read in the same transaction, so two uploads at once each get the photo they really replaced
+		current, err := s.getStudentPhoto(tx, photo.StudentId)
+		switch {
+		case err == nil:
+			replaced = current
+		case !errors.Is(err, storage.ErrPhotoNotFound):
+			return err
+		}
+
+		_, err = tx.Exec(`INSERT INTO student_photos (student_id, content_type, size, width, height, etag, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (student_id) DO UPDATE SET content_type = excluded.content_type, size = excluded.size,
+			width = excluded.width, height = excluded.height, etag = excluded.etag, updated_at = excluded.updated_at`,
+			photo.StudentId, photo.ContentType, photo.Size, photo.Width, photo.Height, photo.ETag, photo.UpdatedAt)
+		return err
+	})
+	if err != nil {
+		return types.Photo{}, err
+	}
+
+	return replaced, nil
+}
+
+func (s *Sqlite) GetStudentPhoto(studentId int64) (types.Photo, error) {
+	return s.getStudentPhoto(s.Db, studentId)
+}
+
+// rowQueryer is what *sql.DB and *sql.Tx have in common for single row reads
+type rowQueryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func (s *Sqlite) getStudentPhoto(q rowQueryer, studentId int64) (types.Photo, error) {
+	var photo types.Photo
+
+	err := q.QueryRow(`SELECT student_id, content_type, size, width, height, etag, updated_at
+		FROM student_photos WHERE student_id = ?`, studentId).
+		Scan(&photo.StudentId, &photo.ContentType, &photo.Size, &photo.Width, &photo.Height, &photo.ETag, &photo.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return types.Photo{}, i18n.Errorf("storage.photo_not_found", "no photo found for student %d", studentId)
+	}
+	if err != nil {
+		return types.Photo{}, fmt.Errorf("qeury error : %w", err)
+	}
+
+	return photo, nil
+}
+
+func (s *Sqlite) DeleteStudentPhoto(studentId int64) (types.Photo, error) {
+	var deleted types.Photo
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		photo, err := s.getStudentPhoto(tx, studentId)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("DELETE FROM student_photos WHERE student_id = ?", studentId); err != nil {
+			return err
+		}
+
+		deleted = photo
+		return nil
+	})
+
+	return deleted, err
+}
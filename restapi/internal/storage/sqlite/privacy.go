@@ -0,0 +1,248 @@
+package sqlite
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// ExportStudent collects every row that is about the student, the reads aren't one snapshot but a
+// student is only ever changed through single requests so that doesn't matter in practice
+func (s *Sqlite) ExportStudent(id int64) (types.StudentExport, error) {
+	student, err := s.GetStudentById(id)
+	if err != nil {
+		return types.StudentExport{}, s.erasedOr(id, err)
+	}
+
+	export := types.StudentExport{Student: student}
+
+	if export.Enrollments, err = s.GetStudentCourses(id); err != nil {
+		return types.StudentExport{}, err
+	}
+	if export.Scores, err = s.studentScores(id); err != nil {
+		return types.StudentExport{}, err
+	}
+
+	photo, err := s.GetStudentPhoto(id)
+	switch {
+	case err == nil:
+		export.Photo = &photo
+	case !errors.Is(err, storage.ErrPhotoNotFound):
+		return types.StudentExport{}, err
+	}
+
+	if export.Events, err = s.studentEvents(id); err != nil {
+		return types.StudentExport{}, err
+	}
+	if export.Disclosures, err = s.studentDisclosures(id); err != nil {
+		return types.StudentExport{}, err
+	}
+
+	return export, nil
+}
+
+func (s *Sqlite) studentScores(id int64) ([]types.StudentScore, error) {
+	rows, err := s.Db.Query(`SELECT a.course_id, a.id, a.name, sc.score, sc.graded_at
+		FROM scores sc JOIN assessments a ON a.id = sc.assessment_id
+		WHERE sc.student_id = ? ORDER BY a.course_id, a.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scores := []types.StudentScore{}
+	for rows.Next() {
+		var score types.StudentScore
+		if err := rows.Scan(&score.CourseId, &score.AssessmentId, &score.Assessment, &score.Score, &score.GradedAt); err != nil {
+			return nil, err
+		}
+		scores = append(scores, score)
+	}
+
+	return scores, rows.Err()
+}
+
+func (s *Sqlite) studentEvents(id int64) ([]types.Event, error) {
+	rows, err := s.Db.Query("SELECT id, event_type, student_id, payload, created_at FROM outbox WHERE student_id = ? ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []types.Event{}
+	for rows.Next() {
+		var event types.Event
+		var payload string
+
+		if err := rows.Scan(&event.Id, &event.Type, &event.StudentId, &payload, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		if payload, err = s.openPayload(payload); err != nil {
+			return nil, fmt.Errorf("event %d: %w", event.Id, err)
+		}
+
+		event.Payload = json.RawMessage(payload)
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (s *Sqlite) studentDisclosures(id int64) ([]types.Disclosure, error) {
+	rows, err := s.Db.Query(`SELECT d.event_id, d.webhook_id, w.url, d.status, d.attempts `+deliveryJoins+
+		` WHERE o.student_id = ? ORDER BY d.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	disclosures := []types.Disclosure{}
+	for rows.Next() {
+		var d types.Disclosure
+		if err := rows.Scan(&d.EventId, &d.WebhookId, &d.URL, &d.Status, &d.Attempts); err != nil {
+			return nil, err
+		}
+		disclosures = append(disclosures, d)
+	}
+
+	return disclosures, rows.Err()
+}
+
+// EraseStudent removes the student for good: their own rows are deleted, the outbox events about them
+// stay [deliveries point at them] but only with the id left in the payload, and a tombstone records it
+// backups taken before the erasure still have the student, they age out with backup.retain
+func (s *Sqlite) EraseStudent(id int64, reason string) (types.Erasure, error) {
+	var erasure types.Erasure
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		removed := map[string]int64{}
+
+		//dependents first, so this works with foreign keys switched off too
+		for _, table := range []string{"scores", "student_photos", "enrollments", "students"} {
+			column := "student_id"
+			if table == "students" {
+				column = "id"
+			}
+
+			result, err := tx.Exec("DELETE FROM "+table+" WHERE "+column+" = ?", id)
+			if err != nil {
+				return err
+			}
+			if removed[table], err = result.RowsAffected(); err != nil {
+				return err
+			}
+		}
+
+		if removed["students"] == 0 {
+			if _, err := s.getErasure(tx, id); err == nil {
+				return storage.ErrErased
+			}
+			return i18n.Errorf("storage.student_not_found", "no student found with id %d", id)
+		}
+
+		//the payloads are the student as it was, the event itself [what happened, when] isn't personal
+		anonymized, err := json.Marshal(types.Student{Id: id})
+		if err != nil {
+			return err
+		}
+		payload, err := s.sealPayload(string(anonymized))
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec("UPDATE outbox SET payload = ? WHERE student_id = ?", payload, id)
+		if err != nil {
+			return err
+		}
+		if removed["outbox"], err = result.RowsAffected(); err != nil {
+			return err
+		}
+
+		if err := s.insertEvent(tx, types.EventStudentErased, types.Student{Id: id}); err != nil {
+			return err
+		}
+
+		erasure = types.Erasure{StudentId: id, Reason: reason, Removed: removed, ErasedAt: time.Now().UTC()}
+		return s.insertErasure(tx, &erasure)
+	})
+
+	return erasure, err
+}
+
+// insertErasure fills in the digest, the hash of the tombstone and of the digest of the one before it
+func (s *Sqlite) insertErasure(tx *sql.Tx, erasure *types.Erasure) error {
+	var previous string
+	err := tx.QueryRow("SELECT digest FROM erasures ORDER BY id DESC LIMIT 1").Scan(&previous)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	removed, err := json.Marshal(erasure.Removed)
+	if err != nil {
+		return err
+	}
+	erasure.Digest = erasureDigest(previous, erasure.StudentId, erasure.Reason, string(removed), erasure.ErasedAt)
+
+	result, err := tx.Exec("INSERT INTO erasures (student_id, reason, removed, erased_at, digest) VALUES (?, ?, ?, ?, ?)",
+		erasure.StudentId, erasure.Reason, string(removed), erasure.ErasedAt, erasure.Digest)
+	if err != nil {
+		return err
+	}
+
+	erasure.Id, err = result.LastInsertId()
+	return err
+}
+
+// erasureDigest is sha256 over the previous digest and every field of the tombstone, one per line
+func erasureDigest(previous string, studentId int64, reason string, removed string, erasedAt time.Time) string {
+	h := sha256.New()
+	for _, part := range []string{previous, strconv.FormatInt(studentId, 10), reason, removed, erasedAt.UTC().Format(time.RFC3339Nano)} {
+		h.Write([]byte(part))
+		h.Write([]byte("\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *Sqlite) GetErasure(studentId int64) (types.Erasure, error) {
+	return s.getErasure(s.Db, studentId)
+}
+
+func (s *Sqlite) getErasure(q rowQueryer, studentId int64) (types.Erasure, error) {
+	var erasure types.Erasure
+	var removed string
+
+	err := q.QueryRow("SELECT id, student_id, reason, removed, erased_at, digest FROM erasures WHERE student_id = ?", studentId).
+		Scan(&erasure.Id, &erasure.StudentId, &erasure.Reason, &removed, &erasure.ErasedAt, &erasure.Digest)
+	if err == sql.ErrNoRows {
+		return types.Erasure{}, i18n.Errorf("storage.erasure_not_found", "student %d was never erased", studentId)
+	}
+	if err != nil {
+		return types.Erasure{}, err
+	}
+
+	if err := json.Unmarshal([]byte(removed), &erasure.Removed); err != nil {
+		return types.Erasure{}, err
+	}
+	erasure.ErasedAt = erasure.ErasedAt.UTC()
+
+	return erasure, nil
+}
+
+// erasedOr turns a missing student into storage.ErrErased when there is a tombstone for them
+func (s *Sqlite) erasedOr(id int64, err error) error {
+	if !errors.Is(err, storage.ErrStudentNotFound) {
+		return err
+	}
+	if _, erasedErr := s.GetErasure(id); erasedErr == nil {
+		return storage.ErrErased
+	}
+	return err
+}
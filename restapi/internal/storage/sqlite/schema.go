@@ -0,0 +1,300 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+)
+
+// migration is one versioned step of the schema, applied at most once and in order
+// never edit a migration that has shipped, add a new one instead
+type migration struct {
+	version int
+	name    string
+	stmts   []string
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "students and webhook outbox",
+		//these were created on every start before migrations existed, hence IF NOT EXISTS
+		stmts: []string{
+			`CREATE TABLE IF NOT EXISTS students (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT,
+			email TEXT,
+			age INTEGER
+			)`,
+
+			// outbox holds one row per student change, written in the same transaction as the change itself
+			`CREATE TABLE IF NOT EXISTS outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_type TEXT NOT NULL,
+			student_id INTEGER NOT NULL,
+			payload TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			dispatched INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX IF NOT EXISTS outbox_dispatched ON outbox (dispatched, id)`,
+
+			`CREATE TABLE IF NOT EXISTS webhooks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+			)`,
+
+			// one delivery per (webhook, event), the dispatcher works through the pending ones
+			`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id INTEGER NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+			event_id INTEGER NOT NULL REFERENCES outbox (id),
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at DATETIME NOT NULL,
+			last_error TEXT NOT NULL DEFAULT '',
+			UNIQUE (webhook_id, event_id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at)`,
+		},
+	},
+	{
+		version: 2,
+		name:    "users and api keys",
+		stmts: []string{
+			`CREATE TABLE users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			username TEXT NOT NULL UNIQUE,
+			password_hash TEXT NOT NULL,
+			role TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+			)`,
+
+			// only a hash of the key is kept, the prefix is there so people can tell their keys apart
+			`CREATE TABLE api_keys (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			prefix TEXT NOT NULL,
+			key_hash TEXT NOT NULL UNIQUE,
+			created_at DATETIME NOT NULL,
+			revoked_at DATETIME
+			)`,
+		},
+	},
+	{
+		version: 3,
+		name:    "courses and enrollments",
+		stmts: []string{
+			`CREATE TABLE courses (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			code TEXT NOT NULL UNIQUE,
+			title TEXT NOT NULL,
+			capacity INTEGER NOT NULL CHECK (capacity > 0),
+			created_at DATETIME NOT NULL
+			)`,
+
+			// one row per (student, course), dropping only changes the status
+			`CREATE TABLE enrollments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			student_id INTEGER NOT NULL REFERENCES students (id) ON DELETE CASCADE,
+			course_id INTEGER NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+			status TEXT NOT NULL,
+			enrolled_at DATETIME NOT NULL,
+			dropped_at DATETIME,
+			UNIQUE (student_id, course_id)
+			)`,
+			`CREATE INDEX enrollments_course ON enrollments (course_id, status)`,
+		},
+	},
+	{
+		version: 4,
+		name:    "assessments and scores",
+		stmts: []string{
+			`ALTER TABLE courses ADD COLUMN term TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE courses ADD COLUMN credits REAL NOT NULL DEFAULT 3`,
+			`ALTER TABLE courses ADD COLUMN scale TEXT NOT NULL DEFAULT ''`,
+
+			`CREATE TABLE assessments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			course_id INTEGER NOT NULL REFERENCES courses (id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			weight REAL NOT NULL CHECK (weight > 0)
+			)`,
+			`CREATE INDEX assessments_course ON assessments (course_id)`,
+
+			// one score per (assessment, student), grading again overwrites it
+			`CREATE TABLE scores (
+			assessment_id INTEGER NOT NULL REFERENCES assessments (id) ON DELETE CASCADE,
+			student_id INTEGER NOT NULL REFERENCES students (id) ON DELETE CASCADE,
+			score REAL NOT NULL CHECK (score BETWEEN 0 AND 100),
+			graded_at DATETIME NOT NULL,
+			PRIMARY KEY (assessment_id, student_id)
+			)`,
+		},
+	},
+	{
+		version: 5,
+		name:    "student photos",
+		stmts: []string{
+			`CREATE TABLE student_photos (
+			student_id INTEGER PRIMARY KEY REFERENCES students (id) ON DELETE CASCADE,
+			content_type TEXT NOT NULL,
+			size INTEGER NOT NULL,
+			width INTEGER NOT NULL,
+			height INTEGER NOT NULL,
+			etag TEXT NOT NULL,
+			updated_at DATETIME NOT NULL
+			)`,
+		},
+	},
+	{
+		version: 6,
+		name:    "student first and last name, date of birth",
+		stmts: []string{
+			`ALTER TABLE students ADD COLUMN first_name TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE students ADD COLUMN last_name TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE students ADD COLUMN date_of_birth TEXT`,
+			//same split as types.SplitName: up to the first space, and the rest
+			`UPDATE students SET
+			first_name = CASE WHEN instr(trim(name), ' ') > 0 THEN substr(trim(name), 1, instr(trim(name), ' ') - 1) ELSE trim(name) END,
+			last_name = CASE WHEN instr(trim(name), ' ') > 0 THEN trim(substr(trim(name), instr(trim(name), ' ') + 1)) ELSE '' END`,
+		},
+	},
+	{
+		version: 7,
+		name:    "email blind index for encrypted emails",
+		stmts: []string{
+			//an encrypted email can't be compared in sql, the hmac of it can [see fieldcrypt.BlindIndex]
+			`ALTER TABLE students ADD COLUMN email_index TEXT`,
+			`CREATE INDEX IF NOT EXISTS students_email_index ON students (email_index)`,
+			`CREATE INDEX IF NOT EXISTS students_email_lower ON students (lower(email))`,
+		},
+	},
+	{
+		version: 8,
+		name:    "erasure tombstones",
+		stmts: []string{
+			//no foreign key, the student it is about is gone [ids are AUTOINCREMENT so never reused]
+			`CREATE TABLE erasures (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			student_id INTEGER NOT NULL UNIQUE,
+			reason TEXT NOT NULL,
+			removed TEXT NOT NULL,
+			erased_at DATETIME NOT NULL,
+			digest TEXT NOT NULL
+			)`,
+			`CREATE INDEX outbox_student ON outbox (student_id)`,
+		},
+	},
+}
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at DATETIME NOT NULL
+	)`
+
+// MigrationStatus is one row of `students-api migrate -status`
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrate applies every pending migration, each one in its own transaction
+// it returns the versions it applied
+func (s *Sqlite) Migrate() ([]int, error) {
+	if _, err := s.Db.Exec(createMigrationsTable); err != nil {
+		return nil, err
+	}
+
+	current, err := s.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []int
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		tx, err := s.Db.Begin()
+		if err != nil {
+			return applied, err
+		}
+
+		for _, stmt := range m.stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				tx.Rollback()
+				return applied, fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+			}
+		}
+
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)", m.version, m.name, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return applied, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return applied, err
+		}
+
+		applied = append(applied, m.version)
+	}
+
+	return applied, nil
+}
+
+// SchemaVersion is the newest migration applied to the db, 0 for a fresh db
+func (s *Sqlite) SchemaVersion() (int, error) {
+	if _, err := s.Db.Exec(createMigrationsTable); err != nil {
+		return 0, err
+	}
+
+	var version int
+	err := s.Db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+// Migrations lists every known migration and when it was applied (nil when it is still pending)
+func (s *Sqlite) Migrations() ([]MigrationStatus, error) {
+	if _, err := s.Db.Exec(createMigrationsTable); err != nil {
+		return nil, err
+	}
+
+	applied := map[int]time.Time{}
+	rows, err := s.Db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.version, Name: m.name}
+		if at, ok := applied[m.version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func latestVersion() int {
+	return migrations[len(migrations)-1].version
+}
@@ -3,10 +3,17 @@ package sqlite
 import (
 	"database/sql"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	//since we are not using directly like obj.something so we are using indirectly so we used _
 	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/fieldcrypt"
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/storage"
 	"github.com/shivakr07/students-api/internal/types"
 )
 
@@ -14,38 +21,159 @@ import (
 
 type Sqlite struct {
 	Db *sql.DB
+
+	//hot statements are prepared once in New and reused by every request
+	stmts statements
+
+	//the columns in encryption.fields and the keys they are sealed with, ring is nil when encryption is off
+	ring   *fieldcrypt.KeyRing
+	sealed map[string]bool
+}
+
+type statements struct {
+	createStudent  *sql.Stmt
+	getStudentById *sql.Stmt
+	getStudents    *sql.Stmt
+	updateStudent  *sql.Stmt
+	deleteStudent  *sql.Stmt
+	insertEvent    *sql.Stmt
 }
 
 // since we don't have constructor concept but we replicate similar using New [as convention]
+// New opens the db, brings the schema up to date (unless auto_migrate is off) and prepares the hot statements
 func New(cfg *config.Config) (*Sqlite, error) {
+	s, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SQLite.AutoMigrate {
+		if _, err := s.Migrate(); err != nil {
+			s.Close()
+			return nil, err
+		}
+	} else {
+		version, err := s.SchemaVersion()
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		if version < latestVersion() {
+			s.Close()
+			return nil, fmt.Errorf("database schema is at version %d but %d is required, run `students-api migrate`", version, latestVersion())
+		}
+	}
+
+	if err := s.prepare(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	//if everthing okay then return sqlite
+	return s, nil
+}
+
+// Open only opens the connection pool, it doesn't touch the schema
+// used by `students-api migrate` which has to work on an outdated db
+func Open(cfg *config.Config) (*Sqlite, error) {
 	//db connection
 	//we need to pass the driver inside the open method and storage path
 	//open method returns two thing instance of the db and error
 	// need to install this driver : browse go sqlite driver [mattnn git]
-	db, err := sql.Open("sqlite3", cfg.StoragePath)
+	db, err := sql.Open("sqlite3", buildDSN(cfg.StoragePath, cfg.SQLite))
 	if err != nil {
 		//we return sqlite and error
 		//since till here we are getting error so instead of sqlite we are returning the nil
 		return nil, err
 	}
 
-	//create table
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS students (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	name TEXT,
-	email TEXT,
-	age INTEGER
-	)`)
+	//every connection of an in-memory db is its own empty db, so keep exactly one
+	if isMemory(cfg.StoragePath) {
+		db.SetMaxOpenConns(1)
+	} else {
+		db.SetMaxOpenConns(cfg.SQLite.MaxOpenConns)
+		db.SetMaxIdleConns(cfg.SQLite.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(cfg.SQLite.ConnMaxLifetime)
 
+	ring, err := fieldcrypt.New(cfg.Encryption)
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
+	sealed := map[string]bool{}
+	for _, field := range cfg.Encryption.Fields {
+		sealed[field] = true
+	}
 
-	//if everthing okay then return sqlite
-	return &Sqlite{
-		Db: db,
-	}, nil
+	return &Sqlite{Db: db, ring: ring, sealed: sealed}, nil
+}
 
+// buildDSN turns the path and the tuning section of the config into a go-sqlite3 connection string
+// the pragmas go in the dsn (and not through Exec) so every new connection of the pool gets them
+// the values were already checked by config.Validate
+func buildDSN(path string, cfg config.SQLite) string {
+	params := url.Values{}
+	params.Set("_journal_mode", strings.ToUpper(cfg.JournalMode))
+	params.Set("_busy_timeout", strconv.FormatInt(cfg.BusyTimeout.Milliseconds(), 10))
+	params.Set("_synchronous", strings.ToUpper(cfg.Synchronous))
+	params.Set("_foreign_keys", strconv.FormatBool(cfg.ForeignKeys))
+	//take the write lock when the transaction starts, otherwise two readers that both want to write deadlock
+	//and one of them fails straight away with "database is locked" instead of waiting for busy_timeout
+	params.Set("_txlock", "immediate")
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	return path + separator + params.Encode()
+}
+
+func isMemory(path string) bool {
+	return path == ":memory:" || strings.Contains(path, "mode=memory")
+}
+
+func (s *Sqlite) prepare() error {
+	queries := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&s.stmts.createStudent, "INSERT INTO students (name, first_name, last_name, email, age, date_of_birth, email_index) VALUES (?, ?, ?, ?, ?, ?, ?)"},
+		{&s.stmts.getStudentById, "SELECT " + studentSelect + " FROM students WHERE id = ? LIMIT 1"},
+		{&s.stmts.getStudents, "SELECT " + studentSelect + " FROM students"},
+		{&s.stmts.updateStudent, "UPDATE students SET name = ?, first_name = ?, last_name = ?, email = ?, age = ?, date_of_birth = ?, email_index = ? WHERE id = ?"},
+		{&s.stmts.deleteStudent, "DELETE FROM students WHERE id = ?"},
+		{&s.stmts.insertEvent, "INSERT INTO outbox (event_type, student_id, payload, created_at) VALUES (?, ?, ?, ?)"},
+	}
+
+	for _, q := range queries {
+		stmt, err := s.Db.Prepare(q.query)
+		if err != nil {
+			return fmt.Errorf("prepare %q: %w", q.query, err)
+		}
+		*q.stmt = stmt
+	}
+
+	return nil
+}
+
+// Close releases the prepared statements and the connection pool
+func (s *Sqlite) Close() error {
+	for _, stmt := range []*sql.Stmt{
+		s.stmts.createStudent,
+		s.stmts.getStudentById,
+		s.stmts.getStudents,
+		s.stmts.updateStudent,
+		s.stmts.deleteStudent,
+		s.stmts.insertEvent,
+	} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+
+	return s.Db.Close()
 }
 
 // exec return two things res [result of the query] and error
@@ -54,19 +182,48 @@ func New(cfg *config.Config) (*Sqlite, error) {
 // 	//why nil in 	}, nil while returning db? bcause we need to return error since we have no error now so we pass the nil
 
 // implementing func to implement interface
-func (s *Sqlite) CreateStudent(name string, email string, age int) (int64, error) {
-	//to create the records in the db
-	stmt, err := s.Db.Prepare("INSERT INTO students (name, email, age) VALUES (?, ?, ?)")
+func (s *Sqlite) CreateStudent(student types.Student) (int64, error) {
+	var lastId int64
+
+	//the student row and its outbox event are written in one transaction
+	//so a webhook is never sent for a student that doesn't exist (and never missed for one that does)
+	err := s.withTx(func(tx *sql.Tx) error {
+		var err error
+		lastId, err = s.createStudent(tx, student)
+		return err
+	})
 	if err != nil {
+		//why we are returning 0 [because in return type it should be int64]so 0 is zeroed value / empty value for int type
 		return 0, err
 	}
 
-	//we need to close this statement also after function execution
-	defer stmt.Close()
+	return lastId, nil
+	//since here we don't have error
+
+	//first we prepare the statement/query and then we bind the data
+}
+
+// createStudent, updateStudent and deleteStudent do the work of the exported methods on a transaction
+// the caller owns, so a batch [see InTx] can run many of them in one transaction
+func (s *Sqlite) createStudent(tx *sql.Tx, student types.Student) (int64, error) {
+	student.Normalize(time.Now())
+
+	if err := s.checkEmail(tx, 0, student.Email); err != nil {
+		return 0, err
+	}
+	//the encrypted fields are sealed here, the student itself [and its outbox event] stays plain
+	values, emailIndex, err := s.studentValues(student.Name, student.FirstName, student.LastName, student.Email)
+	if err != nil {
+		return 0, err
+	}
+
+	//to create the records in the db
+	//the statement was prepared once in New, tx.Stmt binds it to this transaction
+	stmt := tx.Stmt(s.stmts.createStudent)
 
 	// we put ? ? ? [placeholders] to avoid the SQL injection as we don't pass the data direct which we are receiving
 	//these values we are reveiving the func
-	result, err := stmt.Exec(name, email, age)
+	result, err := stmt.Exec(append(values, student.Age, dateValue(student.DateOfBirth), emailIndex)...)
 	if err != nil {
 		return 0, err
 	}
@@ -75,16 +232,13 @@ func (s *Sqlite) CreateStudent(name string, email string, age int) (int64, error
 	// we get methods from Exec
 	// LastInsertId() (int64, error) and RowsAffected() (int64, error)
 	// [check by clicking ctrl + click to see the def]
-	//why we are returning 0 [because in return type it should be int64]so 0 is zeroed value / empty value for int type
 	lastId, err := result.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
 
-	return lastId, nil
-	//since here we don't have error
-
-	//first we prepare the statement/query and then we bind the data
+	student.Id = lastId
+	return lastId, s.insertEvent(tx, types.EventStudentCreated, student)
 }
 
 //how pluging helps
@@ -99,21 +253,16 @@ func (s *Sqlite) CreateStudent(name string, email string, age int) (int64, error
 //POWER OF DEPENDENCY INJECTION
 
 func (s *Sqlite) GetStudentById(id int64) (types.Student, error) {
-	stmt, err := s.Db.Prepare("SELECT id, name, email, age FROM students WHERE id = ? LIMIT 1")
-	if err != nil {
-		return types.Student{}, err
-		//empty struct
-	}
-	defer stmt.Close()
+	stmt := s.stmts.getStudentById
 
 	//whatever data we are getting from the db that needs to be deserialized so
 	var student types.Student
 
-	err = stmt.QueryRow(id).Scan(&student.Id, &student.Name, &student.Email, &student.Age)
+	student, err := s.scanStudent(stmt.QueryRow(id))
 	if err != nil {
 		//sometimes we get error like user not found
 		if err == sql.ErrNoRows {
-			return types.Student{}, fmt.Errorf("n o student found with id %s", fmt.Sprint(id))
+			return types.Student{}, i18n.Errorf("storage.student_not_found", "no student found with id %d", id)
 		}
 		//else this will be error mostly
 		return types.Student{}, fmt.Errorf("qeury error : %w", err)
@@ -127,14 +276,7 @@ func (s *Sqlite) GetStudentById(id int64) (types.Student, error) {
 }
 
 func (s *Sqlite) GetStudents() ([]types.Student, error) {
-	stmt, err := s.Db.Prepare("SELECT id, name, email, age FROM students")
-	if err != nil {
-		return nil, err
-	}
-
-	defer stmt.Close()
-
-	rows, err := stmt.Query()
+	rows, err := s.stmts.getStudents.Query()
 	if err != nil {
 		return nil, err
 	}
@@ -145,9 +287,7 @@ func (s *Sqlite) GetStudents() ([]types.Student, error) {
 
 	//rows is the result of a query, its CURSOR starts before the first row of the result set
 	for rows.Next() {
-		var student types.Student
-
-		err := rows.Scan(&student.Id, &student.Name, &student.Email, &student.Age)
+		student, err := s.scanStudent(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -157,3 +297,216 @@ func (s *Sqlite) GetStudents() ([]types.Student, error) {
 
 	return students, nil
 }
+
+// ListStudents builds the WHERE clause from whatever filters are set in the query
+// values are always passed as placeholders, only the column names are written into the sql
+func (s *Sqlite) ListStudents(query types.StudentQuery) ([]types.Student, error) {
+	return s.listStudents(s.Db, query)
+}
+
+// queryer is what *sql.DB and *sql.Tx have in common, for reads that run inside and outside a transaction
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func (s *Sqlite) listStudents(q queryer, query types.StudentQuery) ([]types.Student, error) {
+	var conditions []string
+	var args []any
+
+	if query.Name != "" {
+		//an encrypted name is random bytes to sql, there is nothing to match a part of it against
+		if s.sealed["name"] {
+			return nil, fmt.Errorf("name: %w", storage.ErrEncryptedField)
+		}
+		//% and _ in the name are matched as themselves, same as ~ in the filter
+		conditions = append(conditions, `name LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(query.Name)+"%")
+	}
+	if query.Email != "" && s.sealed["email"] {
+		condition, emailArgs := s.emailCondition(query.Email)
+		conditions = append(conditions, condition)
+		args = append(args, emailArgs...)
+	} else if query.Email != "" {
+		conditions = append(conditions, "email = ?")
+		args = append(args, query.Email)
+	}
+	if query.MinAge > 0 {
+		conditions = append(conditions, currentAge+" >= ?")
+		args = append(args, query.MinAge)
+	}
+	if query.MaxAge > 0 {
+		conditions = append(conditions, currentAge+" <= ?")
+		args = append(args, query.MaxAge)
+	}
+
+	if query.Filter != nil {
+		condition, filterArgs, err := s.compileFilter(query.Filter, studentColumns)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, filterArgs...)
+	}
+
+	sqlQuery := "SELECT " + studentSelect + " FROM students"
+	if len(conditions) > 0 {
+		sqlQuery += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	sqlQuery += " ORDER BY id"
+
+	//sqlite needs a LIMIT before it accepts an OFFSET, -1 means no limit
+	limit := query.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	sqlQuery += " LIMIT ? OFFSET ?"
+	args = append(args, limit, query.Offset)
+
+	rows, err := q.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	students := []types.Student{}
+	for rows.Next() {
+		student, err := s.scanStudent(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		students = append(students, student)
+	}
+
+	return students, rows.Err()
+}
+
+func (s *Sqlite) UpdateStudent(id int64, student types.Student) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		return s.updateStudent(tx, id, student)
+	})
+}
+
+func (s *Sqlite) updateStudent(tx *sql.Tx, id int64, student types.Student) error {
+	student.Normalize(time.Now())
+
+	if err := s.checkEmail(tx, id, student.Email); err != nil {
+		return err
+	}
+	values, emailIndex, err := s.studentValues(student.Name, student.FirstName, student.LastName, student.Email)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Stmt(s.stmts.updateStudent).Exec(append(values, student.Age, dateValue(student.DateOfBirth), emailIndex, id)...)
+	if err != nil {
+		return err
+	}
+
+	//no rows touched means there was no student with that id
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return i18n.Errorf("storage.student_not_found", "no student found with id %d", id)
+	}
+
+	student.Id = id
+	return s.insertEvent(tx, types.EventStudentUpdated, student)
+}
+
+func (s *Sqlite) DeleteStudent(id int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		return s.deleteStudent(tx, id)
+	})
+}
+
+func (s *Sqlite) deleteStudent(tx *sql.Tx, id int64) error {
+	//enrollments, scores and the photo go with their student, also when foreign keys are switched off
+	if _, err := tx.Exec("DELETE FROM scores WHERE student_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM student_photos WHERE student_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM enrollments WHERE student_id = ?", id); err != nil {
+		return err
+	}
+
+	result, err := tx.Stmt(s.stmts.deleteStudent).Exec(id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return i18n.Errorf("storage.student_not_found", "no student found with id %d", id)
+	}
+
+	return s.insertEvent(tx, types.EventStudentDeleted, types.Student{Id: id})
+}
+
+// the columns scanStudent reads, in its order
+const studentSelect = "id, name, first_name, last_name, email, age, date_of_birth"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (s *Sqlite) scanStudent(row scanner) (types.Student, error) {
+	var student types.Student
+	var dateOfBirth sql.NullString
+
+	err := row.Scan(&student.Id, &student.Name, &student.FirstName, &student.LastName, &student.Email, &student.Age, &dateOfBirth)
+	if err != nil {
+		return types.Student{}, err
+	}
+
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"name", &student.Name}, {"first_name", &student.FirstName}, {"last_name", &student.LastName}, {"email", &student.Email},
+	} {
+		if *field.value, err = s.openColumn(field.column, *field.value); err != nil {
+			return types.Student{}, fmt.Errorf("student %d, %s: %w", student.Id, field.column, err)
+		}
+	}
+
+	if dateOfBirth.Valid {
+		date, err := types.ParseDate(dateOfBirth.String)
+		if err != nil {
+			return types.Student{}, err
+		}
+		student.DateOfBirth = &date
+	}
+
+	return student, nil
+}
+
+// dateValue is what a *types.Date is stored as, NULL when there is none
+func dateValue(date *types.Date) any {
+	if date == nil {
+		return nil
+	}
+	return date.String()
+}
+
+// withTx runs fn inside a transaction, commits when fn returns nil and rolls back otherwise
+func (s *Sqlite) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,172 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// the statements New prepares once, against preparing the same sql on every call
+//
+//	go test -run '^$' -bench . -cpu 1,4,8 ./internal/storage/sqlite/
+
+const (
+	benchInsert = "INSERT INTO students (name, first_name, last_name, email, age, date_of_birth, email_index) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	benchSelect = "SELECT " + studentSelect + " FROM students WHERE id = ? LIMIT 1"
+)
+
+// benchStorage is a migrated database file in the benchmark's temp dir, with the default pool and
+// pragmas [a file, :memory: is a single connection and would serialize the parallel runs]
+func benchStorage(b *testing.B, students int) *Sqlite {
+	b.Helper()
+
+	s := openStorage(b, b.TempDir(), config.Encryption{})
+
+	for i := 0; i < students; i++ {
+		if _, err := s.Db.Exec(benchInsert, "Alan Turing", "Alan", "Turing", fmt.Sprintf("alan%d@gmail.com", i), 25, nil, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return s
+}
+
+// openStorage opens [and migrates] the database file in dir with the given encryption config,
+// opening the same dir again is how a test restarts with other keys
+func openStorage(tb testing.TB, dir string, encryption config.Encryption) *Sqlite {
+	tb.Helper()
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("env: \"test\"\nstorage_path: \"students.db\"\nhttp_server:\n  address: \"localhost:0\"\n"), 0o644); err != nil {
+		tb.Fatal(err)
+	}
+
+	cfg, err := config.Load(path, map[string]string{
+		"storage_path": filepath.Join(dir, "students.db"),
+		"photos.dir":   filepath.Join(dir, "blobs"),
+		"backup.dir":   filepath.Join(dir, "backups"),
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	cfg.Encryption = encryption
+
+	s, err := New(cfg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+// BenchmarkInsert has parallel writers each committing one insert per transaction
+func BenchmarkInsert(b *testing.B) {
+	//stmt returns the statement to run on tx and what to do with it afterwards
+	run := func(b *testing.B, stmt func(s *Sqlite, tx *sql.Tx) (*sql.Stmt, func(), error)) {
+		s := benchStorage(b, 0)
+		var n atomic.Int64
+
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				email := fmt.Sprintf("alan%d@gmail.com", n.Add(1))
+				err := s.withTx(func(tx *sql.Tx) error {
+					st, done, err := stmt(s, tx)
+					if err != nil {
+						return err
+					}
+					defer done()
+
+					_, err = st.Exec("Alan Turing", "Alan", "Turing", email, 25, nil, nil)
+					return err
+				})
+				if err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	}
+
+	b.Run("prepared", func(b *testing.B) {
+		run(b, func(s *Sqlite, tx *sql.Tx) (*sql.Stmt, func(), error) {
+			return tx.Stmt(s.stmts.createStudent), func() {}, nil
+		})
+	})
+
+	b.Run("per-call", func(b *testing.B) {
+		run(b, func(s *Sqlite, tx *sql.Tx) (*sql.Stmt, func(), error) {
+			st, err := tx.Prepare(benchInsert)
+			if err != nil {
+				return nil, nil, err
+			}
+			return st, func() { st.Close() }, nil
+		})
+	})
+}
+
+// BenchmarkGetStudentById has parallel readers, reads don't take the write lock so this is where
+// the pool [and skipping the parse on every call] shows the most
+func BenchmarkGetStudentById(b *testing.B) {
+	const students = 1000
+
+	run := func(b *testing.B, get func(s *Sqlite, id int64) error) {
+		s := benchStorage(b, students)
+		var n atomic.Int64
+
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if err := get(s, n.Add(1)%students+1); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	}
+
+	b.Run("prepared", func(b *testing.B) {
+		run(b, func(s *Sqlite, id int64) error {
+			_, err := s.scanStudent(s.stmts.getStudentById.QueryRow(id))
+			return err
+		})
+	})
+
+	b.Run("per-call", func(b *testing.B) {
+		run(b, func(s *Sqlite, id int64) error {
+			st, err := s.Db.Prepare(benchSelect)
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+
+			_, err = s.scanStudent(st.QueryRow(id))
+			return err
+		})
+	})
+}
+
+// BenchmarkCreateStudent is the whole write path [email check, insert, outbox event] from parallel
+// writers, to put the statement cost in proportion
+func BenchmarkCreateStudent(b *testing.B) {
+	s := benchStorage(b, 0)
+	var n atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			student := types.Student{Name: "Alan Turing", Email: fmt.Sprintf("alan%d@gmail.com", n.Add(1)), Age: 25}
+			if _, err := s.CreateStudent(student); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
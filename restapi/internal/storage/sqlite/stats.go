@@ -0,0 +1,139 @@
+package sqlite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// what each group by field means in sql, only these are ever written into the query
+var statsGroupColumns = map[string]string{
+	"age":          "CAST(" + currentAge + " AS TEXT)",
+	"email_domain": "lower(substr(email, instr(email, '@') + 1))",
+}
+
+// a group by over unique values [every email domain...] shouldn't turn into a dump of the table
+const maxGroups = 100
+
+// StudentStats does all the counting in sql, every part runs over the same filtered set of students
+func (s *Sqlite) StudentStats(query types.StatsQuery) (types.StudentStats, error) {
+	where, args := "", []any{}
+	if query.Filter != nil {
+		condition, filterArgs, err := s.compileFilter(query.Filter, studentColumns)
+		if err != nil {
+			return types.StudentStats{}, err
+		}
+		where, args = " WHERE "+condition, filterArgs
+	}
+
+	stats := types.StudentStats{GroupBy: query.GroupBy, ComputedAt: time.Now().UTC()}
+
+	var minAge, maxAge *int
+	err := s.Db.QueryRow("SELECT COUNT(*), MIN("+currentAge+"), MAX("+currentAge+"), AVG("+currentAge+") FROM students"+where, args...).
+		Scan(&stats.Count, &minAge, &maxAge, &stats.AvgAge)
+	if err != nil {
+		return types.StudentStats{}, err
+	}
+	stats.MinAge, stats.MaxAge = minAge, maxAge
+	if stats.AvgAge != nil {
+		avg := float64(int(*stats.AvgAge*100+0.5)) / 100
+		stats.AvgAge = &avg
+	}
+
+	if stats.AgeBands, err = s.ageHistogram(query.AgeBuckets, where, args); err != nil {
+		return types.StudentStats{}, err
+	}
+
+	if query.GroupBy != "" {
+		if stats.Groups, err = s.groupCounts(query.GroupBy, where, args); err != nil {
+			return types.StudentStats{}, err
+		}
+	}
+
+	return stats, nil
+}
+
+// ageHistogram puts every student in a bucket with one CASE, buckets nobody falls in are still listed
+func (s *Sqlite) ageHistogram(bounds []int, where string, args []any) ([]types.AgeBucket, error) {
+	bands := make([]types.AgeBucket, len(bounds)+1)
+	for i := range bands {
+		if i > 0 {
+			bands[i].Min = &bounds[i-1]
+		}
+		if i < len(bounds) {
+			bands[i].Max = &bounds[i]
+		}
+		bands[i].Label = bucketLabel(bands[i].Min, bands[i].Max)
+	}
+
+	var bucket strings.Builder
+	caseArgs := make([]any, 0, len(bounds))
+	bucket.WriteString("CASE")
+	for i, bound := range bounds {
+		fmt.Fprintf(&bucket, " WHEN %s < ? THEN %d", currentAge, i)
+		caseArgs = append(caseArgs, bound)
+	}
+	fmt.Fprintf(&bucket, " ELSE %d END", len(bounds))
+
+	rows, err := s.Db.Query("SELECT "+bucket.String()+" AS bucket, COUNT(*) FROM students"+where+" GROUP BY bucket",
+		append(caseArgs, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i, count int
+		if err := rows.Scan(&i, &count); err != nil {
+			return nil, err
+		}
+		bands[i].Count = count
+	}
+
+	return bands, rows.Err()
+}
+
+func (s *Sqlite) groupCounts(field string, where string, args []any) ([]types.GroupCount, error) {
+	column, ok := statsGroupColumns[field]
+	if !ok {
+		return nil, fmt.Errorf("can't group by %q", field)
+	}
+	//the domain is inside the sealed value, sql can't see it
+	if field == "email_domain" && s.sealed["email"] {
+		return nil, fmt.Errorf("%s: %w", field, storage.ErrEncryptedField)
+	}
+
+	rows, err := s.Db.Query("SELECT "+column+" AS value, COUNT(*) AS n FROM students"+where+
+		" GROUP BY value ORDER BY n DESC, value LIMIT ?", append(args, maxGroups)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []types.GroupCount{}
+	for rows.Next() {
+		var group types.GroupCount
+		if err := rows.Scan(&group.Value, &group.Count); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+// labels like "<18", "18-20" and "40+", the upper bound of a bucket is exclusive
+func bucketLabel(min *int, max *int) string {
+	switch {
+	case min == nil:
+		return "<" + strconv.Itoa(*max)
+	case max == nil:
+		return strconv.Itoa(*min) + "+"
+	default:
+		return strconv.Itoa(*min) + "-" + strconv.Itoa(*max-1)
+	}
+}
@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// Tx is the storage.TxStorage handed out by InTx, the same queries as Sqlite but on one transaction
+type Tx struct {
+	s          *Sqlite
+	tx         *sql.Tx
+	savepoints int
+}
+
+// InTx runs fn in one transaction, the dsn starts it with BEGIN IMMEDIATE so nothing else writes in between
+func (s *Sqlite) InTx(fn func(tx storage.TxStorage) error) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		return fn(&Tx{s: s, tx: tx})
+	})
+}
+
+func (t *Tx) CreateStudent(student types.Student) (int64, error) {
+	return t.s.createStudent(t.tx, student)
+}
+
+func (t *Tx) GetStudentById(id int64) (types.Student, error) {
+	student, err := t.s.scanStudent(t.tx.Stmt(t.s.stmts.getStudentById).QueryRow(id))
+	if err == sql.ErrNoRows {
+		return types.Student{}, i18n.Errorf("storage.student_not_found", "no student found with id %d", id)
+	}
+	if err != nil {
+		return types.Student{}, fmt.Errorf("qeury error : %w", err)
+	}
+
+	return student, nil
+}
+
+func (t *Tx) GetStudents() ([]types.Student, error) {
+	return t.s.listStudents(t.tx, types.StudentQuery{})
+}
+
+func (t *Tx) ListStudents(query types.StudentQuery) ([]types.Student, error) {
+	return t.s.listStudents(t.tx, query)
+}
+
+func (t *Tx) UpdateStudent(id int64, student types.Student) error {
+	return t.s.updateStudent(t.tx, id, student)
+}
+
+func (t *Tx) DeleteStudent(id int64) error {
+	return t.s.deleteStudent(t.tx, id)
+}
+
+// GetStudentPhoto lets the photo cleanup find the blobs of a student the transaction deletes
+func (t *Tx) GetStudentPhoto(studentId int64) (types.Photo, error) {
+	return t.s.getStudentPhoto(t.tx, studentId)
+}
+
+// Savepoint nests a SAVEPOINT in the transaction, ROLLBACK TO only undoes what fn did
+func (t *Tx) Savepoint(fn func() error) error {
+	t.savepoints++
+	name := fmt.Sprintf("sp%d", t.savepoints)
+
+	if _, err := t.tx.Exec("SAVEPOINT " + name); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		if _, rollbackErr := t.tx.Exec("ROLLBACK TO " + name); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		t.tx.Exec("RELEASE " + name)
+		return err
+	}
+
+	_, err := t.tx.Exec("RELEASE " + name)
+	return err
+}
@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+func (s *Sqlite) CreateUser(username string, passwordHash string, role string) (int64, error) {
+	result, err := s.Db.Exec("INSERT INTO users (username, password_hash, role, created_at) VALUES (?, ?, ?, ?)",
+		username, passwordHash, role, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (s *Sqlite) GetUserByUsername(username string) (types.User, error) {
+	var user types.User
+
+	err := s.Db.QueryRow("SELECT id, username, password_hash, role, created_at FROM users WHERE username = ?", username).
+		Scan(&user.Id, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return types.User{}, fmt.Errorf("no user found with username %s", username)
+	}
+	if err != nil {
+		return types.User{}, fmt.Errorf("qeury error : %w", err)
+	}
+
+	return user, nil
+}
+
+func (s *Sqlite) GetUsers() ([]types.User, error) {
+	rows, err := s.Db.Query("SELECT id, username, role, created_at FROM users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []types.User{}
+	for rows.Next() {
+		var user types.User
+
+		if err := rows.Scan(&user.Id, &user.Username, &user.Role, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (s *Sqlite) DeleteUser(username string) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		//keys go with their user, also when foreign keys are switched off
+		if _, err := tx.Exec("DELETE FROM api_keys WHERE user_id = (SELECT id FROM users WHERE username = ?)", username); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec("DELETE FROM users WHERE username = ?", username)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return fmt.Errorf("no user found with username %s", username)
+		}
+
+		return nil
+	})
+}
+
+func (s *Sqlite) CreateAPIKey(userId int64, name string, prefix string, keyHash string) (int64, error) {
+	result, err := s.Db.Exec("INSERT INTO api_keys (user_id, name, prefix, key_hash, created_at) VALUES (?, ?, ?, ?, ?)",
+		userId, name, prefix, keyHash, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (s *Sqlite) GetAPIKeys() ([]types.APIKey, error) {
+	rows, err := s.Db.Query(`SELECT k.id, k.user_id, u.username, k.name, k.prefix, k.created_at, k.revoked_at
+		FROM api_keys k JOIN users u ON u.id = k.user_id ORDER BY k.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []types.APIKey{}
+	for rows.Next() {
+		var key types.APIKey
+		var revokedAt sql.NullTime
+
+		if err := rows.Scan(&key.Id, &key.UserId, &key.Username, &key.Name, &key.Prefix, &key.CreatedAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		if revokedAt.Valid {
+			key.RevokedAt = &revokedAt.Time
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (s *Sqlite) GetUserByAPIKey(keyHash string) (types.User, error) {
+	var user types.User
+
+	err := s.Db.QueryRow(`SELECT u.id, u.username, u.role, u.created_at
+		FROM api_keys k JOIN users u ON u.id = k.user_id WHERE k.key_hash = ? AND k.revoked_at IS NULL`, keyHash).
+		Scan(&user.Id, &user.Username, &user.Role, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return types.User{}, fmt.Errorf("no active api key found")
+	}
+	if err != nil {
+		return types.User{}, fmt.Errorf("query error : %w", err)
+	}
+
+	return user, nil
+}
+
+func (s *Sqlite) RevokeAPIKey(id int64) error {
+	result, err := s.Db.Exec("UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("no active api key found with id %d", id)
+	}
+
+	return nil
+}
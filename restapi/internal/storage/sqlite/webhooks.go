@@ -0,0 +1,213 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// insertEvent appends a student event to the outbox, it must be called with the tx of the change itself
+func (s *Sqlite) insertEvent(tx *sql.Tx, eventType string, student types.Student) error {
+	payload, err := json.Marshal(student)
+	if err != nil {
+		return err
+	}
+	//the payload is the whole student, it is kept as well protected as the row it comes from
+	sealed, err := s.sealPayload(string(payload))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Stmt(s.stmts.insertEvent).Exec(eventType, student.Id, sealed, time.Now().UTC())
+	return err
+}
+
+func (s *Sqlite) CreateWebhook(url string, secret string) (int64, error) {
+	result, err := s.Db.Exec("INSERT INTO webhooks (url, secret, created_at) VALUES (?, ?, ?)", url, secret, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// GetWebhooks leaves the secret out, it is only ever read back by the dispatcher
+func (s *Sqlite) GetWebhooks() ([]types.Webhook, error) {
+	rows, err := s.Db.Query("SELECT id, url, created_at FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []types.Webhook{}
+	for rows.Next() {
+		var webhook types.Webhook
+
+		if err := rows.Scan(&webhook.Id, &webhook.URL, &webhook.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (s *Sqlite) DeleteWebhook(id int64) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		//the cascade only runs with sqlite.foreign_keys on [the default], it can be switched off so clean up ourselves
+		if _, err := tx.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ?", id); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec("DELETE FROM webhooks WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return i18n.Errorf("storage.webhook_not_found", "no webhook found with id %d", id)
+		}
+
+		return nil
+	})
+}
+
+func (s *Sqlite) FanOutEvents(limit int) (int, error) {
+	var count int
+
+	err := s.withTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT id FROM outbox WHERE dispatched = 0 ORDER BY id LIMIT ?", limit)
+		if err != nil {
+			return err
+		}
+
+		var ids []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		for _, id := range ids {
+			//webhooks registered after the event was written don't get it, they start from "now"
+			_, err := tx.Exec(`INSERT OR IGNORE INTO webhook_deliveries (webhook_id, event_id, status, next_attempt_at)
+				SELECT w.id, ?, ?, ? FROM webhooks w WHERE w.created_at <= ?`,
+				id, types.DeliveryPending, now, now)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.Exec("UPDATE outbox SET dispatched = 1 WHERE id = ?", id); err != nil {
+				return err
+			}
+		}
+
+		count = len(ids)
+		return nil
+	})
+
+	return count, err
+}
+
+const deliveryColumns = `d.id, d.webhook_id, w.url, w.secret, d.status, d.attempts, d.next_attempt_at, d.last_error,
+	o.id, o.event_type, o.student_id, o.payload, o.created_at`
+
+const deliveryJoins = `FROM webhook_deliveries d
+	JOIN webhooks w ON w.id = d.webhook_id
+	JOIN outbox o ON o.id = d.event_id`
+
+func (s *Sqlite) DueDeliveries(now time.Time, limit int) ([]types.Delivery, error) {
+	return s.queryDeliveries("SELECT "+deliveryColumns+" "+deliveryJoins+
+		" WHERE d.status = ? AND d.next_attempt_at <= ? ORDER BY d.next_attempt_at, d.id LIMIT ?",
+		types.DeliveryPending, now.UTC(), limit)
+}
+
+// GetDeliveries lists deliveries in the given status, or all of them when status is empty
+func (s *Sqlite) GetDeliveries(status string) ([]types.Delivery, error) {
+	if status == "" {
+		return s.queryDeliveries("SELECT " + deliveryColumns + " " + deliveryJoins + " ORDER BY d.id")
+	}
+
+	return s.queryDeliveries("SELECT "+deliveryColumns+" "+deliveryJoins+" WHERE d.status = ? ORDER BY d.id", status)
+}
+
+func (s *Sqlite) queryDeliveries(query string, args ...any) ([]types.Delivery, error) {
+	rows, err := s.Db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []types.Delivery{}
+	for rows.Next() {
+		var d types.Delivery
+		var payload string
+
+		err := rows.Scan(&d.Id, &d.WebhookId, &d.URL, &d.Secret, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastError,
+			&d.Event.Id, &d.Event.Type, &d.Event.StudentId, &payload, &d.Event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if payload, err = s.openPayload(payload); err != nil {
+			return nil, fmt.Errorf("event %d: %w", d.Event.Id, err)
+		}
+
+		d.Event.Payload = json.RawMessage(payload)
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (s *Sqlite) MarkDelivered(id int64) error {
+	_, err := s.Db.Exec("UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, last_error = '' WHERE id = ?",
+		types.DeliveryDelivered, id)
+	return err
+}
+
+func (s *Sqlite) MarkFailed(id int64, lastError string, next time.Time, dead bool) error {
+	status := types.DeliveryPending
+	if dead {
+		status = types.DeliveryDead
+	}
+
+	_, err := s.Db.Exec("UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE id = ?",
+		status, next.UTC(), lastError, id)
+	return err
+}
+
+// RedeliverDelivery puts a delivery back in the queue with a fresh set of attempts
+func (s *Sqlite) RedeliverDelivery(id int64) error {
+	result, err := s.Db.Exec("UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ? WHERE id = ?",
+		types.DeliveryPending, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return i18n.Errorf("storage.delivery_not_found", "no delivery found with id %d", id)
+	}
+
+	return nil
+}
@@ -1,14 +1,159 @@
 // DB Setup ----------
 package storage
 
-import "github.com/shivakr07/students-api/internal/types"
+import (
+	"time"
+
+	"github.com/shivakr07/students-api/internal/i18n"
+	"github.com/shivakr07/students-api/internal/types"
+)
 
 // we will use interfaces here
 // we can make it like pluging as we did for payment methods
 // so we can switch to any DB with minimal changes
 
 type Storage interface {
-	CreateStudent(name string, email string, age int) (int64, error)
+	CreateStudent(student types.Student) (int64, error)
 	GetStudentById(id int64) (types.Student, error)
 	GetStudents() ([]types.Student, error)
+	// ListStudents is GetStudents with filtering and pagination pushed down to the backend
+	ListStudents(query types.StudentQuery) ([]types.Student, error)
+	UpdateStudent(id int64, student types.Student) error
+	DeleteStudent(id int64) error
+}
+
+// errors of the student writes and queries, handlers answer ErrEmailTaken with 409 and ErrEncryptedField with 400
+var (
+	ErrEmailTaken     = i18n.New("storage.email_taken", "email is already used by another student")
+	ErrEncryptedField = i18n.New("storage.encrypted_field", "the field is encrypted, only exact email lookups are possible")
+)
+
+// the not found errors name the id in their message, errors.Is matches them by their key
+var (
+	ErrStudentNotFound    = i18n.New("storage.student_not_found", "no student found")
+	ErrCourseNotFound     = i18n.New("storage.course_not_found", "no course found")
+	ErrPhotoNotFound      = i18n.New("storage.photo_not_found", "no photo found")
+	ErrAssessmentNotFound = i18n.New("storage.assessment_not_found", "no assessment found")
+	ErrWebhookNotFound    = i18n.New("storage.webhook_not_found", "no webhook found")
+	ErrDeliveryNotFound   = i18n.New("storage.delivery_not_found", "no delivery found")
+)
+
+// EncryptionStorage brings stored values in line with the encryption config: values sealed with an
+// older key get the active one, plain values of encrypted fields get sealed, and fields taken out of
+// the config get decrypted, the re-encryption job only depends on this
+type EncryptionStorage interface {
+	// Reencrypt handles at most limit rows and returns how many it changed, 0 means nothing is left
+	Reencrypt(limit int) (int, error)
+}
+
+// TxStorage is a Storage bound to one transaction, everything done through it commits or rolls back together
+type TxStorage interface {
+	Storage
+	// Savepoint runs fn so that when it fails only the changes fn made are rolled back, the transaction goes on
+	Savepoint(fn func() error) error
+}
+
+// Transactor is implemented by backends [and decorators] that can run several operations atomically
+type Transactor interface {
+	// InTx commits when fn returns nil and rolls back everything otherwise
+	InTx(fn func(tx TxStorage) error) error
+}
+
+// WebhookStorage is implemented by backends that keep an outbox of student events
+// the webhook dispatcher and the admin api only depend on this
+type WebhookStorage interface {
+	CreateWebhook(url string, secret string) (int64, error)
+	GetWebhooks() ([]types.Webhook, error)
+	DeleteWebhook(id int64) error
+
+	// FanOutEvents turns undispatched outbox events into one pending delivery per webhook
+	FanOutEvents(limit int) (int, error)
+	DueDeliveries(now time.Time, limit int) ([]types.Delivery, error)
+	GetDeliveries(status string) ([]types.Delivery, error)
+	MarkDelivered(id int64) error
+	// MarkFailed records a failed attempt, the delivery is retried at next unless dead is set
+	MarkFailed(id int64, lastError string, next time.Time, dead bool) error
+	RedeliverDelivery(id int64) error
+}
+
+// EventStorage reads back the outbox as an ordered event log, ids only ever grow
+type EventStorage interface {
+	EventsAfter(id int64, limit int) ([]types.Event, error)
+	LastEventId() (int64, error)
+}
+
+// UserStorage keeps the users and the (hashed) api keys that belong to them
+type UserStorage interface {
+	CreateUser(username string, passwordHash string, role string) (int64, error)
+	GetUserByUsername(username string) (types.User, error)
+	GetUsers() ([]types.User, error)
+	DeleteUser(username string) error
+
+	CreateAPIKey(userId int64, name string, prefix string, keyHash string) (int64, error)
+	GetAPIKeys() ([]types.APIKey, error)
+	RevokeAPIKey(id int64) error
+	// GetUserByAPIKey is the owner of a key that isn't revoked
+	GetUserByAPIKey(keyHash string) (types.User, error)
+}
+
+// errors the course storage returns when a course or an enrollment is refused, handlers answer them with
+// 409 [ErrNotEnrolled with 404 when dropping, grading a student who isn't enrolled stays a 409]
+var (
+	ErrCourseCodeTaken = i18n.New("storage.course_code_taken", "course code is already used by another course")
+	ErrCourseFull      = i18n.New("storage.course_full", "course is full")
+	ErrAlreadyEnrolled = i18n.New("storage.already_enrolled", "student is already enrolled in this course")
+	ErrNotEnrolled     = i18n.New("storage.not_enrolled", "student is not enrolled in this course")
+)
+
+// CourseStorage keeps courses and the enrollments of students in them
+type CourseStorage interface {
+	CreateCourse(code string, title string, term string, credits float64, scale string, capacity int) (int64, error)
+	GetCourseById(id int64) (types.Course, error)
+	GetCourses() ([]types.Course, error)
+
+	// Enroll checks the capacity and adds the enrollment in one transaction, a dropped enrollment is reactivated
+	Enroll(courseId int64, studentId int64) (types.Enrollment, error)
+	DropEnrollment(courseId int64, studentId int64) error
+	GetCourseEnrollments(courseId int64) ([]types.Enrollment, error)
+	// GetStudentCourses lists the enrollments of a student with their course filled in
+	GetStudentCourses(studentId int64) ([]types.Enrollment, error)
+}
+
+// GradeStorage keeps the assessments of courses and the scores students got on them
+type GradeStorage interface {
+	CreateAssessment(courseId int64, name string, weight float64) (int64, error)
+	GetAssessments(courseId int64) ([]types.Assessment, error)
+	// RecordScore sets (or corrects) a score, only students actively enrolled in the course can be graded
+	RecordScore(assessmentId int64, studentId int64, score float64) error
+	// GetStudentGrades returns every active enrollment of the student with the assessments and scores so far
+	GetStudentGrades(studentId int64) ([]types.CourseGrades, error)
+}
+
+// PhotoStorage keeps the metadata of student photos, the files themselves go to a blob.Store
+// under keys with the etag in them [see photo.Key], so the row says which files are the current ones
+type PhotoStorage interface {
+	// SetStudentPhoto adds or replaces the photo of an existing student and returns the photo it
+	// replaced [a zero Photo when there was none] so the old files can go once the new row is in
+	SetStudentPhoto(photo types.Photo) (types.Photo, error)
+	GetStudentPhoto(studentId int64) (types.Photo, error)
+	// DeleteStudentPhoto returns the photo it removed, for the same reason
+	DeleteStudentPhoto(studentId int64) (types.Photo, error)
+}
+
+// ErrErased is returned for a student that was erased, handlers answer it with 410 and the tombstone
+var ErrErased = i18n.New("storage.student_erased", "the student was erased")
+
+// PrivacyStorage serves the data subject requests of a student: a copy of everything held about them,
+// and erasure, the decorators [cache, photo cleanup] pass erasure through so nothing is left behind them
+type PrivacyStorage interface {
+	ExportStudent(id int64) (types.StudentExport, error)
+	// EraseStudent deletes the student and their rows in other tables, anonymizes what has to stay
+	// [outbox events that deliveries point at] and records the tombstone, all in one transaction
+	EraseStudent(id int64, reason string) (types.Erasure, error)
+	GetErasure(studentId int64) (types.Erasure, error)
+}
+
+// StatsStorage aggregates in the backend, so stats never need every student in memory
+type StatsStorage interface {
+	StudentStats(query types.StatsQuery) (types.StudentStats, error)
 }
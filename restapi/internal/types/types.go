@@ -1,8 +1,378 @@
 package types
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/filter"
+)
+
+// Student is the domain model every api version maps its own json shape onto [see internal/api]
+// Name is always FirstName and LastName joined, Normalize keeps the two in step
 type Student struct {
-	Id    int64  `json:"id"`
-	Name  string `json:"name" validate:"required"`
-	Email string `json:"email" validate:"required"`
-	Age   int    `json:"age" validate:"required"`
+	Id          int64  `json:"id"`
+	Name        string `json:"name" validate:"required"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Email       string `json:"email" validate:"required"`
+	Age         int    `json:"age" validate:"required"`
+	DateOfBirth *Date  `json:"date_of_birth,omitempty"` //nil for students created before v2
+}
+
+// Normalize fills whichever of Name or FirstName/LastName is missing and works out Age from the
+// date of birth when there is one, storage calls it before every write
+func (s *Student) Normalize(now time.Time) {
+	if s.FirstName == "" && s.LastName == "" {
+		s.FirstName, s.LastName = SplitName(s.Name)
+	}
+	s.Name = strings.TrimSpace(s.FirstName + " " + s.LastName)
+	s.Age = s.CurrentAge(now)
+}
+
+// CurrentAge is the age on now, the stored age only counts when there is no date of birth
+func (s Student) CurrentAge(now time.Time) int {
+	if s.DateOfBirth == nil {
+		return s.Age
+	}
+	return s.DateOfBirth.AgeOn(now)
+}
+
+// SplitName splits a full name at the first space, "Ada King Lovelace" gives "Ada" and "King Lovelace"
+func SplitName(name string) (first string, last string) {
+	first, last, _ = strings.Cut(strings.TrimSpace(name), " ")
+	return first, strings.TrimSpace(last)
+}
+
+// Date is a calendar day, written as "2006-01-02" in json and in the database
+type Date struct {
+	time.Time
+}
+
+const DateLayout = "2006-01-02"
+
+func ParseDate(value string) (Date, error) {
+	t, err := time.Parse(DateLayout, value)
+	if err != nil {
+		return Date{}, fmt.Errorf("%q is not a date like 2006-01-02", value)
+	}
+	return Date{Time: t}, nil
+}
+
+func (d Date) String() string { return d.Format(DateLayout) }
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := ParseDate(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// AgeOn is the age in whole years on the given day
+func (d Date) AgeOn(now time.Time) int {
+	age := now.Year() - d.Year()
+	if now.Month() < d.Month() || (now.Month() == d.Month() && now.Day() < d.Day()) {
+		age--
+	}
+	return age
+}
+
+// StudentQuery describes which students a list call should return
+// zero values mean "no filter" so an empty query returns everything
+type StudentQuery struct {
+	Name   string // substring match on name
+	Email  string // exact match on email
+	MinAge int
+	MaxAge int
+	Limit  int
+	Offset int
+	Filter filter.Expr // parsed ?filter= expression, nil when there is none, the backend compiles it
+}
+
+// StudentFields are the json names of a student, what ?fields and ?filter accept
+// every api version offers a subset of these under the same names
+var StudentFields = map[string]filter.Kind{
+	"id":         filter.Number,
+	"name":       filter.String,
+	"first_name": filter.String,
+	"last_name":  filter.String,
+	"email":      filter.String,
+	"age":        filter.Number,
+}
+
+// event types written to the outbox, downstream systems subscribe to these
+const (
+	EventStudentCreated = "student.created"
+	EventStudentUpdated = "student.updated"
+	EventStudentDeleted = "student.deleted"
+	EventStudentErased  = "student.erased" //receivers should erase their copies too
+)
+
+// Event is one row of the outbox, Payload is the student as it looked after the change
+type Event struct {
+	Id        int64           `json:"id"`
+	Type      string          `json:"type"`
+	StudentId int64           `json:"student_id"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type Webhook struct {
+	Id        int64     `json:"id"`
+	URL       string    `json:"url" validate:"required,http_url"`
+	Secret    string    `json:"secret,omitempty" validate:"required,min=16"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// delivery states, a delivery ends up dead once it has used all its attempts
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Delivery tracks sending one event to one webhook
+type Delivery struct {
+	Id            int64     `json:"id"`
+	WebhookId     int64     `json:"webhook_id"`
+	URL           string    `json:"url"`
+	Secret        string    `json:"-"`
+	Event         Event     `json:"event"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// user roles, admins can manage webhooks/backups, staff only use the students api
+const (
+	RoleAdmin = "admin"
+	RoleStaff = "staff"
+)
+
+type User struct {
+	Id           int64     `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	Role         string    `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// APIKey never holds the key itself, only the first characters of it to recognise it by
+type APIKey struct {
+	Id        int64      `json:"id"`
+	UserId    int64      `json:"user_id"`
+	Username  string     `json:"username"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Course is something students enroll in, capacity caps the active enrollments
+type Course struct {
+	Id        int64     `json:"id"`
+	Code      string    `json:"code" validate:"required,max=32"`
+	Title     string    `json:"title" validate:"required"`
+	Term      string    `json:"term" validate:"required"` //like "2026-fall", transcripts group by it
+	Credits   float64   `json:"credits" validate:"required,gt=0"`
+	Scale     string    `json:"scale"` //grading scale name from the config, empty means the default one
+	Capacity  int       `json:"capacity" validate:"required,gt=0"`
+	Enrolled  int       `json:"enrolled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	EnrollmentActive  = "enrolled"
+	EnrollmentDropped = "dropped"
+)
+
+// Enrollment links a student to a course, dropping keeps the row so the history stays
+type Enrollment struct {
+	Id         int64      `json:"id"`
+	StudentId  int64      `json:"student_id" validate:"required"`
+	CourseId   int64      `json:"course_id"`
+	Status     string     `json:"status"`
+	EnrolledAt time.Time  `json:"enrolled_at"`
+	DroppedAt  *time.Time `json:"dropped_at,omitempty"`
+	Course     *Course    `json:"course,omitempty"`
+}
+
+// Assessment is one graded piece of a course [exam, homework...], weights are relative to each other
+type Assessment struct {
+	Id       int64   `json:"id"`
+	CourseId int64   `json:"course_id"`
+	Name     string  `json:"name" validate:"required"`
+	Weight   float64 `json:"weight" validate:"required,gt=0"`
+}
+
+// Score is a student's result on an assessment in percent
+type Score struct {
+	AssessmentId int64    `json:"assessment_id"`
+	StudentId    int64    `json:"student_id"`
+	Score        *float64 `json:"score" validate:"required,gte=0,lte=100"` //a pointer so a missing score isn't a 0
+}
+
+// AssessmentScore is an assessment with the student's score, nil until it is graded
+type AssessmentScore struct {
+	Assessment
+	Score *float64 `json:"score"`
+}
+
+// CourseGrades is what the transcript is computed from, one per active enrollment
+type CourseGrades struct {
+	Course      Course            `json:"course"`
+	Assessments []AssessmentScore `json:"assessments"`
+}
+
+// TranscriptCourse is one line of a transcript, Letter is empty while nothing is graded yet
+type TranscriptCourse struct {
+	CourseId int64    `json:"course_id"`
+	Code     string   `json:"code"`
+	Title    string   `json:"title"`
+	Credits  float64  `json:"credits"`
+	Percent  *float64 `json:"percent"`
+	Letter   string   `json:"letter"`
+	Points   *float64 `json:"points"`
+}
+
+type TermSummary struct {
+	Term    string             `json:"term"`
+	Courses []TranscriptCourse `json:"courses"`
+	Credits float64            `json:"credits"` //graded credits, the ones the gpa is over
+	GPA     *float64           `json:"gpa"`
+}
+
+type Transcript struct {
+	Student       Student       `json:"student"`
+	Terms         []TermSummary `json:"terms"`
+	Credits       float64       `json:"credits"`
+	CumulativeGPA *float64      `json:"cumulative_gpa"`
+}
+
+// Photo is the metadata of a student's profile photo, the bytes live in the blob store
+type Photo struct {
+	StudentId   int64     `json:"student_id"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	ETag        string    `json:"etag"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchOperation is one step of POST /api/batch, Student is needed for create and update, Id for update and delete
+type BatchOperation struct {
+	Op      string          `json:"op"`
+	Id      int64           `json:"id,omitempty"`
+	Student json.RawMessage `json:"student,omitempty"` //in the json form of the api version the batch was sent to
+}
+
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations"`
+	// by default one failure rolls the whole batch back, with this only the failed operations are skipped
+	ContinueOnError bool `json:"continue_on_error"`
+}
+
+// BatchResult reports one operation, in the order they were sent
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Status int    `json:"status"` //the http status the single operation would have had
+	Id     int64  `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// StatsQuery is what GET /api/students/stats asks the backend to aggregate
+type StatsQuery struct {
+	AgeBuckets []int       // ascending bucket boundaries, [18 25] gives <18, 18-24 and 25+
+	GroupBy    string      // one of StatsGroupBy, empty for no grouping
+	Filter     filter.Expr // same ?filter= language as the list
+}
+
+// the fields stats can be grouped by, email_domain is the part after the @
+var StatsGroupBy = []string{"age", "email_domain"}
+
+type StudentStats struct {
+	Count      int          `json:"count"`
+	MinAge     *int         `json:"min_age"` //nil when there are no students
+	MaxAge     *int         `json:"max_age"`
+	AvgAge     *float64     `json:"avg_age"`
+	AgeBands   []AgeBucket  `json:"age_histogram"`
+	GroupBy    string       `json:"group_by,omitempty"`
+	Groups     []GroupCount `json:"groups,omitempty"`
+	ComputedAt time.Time    `json:"computed_at"`
+}
+
+// AgeBucket counts the students with Min <= age < Max, a nil bound is open
+type AgeBucket struct {
+	Label string `json:"label"`
+	Min   *int   `json:"min"`
+	Max   *int   `json:"max"`
+	Count int    `json:"count"`
+}
+
+type GroupCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// StudentExport is everything held about one student, what a data subject export is made of
+type StudentExport struct {
+	Student     Student        `json:"student"`
+	Enrollments []Enrollment   `json:"enrollments"` //dropped ones too
+	Scores      []StudentScore `json:"scores"`      //every score, also of courses the student dropped
+	Photo       *Photo         `json:"photo"`
+	Events      []Event        `json:"events"`      //the audit trail, every change as it was written to the outbox
+	Disclosures []Disclosure   `json:"disclosures"` //which webhooks those events were sent to
+}
+
+type StudentScore struct {
+	CourseId     int64     `json:"course_id"`
+	AssessmentId int64     `json:"assessment_id"`
+	Assessment   string    `json:"assessment"`
+	Score        float64   `json:"score"`
+	GradedAt     time.Time `json:"graded_at"`
+}
+
+// Disclosure is one delivery of a student event to a webhook, without the payload [that's in the event]
+type Disclosure struct {
+	EventId   int64  `json:"event_id"`
+	WebhookId int64  `json:"webhook_id"`
+	URL       string `json:"url"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+}
+
+// Erasure is the tombstone of an erased student, it proves the erasure without holding anything about them
+// Digest chains every tombstone to the one before it, so one can't be removed or changed unnoticed
+type Erasure struct {
+	Id        int64            `json:"id"`
+	StudentId int64            `json:"student_id"`
+	Reason    string           `json:"reason,omitempty"`
+	Removed   map[string]int64 `json:"removed"` //rows deleted or anonymized, by table
+	ErasedAt  time.Time        `json:"erased_at"`
+	Digest    string           `json:"digest"`
 }
@@ -0,0 +1,166 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/shivakr07/students-api/internal/i18n"
+)
+
+// the counterpart of the response package: every handler reads its json body through DecodeJson
+// so all of them get the same limits and the same error messages
+//
+// a body has to be exactly one json value, with no fields the target doesn't have [a typo like
+// "emial" is an error, not a silently missing email] and no more than the configured number of bytes
+
+// Reader reads bodies up to a limit [http_server.max_body_bytes], the server builds one and the
+// handlers that read a body get it as a dependency
+type Reader struct {
+	maxBytes int64
+}
+
+func NewReader(maxBytes int64) Reader {
+	return Reader{maxBytes: maxBytes}
+}
+
+// ErrEmptyBody is what a request without a body gets
+var ErrEmptyBody = i18n.New("request.empty_body", "empty body")
+
+// TooLargeError means the body went over the limit, handlers answer it with 413
+type TooLargeError struct {
+	Limit int64
+}
+
+func (e *TooLargeError) Error() string { return e.Unwrap().Error() }
+
+// Unwrap gives the translatable form of the message
+func (e *TooLargeError) Unwrap() error {
+	return i18n.Errorf("request.too_large", "body is larger than %d bytes", e.Limit)
+}
+
+// Status is the http status for an error from this package, 413 for a too large body and 400 otherwise
+func Status(err error) int {
+	var tooLarge *TooLargeError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// PathId reads the {name} path value as an id, every handler gives the same translated message for a bad one
+// [strconv's "parsing \"abc\": invalid syntax" means nothing to a client]
+func PathId(r *http.Request, name string) (int64, error) {
+	raw := r.PathValue(name)
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, i18n.Errorf("request.invalid_id", "invalid %s \"%s\"", name, raw)
+	}
+	return id, nil
+}
+
+// ReadBody reads the whole body up to the limit, for handlers that need the raw bytes [json patch]
+func (rd Reader) ReadBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	limit := rd.maxBytes
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, &TooLargeError{Limit: limit}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return nil, ErrEmptyBody
+	}
+	return body, nil
+}
+
+// DecodeJson decodes the body into v
+func (rd Reader) DecodeJson(w http.ResponseWriter, r *http.Request, v any) error {
+	limit := rd.maxBytes
+	err := decode(http.MaxBytesReader(w, r.Body, limit), v)
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &TooLargeError{Limit: limit}
+	}
+	return err
+}
+
+// UnmarshalStrict applies the same rules to json that is already in memory
+func UnmarshalStrict(data []byte, v any) error {
+	return decode(bytes.NewReader(data), v)
+}
+
+func decode(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return describe(err)
+	}
+
+	//anything after the value [a second object, stray characters] means the client sent something we don't understand
+	end := decoder.InputOffset()
+	var extra json.RawMessage
+	err := decoder.Decode(&extra)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, io.EOF):
+	case errors.As(err, &tooLarge):
+		return err
+	default:
+		return i18n.Errorf("request.single_value", "body must contain a single json value, found more data after offset %d", end)
+	}
+
+	return nil
+}
+
+// describe turns the errors encoding/json gives back into messages that say what and where
+func describe(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return ErrEmptyBody
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return i18n.Errorf("request.unexpected_end", "malformed json: body ends in the middle of a value")
+	case errors.As(err, &syntaxErr):
+		return i18n.Errorf("request.syntax", "malformed json at offset %d: %s", syntaxErr.Offset, strings.TrimPrefix(syntaxErr.Error(), "json: "))
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return i18n.Errorf("request.body_type", "body must be a json %s, got %s", jsonType(typeErr.Type.String()), typeErr.Value)
+		}
+		return i18n.Errorf("request.field_type", "field \"%s\" must be a %s, got %s (offset %d)", typeErr.Field, jsonType(typeErr.Type.String()), typeErr.Value, typeErr.Offset)
+	case errors.As(err, &tooLarge):
+		return err
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		//encoding/json has no type for this one
+		return i18n.Errorf("request.unknown_field", "unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	default:
+		return err
+	}
+}
+
+// jsonType names a go type the way a client thinks of it
+func jsonType(goType string) string {
+	switch {
+	case strings.HasPrefix(goType, "int"), strings.HasPrefix(goType, "uint"), strings.HasPrefix(goType, "float"):
+		return "number"
+	case goType == "string":
+		return "string"
+	case goType == "bool":
+		return "boolean"
+	case strings.HasPrefix(goType, "[]"):
+		return "array"
+	default:
+		return "object"
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/shivakr07/students-api/internal/i18n"
 )
 
 type Response struct {
@@ -17,6 +18,10 @@ type Response struct {
 	//we can define how this should look like when it gets deserialize into json
 	Status string `json:"status"`
 	Error  string `json:"error"`
+
+	//what the message was built from, WriteJson uses them to translate it
+	err    error
+	fields validator.ValidationErrors
 }
 
 const (
@@ -24,10 +29,18 @@ const (
 	StatusError = "Error"
 )
 
+// errors are answered in the Content-Language the i18n middleware negotiated, with its catalog
+// [without the middleware everything stays in english]
 // it will take the response object of func(w.http.ResponseWriter, r *http.Request)
 // since we don't know what kind of data we are going to receive so we take generic type  data any or data interface {}
 func WriteJson(w http.ResponseWriter, status int, data interface{}) error {
 
+	if resp, ok := data.(Response); ok {
+		if catalog := i18n.From(w); catalog != nil {
+			data = resp.translate(catalog, w.Header().Get("Content-Language"))
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	// this allow us to send json data[we need to add header]
 	w.WriteHeader(status)
@@ -42,6 +55,7 @@ func GeneralError(err error) Response {
 	return Response{
 		Status: StatusError,
 		Error:  err.Error(),
+		err:    err,
 	}
 }
 
@@ -63,5 +77,30 @@ func ValidationError(errs validator.ValidationErrors) Response {
 		Status: StatusError,
 		Error:  strings.Join(errMessages, ", "),
 		//it will join all the string elements of that slice as we have in python
+		fields: errs,
+	}
+}
+
+// translate rebuilds the message in lang, whatever the catalog doesn't know stays in english
+func (r Response) translate(catalog *i18n.Catalog, lang string) Response {
+	switch {
+	case lang == "":
+	case r.fields != nil:
+		//a catalog can have a message per validator tag [validation.max...], validation.invalid is the catch-all
+		messages := make([]string, 0, len(r.fields))
+		for _, fe := range r.fields {
+			msg, ok := catalog.T(lang, "validation."+fe.ActualTag(), fe.Field(), fe.Param())
+			if !ok {
+				msg, ok = catalog.T(lang, "validation.invalid", fe.Field(), fe.Param())
+			}
+			if !ok {
+				return r
+			}
+			messages = append(messages, msg)
+		}
+		r.Error = strings.Join(messages, ", ")
+	case r.err != nil:
+		r.Error = catalog.Translate(lang, r.err)
 	}
+	return r
 }
@@ -0,0 +1,167 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/storage"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+// headers sent with every delivery, receivers verify SignatureHeader against the raw body
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventIdHeader   = "X-Webhook-Event-Id"
+	EventTypeHeader = "X-Webhook-Event-Type"
+)
+
+// how many outbox rows / deliveries one tick works through
+const batchSize = 100
+
+// Dispatcher moves outbox events to the registered webhooks
+// every tick it fans new events out into deliveries and then sends the ones that are due
+type Dispatcher struct {
+	store  storage.WebhookStorage
+	cfg    config.Webhooks
+	client *http.Client
+}
+
+func New(store storage.WebhookStorage, cfg config.Webhooks) *Dispatcher {
+	return &Dispatcher{
+		store:  store,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Run blocks until ctx is cancelled, main runs it in its own goroutine
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	slog.Info("webhook dispatcher started", slog.String("interval", d.cfg.PollInterval.String()))
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+			d.tick(ctx)
+		}
+	}
+}
+
+func (d *Dispatcher) tick(ctx context.Context) {
+	if _, err := d.store.FanOutEvents(batchSize); err != nil {
+		slog.Error("failed to fan out outbox events", slog.String("error", err.Error()))
+		return
+	}
+
+	deliveries, err := d.store.DueDeliveries(time.Now(), batchSize)
+	if err != nil {
+		slog.Error("failed to load due deliveries", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		d.deliver(ctx, delivery)
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery types.Delivery) {
+	err := d.send(ctx, delivery)
+	if err == nil {
+		if err := d.store.MarkDelivered(delivery.Id); err != nil {
+			slog.Error("failed to mark delivery", slog.String("id", fmt.Sprint(delivery.Id)), slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	attempt := delivery.Attempts + 1
+	dead := attempt >= d.cfg.MaxAttempts
+	next := time.Now().Add(d.Backoff(attempt))
+
+	slog.Warn("webhook delivery failed",
+		slog.String("id", fmt.Sprint(delivery.Id)),
+		slog.String("url", delivery.URL),
+		slog.Int("attempt", attempt),
+		slog.Bool("dead", dead),
+		slog.String("error", err.Error()))
+
+	if err := d.store.MarkFailed(delivery.Id, err.Error(), next, dead); err != nil {
+		slog.Error("failed to mark delivery", slog.String("id", fmt.Sprint(delivery.Id)), slog.String("error", err.Error()))
+	}
+}
+
+// Backoff doubles the wait after every failed attempt, starting at BackoffBase and capped at BackoffMax
+func (d *Dispatcher) Backoff(attempt int) time.Duration {
+	wait := d.cfg.BackoffBase
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if wait >= d.cfg.BackoffMax {
+			return d.cfg.BackoffMax
+		}
+	}
+
+	return wait
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery types.Delivery) error {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(EventIdHeader, strconv.FormatInt(delivery.Event.Id, 10))
+	req.Header.Set(EventTypeHeader, delivery.Event.Type)
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	//drain so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver answered %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Sign returns the value of SignatureHeader: an HMAC-SHA256 over "timestamp.body" keyed with the webhook secret
+// the timestamp is part of the signed content so a captured request can't be replayed later with a new timestamp
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
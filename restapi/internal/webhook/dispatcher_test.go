@@ -0,0 +1,216 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shivakr07/students-api/internal/apitest"
+	"github.com/shivakr07/students-api/internal/config"
+	"github.com/shivakr07/students-api/internal/types"
+)
+
+const secret = "0123456789abcdef-secret"
+
+// receiver answers every delivery with the status in failWith [200 when it is 0]
+type receiver struct {
+	calls    atomic.Int32
+	failWith atomic.Int32
+
+	mu     sync.Mutex //the last request, the dispatcher has its answer before the test reads it
+	header http.Header
+	body   []byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.calls.Add(1)
+	rc.mu.Lock()
+	rc.header = r.Header.Clone()
+	rc.body, _ = io.ReadAll(r.Body)
+	rc.mu.Unlock()
+
+	if status := rc.failWith.Load(); status != 0 {
+		w.WriteHeader(int(status))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// newDispatcher has one webhook pointing at a local receiver and one student created, so one
+// delivery is waiting once the outbox is fanned out
+func newDispatcher(t *testing.T, cfg config.Webhooks) (*Dispatcher, *apitest.Harness, *receiver) {
+	h := apitest.New(t)
+
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	if _, err := h.Storage.CreateWebhook(server.URL, secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Storage.CreateStudent(types.Student{Name: "Alan Turing", Email: "alan@gmail.com", Age: 25}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Timeout = 5 * time.Second
+	return New(h.Storage, cfg), h, rc
+}
+
+func delivery(t *testing.T, h *apitest.Harness) types.Delivery {
+	t.Helper()
+
+	deliveries, err := h.Storage.GetDeliveries("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+// deliverDue sends what would be due at the given time, so a backoff doesn't have to be waited out
+func deliverDue(t *testing.T, d *Dispatcher, h *apitest.Harness, at time.Time) int {
+	t.Helper()
+
+	due, err := h.Storage.DueDeliveries(at, batchSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, delivery := range due {
+		d.deliver(context.Background(), delivery)
+	}
+	return len(due)
+}
+
+func TestSignature(t *testing.T) {
+	d, h, rc := newDispatcher(t, config.Webhooks{MaxAttempts: 3, BackoffBase: time.Hour, BackoffMax: time.Hour})
+
+	d.tick(context.Background())
+
+	if rc.calls.Load() != 1 {
+		t.Fatalf("receiver got %d calls, want 1", rc.calls.Load())
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	timestamp := rc.header.Get(TimestampHeader)
+	if got, want := rc.header.Get(SignatureHeader), Sign(secret, timestamp, rc.body); got != want {
+		t.Errorf("signature: got %s, want %s", got, want)
+	}
+	if got := rc.header.Get(EventTypeHeader); got != types.EventStudentCreated {
+		t.Errorf("event type: got %s", got)
+	}
+
+	var event types.Event
+	if err := json.Unmarshal(rc.body, &event); err != nil {
+		t.Fatal(err)
+	}
+	if rc.header.Get(EventIdHeader) != "1" || event.Id != 1 || event.StudentId != 1 {
+		t.Errorf("got event %+v with id header %s", event, rc.header.Get(EventIdHeader))
+	}
+
+	if got := delivery(t, h); got.Status != types.DeliveryDelivered || got.Attempts != 1 {
+		t.Errorf("got %+v", got)
+	}
+
+	//a signature with another secret or over another body doesn't match
+	if Sign("another secret of 16", timestamp, rc.body) == rc.header.Get(SignatureHeader) ||
+		Sign(secret, timestamp, append(rc.body, ' ')) == rc.header.Get(SignatureHeader) {
+		t.Error("signature doesn't depend on the secret and the body")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := New(nil, config.Webhooks{BackoffBase: 5 * time.Second, BackoffMax: time.Minute})
+
+	for attempt, want := range map[int]time.Duration{
+		1: 5 * time.Second,
+		2: 10 * time.Second,
+		3: 20 * time.Second,
+		4: 40 * time.Second,
+		5: time.Minute,
+		9: time.Minute,
+	} {
+		if got := d.Backoff(attempt); got != want {
+			t.Errorf("attempt %d: got %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	d, h, rc := newDispatcher(t, config.Webhooks{MaxAttempts: 5, BackoffBase: time.Hour, BackoffMax: 4 * time.Hour})
+	rc.failWith.Store(http.StatusInternalServerError)
+
+	before := time.Now()
+	d.tick(context.Background())
+
+	got := delivery(t, h)
+	if got.Status != types.DeliveryPending || got.Attempts != 1 || got.LastError != "receiver answered 500 Internal Server Error" {
+		t.Errorf("after a failure: got %+v", got)
+	}
+	if got.NextAttemptAt.Before(before.Add(time.Hour)) || got.NextAttemptAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("next attempt at %s, want an hour from now", got.NextAttemptAt)
+	}
+
+	//not due yet, the receiver isn't called again
+	d.tick(context.Background())
+	if rc.calls.Load() != 1 {
+		t.Errorf("receiver got %d calls during the backoff, want 1", rc.calls.Load())
+	}
+
+	//the second failure waits twice as long
+	before = time.Now()
+	deliverDue(t, d, h, time.Now().Add(time.Hour))
+	if got := delivery(t, h); got.Attempts != 2 || got.NextAttemptAt.Before(before.Add(2*time.Hour)) {
+		t.Errorf("after the second failure: got %+v", got)
+	}
+
+	rc.failWith.Store(0)
+	deliverDue(t, d, h, time.Now().Add(3*time.Hour))
+	if got := delivery(t, h); got.Status != types.DeliveryDelivered || got.Attempts != 3 || got.LastError != "" {
+		t.Errorf("after the receiver recovered: got %+v", got)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	d, h, rc := newDispatcher(t, config.Webhooks{MaxAttempts: 3, BackoffBase: time.Minute, BackoffMax: time.Minute})
+	rc.failWith.Store(http.StatusServiceUnavailable)
+
+	d.tick(context.Background())
+	for i := 0; i < 2; i++ {
+		deliverDue(t, d, h, time.Now().Add(time.Hour))
+	}
+
+	got := delivery(t, h)
+	if got.Status != types.DeliveryDead || got.Attempts != 3 {
+		t.Fatalf("after max attempts: got %+v", got)
+	}
+
+	//dead letters are never due again
+	if n := deliverDue(t, d, h, time.Now().Add(24*time.Hour)); n != 0 || rc.calls.Load() != 3 {
+		t.Errorf("got %d due and %d calls, want 0 and 3", n, rc.calls.Load())
+	}
+
+	//until they are redelivered, with a fresh set of attempts
+	rc.failWith.Store(0)
+	if err := h.Storage.RedeliverDelivery(got.Id); err != nil {
+		t.Fatal(err)
+	}
+	d.tick(context.Background())
+
+	if got := delivery(t, h); got.Status != types.DeliveryDelivered || got.Attempts != 1 || rc.calls.Load() != 4 {
+		t.Errorf("after redeliver: got %+v and %d calls", got, rc.calls.Load())
+	}
+
+	if err := h.Storage.RedeliverDelivery(99); err == nil {
+		t.Error("redelivering a delivery that doesn't exist worked")
+	}
+}